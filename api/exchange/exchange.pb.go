@@ -20,20 +20,194 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type OrderStatus int32
+
+const (
+	OrderStatus_STATUS_UNKNOWN   OrderStatus = 0
+	OrderStatus_PARTIALLY_FILLED OrderStatus = 1
+	OrderStatus_FILLED           OrderStatus = 2 // последний отчет по заявке
+	OrderStatus_EXPIRED          OrderStatus = 3 // заявка снята при экспирации серии, LeavesVolume - снятый остаток, последний отчет по заявке
+	OrderStatus_SETTLED          OrderStatus = 4 // финальный расчет позиции клиента при экспирации: ID 0, Volume - позиция со знаком, Price - расчетная цена
+)
+
+// Enum value maps for OrderStatus.
+var (
+	OrderStatus_name = map[int32]string{
+		0: "STATUS_UNKNOWN",
+		1: "PARTIALLY_FILLED",
+		2: "FILLED",
+		3: "EXPIRED",
+		4: "SETTLED",
+	}
+	OrderStatus_value = map[string]int32{
+		"STATUS_UNKNOWN":   0,
+		"PARTIALLY_FILLED": 1,
+		"FILLED":           2,
+		"EXPIRED":          3,
+		"SETTLED":          4,
+	}
+)
+
+func (x OrderStatus) Enum() *OrderStatus {
+	p := new(OrderStatus)
+	*p = x
+	return p
+}
+
+func (x OrderStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (OrderStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_exchange_exchange_proto_enumTypes[0].Descriptor()
+}
+
+func (OrderStatus) Type() protoreflect.EnumType {
+	return &file_api_exchange_exchange_proto_enumTypes[0]
+}
+
+func (x OrderStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use OrderStatus.Descriptor instead.
+func (OrderStatus) EnumDescriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{0}
+}
+
+type Side int32
+
+const (
+	Side_SIDE_UNKNOWN Side = 0
+	Side_BUY          Side = 1
+	Side_SELL         Side = 2
+)
+
+// Enum value maps for Side.
+var (
+	Side_name = map[int32]string{
+		0: "SIDE_UNKNOWN",
+		1: "BUY",
+		2: "SELL",
+	}
+	Side_value = map[string]int32{
+		"SIDE_UNKNOWN": 0,
+		"BUY":          1,
+		"SELL":         2,
+	}
+)
+
+func (x Side) Enum() *Side {
+	p := new(Side)
+	*p = x
+	return p
+}
+
+func (x Side) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Side) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_exchange_exchange_proto_enumTypes[1].Descriptor()
+}
+
+func (Side) Type() protoreflect.EnumType {
+	return &file_api_exchange_exchange_proto_enumTypes[1]
+}
+
+func (x Side) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Side.Descriptor instead.
+func (Side) EnumDescriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{1}
+}
+
+type JournalEntryType int32
+
+const (
+	JournalEntryType_ENTRY_UNKNOWN    JournalEntryType = 0
+	JournalEntryType_ORDER_CREATED    JournalEntryType = 1 // Deal - заявка как она встала в стакан
+	JournalEntryType_ORDER_CANCELLED  JournalEntryType = 2 // Deal - ID, BrokerID и Ticker снятой заявки
+	JournalEntryType_ORDER_FILLED     JournalEntryType = 3 // Deal - отчет об исполнении, Side - сторона брокера
+	JournalEntryType_HEARTBEAT        JournalEntryType = 4 // без номера, праймари жив
+	JournalEntryType_TICK             JournalEntryType = 5 // Tick - тик, с которым шард сводил заявки, пишется только в файл журнала и не реплицируется
+	JournalEntryType_ORDER_EXPIRED    JournalEntryType = 6 // Deal - отчет о снятии заявки при экспирации серии
+	JournalEntryType_POSITION_SETTLED JournalEntryType = 7 // Deal - отчет о финальном расчете позиции при экспирации серии
+)
+
+// Enum value maps for JournalEntryType.
+var (
+	JournalEntryType_name = map[int32]string{
+		0: "ENTRY_UNKNOWN",
+		1: "ORDER_CREATED",
+		2: "ORDER_CANCELLED",
+		3: "ORDER_FILLED",
+		4: "HEARTBEAT",
+		5: "TICK",
+		6: "ORDER_EXPIRED",
+		7: "POSITION_SETTLED",
+	}
+	JournalEntryType_value = map[string]int32{
+		"ENTRY_UNKNOWN":    0,
+		"ORDER_CREATED":    1,
+		"ORDER_CANCELLED":  2,
+		"ORDER_FILLED":     3,
+		"HEARTBEAT":        4,
+		"TICK":             5,
+		"ORDER_EXPIRED":    6,
+		"POSITION_SETTLED": 7,
+	}
+)
+
+func (x JournalEntryType) Enum() *JournalEntryType {
+	p := new(JournalEntryType)
+	*p = x
+	return p
+}
+
+func (x JournalEntryType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JournalEntryType) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_exchange_exchange_proto_enumTypes[2].Descriptor()
+}
+
+func (JournalEntryType) Type() protoreflect.EnumType {
+	return &file_api_exchange_exchange_proto_enumTypes[2]
+}
+
+func (x JournalEntryType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JournalEntryType.Descriptor instead.
+func (JournalEntryType) EnumDescriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{2}
+}
+
 type OHLCV struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ID       int64   `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"` // внутренний идентификатор, просто авто-инкремент
-	Time     int32   `protobuf:"varint,2,opt,name=Time,proto3" json:"Time,omitempty"`
-	Interval int32   `protobuf:"varint,3,opt,name=Interval,proto3" json:"Interval,omitempty"` // в данном случае - 1 секунда
-	Open     float32 `protobuf:"fixed32,4,opt,name=Open,proto3" json:"Open,omitempty"`
-	High     float32 `protobuf:"fixed32,5,opt,name=High,proto3" json:"High,omitempty"`
-	Low      float32 `protobuf:"fixed32,6,opt,name=Low,proto3" json:"Low,omitempty"`
-	Close    float32 `protobuf:"fixed32,7,opt,name=Close,proto3" json:"Close,omitempty"`
-	Volume   int32   `protobuf:"varint,8,opt,name=Volume,proto3" json:"Volume,omitempty"`
-	Ticker   string  `protobuf:"bytes,9,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	ID           int64   `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"` // внутренний идентификатор, просто авто-инкремент
+	Time         int32   `protobuf:"varint,2,opt,name=Time,proto3" json:"Time,omitempty"`
+	Interval     int32   `protobuf:"varint,3,opt,name=Interval,proto3" json:"Interval,omitempty"` // в данном случае - 1 секунда
+	Open         float32 `protobuf:"fixed32,4,opt,name=Open,proto3" json:"Open,omitempty"`
+	High         float32 `protobuf:"fixed32,5,opt,name=High,proto3" json:"High,omitempty"`
+	Low          float32 `protobuf:"fixed32,6,opt,name=Low,proto3" json:"Low,omitempty"`
+	Close        float32 `protobuf:"fixed32,7,opt,name=Close,proto3" json:"Close,omitempty"`
+	Volume       int32   `protobuf:"varint,8,opt,name=Volume,proto3" json:"Volume,omitempty"`
+	Ticker       string  `protobuf:"bytes,9,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	BestBid      float32 `protobuf:"fixed32,10,opt,name=BestBid,proto3" json:"BestBid,omitempty"`          // лучшая заявка на покупку в стакане на закрытии, 0 если нет
+	BestAsk      float32 `protobuf:"fixed32,11,opt,name=BestAsk,proto3" json:"BestAsk,omitempty"`          // лучшая заявка на продажу в стакане на закрытии, 0 если нет
+	VWAP         float32 `protobuf:"fixed32,12,opt,name=VWAP,proto3" json:"VWAP,omitempty"`                // средняя цена взвешенная по объему
+	Trades       int32   `protobuf:"varint,13,opt,name=Trades,proto3" json:"Trades,omitempty"`             // количество сделок (тиков) за интервал
+	OpenInterest int64   `protobuf:"varint,14,opt,name=OpenInterest,proto3" json:"OpenInterest,omitempty"` // открытые позиции клиентов по тикеру на закрытии
+	Conflated    bool    `protobuf:"varint,15,opt,name=Conflated,proto3" json:"Conflated,omitempty"`       // подписчик не успевал, предыдущие неотправленные свечи тикера заменены этой
 }
 
 func (x *OHLCV) Reset() {
@@ -131,6 +305,48 @@ func (x *OHLCV) GetTicker() string {
 	return ""
 }
 
+func (x *OHLCV) GetBestBid() float32 {
+	if x != nil {
+		return x.BestBid
+	}
+	return 0
+}
+
+func (x *OHLCV) GetBestAsk() float32 {
+	if x != nil {
+		return x.BestAsk
+	}
+	return 0
+}
+
+func (x *OHLCV) GetVWAP() float32 {
+	if x != nil {
+		return x.VWAP
+	}
+	return 0
+}
+
+func (x *OHLCV) GetTrades() int32 {
+	if x != nil {
+		return x.Trades
+	}
+	return 0
+}
+
+func (x *OHLCV) GetOpenInterest() int64 {
+	if x != nil {
+		return x.OpenInterest
+	}
+	return 0
+}
+
+func (x *OHLCV) GetConflated() bool {
+	if x != nil {
+		return x.Conflated
+	}
+	return false
+}
+
 type Deal struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -144,6 +360,17 @@ type Deal struct {
 	Partial  bool    `protobuf:"varint,6,opt,name=Partial,proto3" json:"Partial,omitempty"` // флаг что сделка клиента исполнилсь частично
 	Time     int32   `protobuf:"varint,7,opt,name=Time,proto3" json:"Time,omitempty"`
 	Price    float32 `protobuf:"fixed32,8,opt,name=Price,proto3" json:"Price,omitempty"`
+	// заполняются биржей в отчетах об исполнении в Results
+	CumVolume    int32       `protobuf:"varint,9,opt,name=CumVolume,proto3" json:"CumVolume,omitempty"`        // исполнено по заявке всего
+	LeavesVolume int32       `protobuf:"varint,10,opt,name=LeavesVolume,proto3" json:"LeavesVolume,omitempty"` // осталось исполнить
+	AvgPrice     float32     `protobuf:"fixed32,11,opt,name=AvgPrice,proto3" json:"AvgPrice,omitempty"`        // средняя цена исполнения
+	Status       OrderStatus `protobuf:"varint,12,opt,name=Status,proto3,enum=main.OrderStatus" json:"Status,omitempty"`
+	// айсберг: видимая часть заявки, после ее исполнения выставляется следующая из скрытого остатка
+	// с потерей приоритета по времени. 0 - заявка видна целиком
+	DisplayVolume int32 `protobuf:"varint,13,opt,name=DisplayVolume,proto3" json:"DisplayVolume,omitempty"`
+	// номер отчета об исполнении в журнале биржи, растет для каждого брокера,
+	// по нему Results возобновляется без потерь и повторов
+	ExecSeq int64 `protobuf:"varint,14,opt,name=ExecSeq,proto3" json:"ExecSeq,omitempty"`
 }
 
 func (x *Deal) Reset() {
@@ -234,6 +461,48 @@ func (x *Deal) GetPrice() float32 {
 	return 0
 }
 
+func (x *Deal) GetCumVolume() int32 {
+	if x != nil {
+		return x.CumVolume
+	}
+	return 0
+}
+
+func (x *Deal) GetLeavesVolume() int32 {
+	if x != nil {
+		return x.LeavesVolume
+	}
+	return 0
+}
+
+func (x *Deal) GetAvgPrice() float32 {
+	if x != nil {
+		return x.AvgPrice
+	}
+	return 0
+}
+
+func (x *Deal) GetStatus() OrderStatus {
+	if x != nil {
+		return x.Status
+	}
+	return OrderStatus_STATUS_UNKNOWN
+}
+
+func (x *Deal) GetDisplayVolume() int32 {
+	if x != nil {
+		return x.DisplayVolume
+	}
+	return 0
+}
+
+func (x *Deal) GetExecSeq() int64 {
+	if x != nil {
+		return x.ExecSeq
+	}
+	return 0
+}
+
 type DealID struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -295,6 +564,9 @@ type BrokerID struct {
 	unknownFields protoimpl.UnknownFields
 
 	ID int64 `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"`
+	// Results: сначала досылаются отчеты с ExecSeq больше этого, 0 - только новые,
+	// больше последнего в журнале биржи (отчеты потеряны при переключении на резерв) - FailedPrecondition
+	LastExecSeq int64 `protobuf:"varint,2,opt,name=LastExecSeq,proto3" json:"LastExecSeq,omitempty"`
 }
 
 func (x *BrokerID) Reset() {
@@ -336,6 +608,13 @@ func (x *BrokerID) GetID() int64 {
 	return 0
 }
 
+func (x *BrokerID) GetLastExecSeq() int64 {
+	if x != nil {
+		return x.LastExecSeq
+	}
+	return 0
+}
+
 type CancelResult struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -383,127 +662,1598 @@ func (x *CancelResult) GetSuccess() bool {
 	return false
 }
 
-var File_api_exchange_exchange_proto protoreflect.FileDescriptor
+type CandlesRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_api_exchange_exchange_proto_rawDesc = []byte{
-	0x0a, 0x1b, 0x61, 0x70, 0x69, 0x2f, 0x65, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x2f, 0x65,
-	0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x04, 0x6d,
-	0x61, 0x69, 0x6e, 0x22, 0xc7, 0x01, 0x0a, 0x05, 0x4f, 0x48, 0x4c, 0x43, 0x56, 0x12, 0x0e, 0x0a,
-	0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x12, 0x0a,
-	0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x54, 0x69, 0x6d,
-	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x03, 0x20,
-	0x01, 0x28, 0x05, 0x52, 0x08, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x12, 0x0a,
-	0x04, 0x4f, 0x70, 0x65, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x02, 0x52, 0x04, 0x4f, 0x70, 0x65,
-	0x6e, 0x12, 0x12, 0x0a, 0x04, 0x48, 0x69, 0x67, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x02, 0x52,
-	0x04, 0x48, 0x69, 0x67, 0x68, 0x12, 0x10, 0x0a, 0x03, 0x4c, 0x6f, 0x77, 0x18, 0x06, 0x20, 0x01,
-	0x28, 0x02, 0x52, 0x03, 0x4c, 0x6f, 0x77, 0x12, 0x14, 0x0a, 0x05, 0x43, 0x6c, 0x6f, 0x73, 0x65,
-	0x18, 0x07, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x12, 0x16, 0x0a,
-	0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x56,
-	0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18,
-	0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x22, 0xc2, 0x01,
-	0x0a, 0x04, 0x44, 0x65, 0x61, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72,
-	0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72,
-	0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x12, 0x16,
-	0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
-	0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65,
-	0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x18,
-	0x0a, 0x07, 0x50, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52,
-	0x07, 0x50, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65,
-	0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05,
-	0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x50, 0x72, 0x69,
-	0x63, 0x65, 0x22, 0x34, 0x0a, 0x06, 0x44, 0x65, 0x61, 0x6c, 0x49, 0x44, 0x12, 0x0e, 0x0a, 0x02,
-	0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08,
-	0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08,
-	0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x22, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b,
-	0x65, 0x72, 0x49, 0x44, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03,
-	0x52, 0x02, 0x49, 0x44, 0x22, 0x28, 0x0a, 0x0c, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65,
-	0x73, 0x75, 0x6c, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x32, 0xb7,
-	0x01, 0x0a, 0x08, 0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12, 0x2c, 0x0a, 0x09, 0x53,
-	0x74, 0x61, 0x74, 0x69, 0x73, 0x74, 0x69, 0x63, 0x12, 0x0e, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e,
-	0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x1a, 0x0b, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e,
-	0x4f, 0x48, 0x4c, 0x43, 0x56, 0x22, 0x00, 0x30, 0x01, 0x12, 0x24, 0x0a, 0x06, 0x43, 0x72, 0x65,
-	0x61, 0x74, 0x65, 0x12, 0x0a, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x1a,
-	0x0c, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x49, 0x44, 0x22, 0x00, 0x12,
-	0x2c, 0x0a, 0x06, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x12, 0x0c, 0x2e, 0x6d, 0x61, 0x69, 0x6e,
-	0x2e, 0x44, 0x65, 0x61, 0x6c, 0x49, 0x44, 0x1a, 0x12, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x43,
-	0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22, 0x00, 0x12, 0x29, 0x0a,
-	0x07, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x0e, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e,
-	0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x1a, 0x0a, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e,
-	0x44, 0x65, 0x61, 0x6c, 0x22, 0x00, 0x30, 0x01, 0x42, 0x10, 0x5a, 0x0e, 0x2e, 0x2f, 0x61, 0x70,
-	0x69, 0x2f, 0x65, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74,
-	0x6f, 0x33,
+	Ticker    string `protobuf:"bytes,1,opt,name=Ticker,proto3" json:"Ticker,omitempty"`       // пустой - все тикеры
+	Interval  int32  `protobuf:"varint,2,opt,name=Interval,proto3" json:"Interval,omitempty"`  // в секундах
+	From      int32  `protobuf:"varint,3,opt,name=From,proto3" json:"From,omitempty"`          // unix time, включительно
+	To        int32  `protobuf:"varint,4,opt,name=To,proto3" json:"To,omitempty"`              // unix time, включительно, 0 - до текущего момента
+	PageSize  int32  `protobuf:"varint,5,opt,name=PageSize,proto3" json:"PageSize,omitempty"`  // 1000 если не задан
+	PageToken string `protobuf:"bytes,6,opt,name=PageToken,proto3" json:"PageToken,omitempty"` // NextPageToken из предыдущего ответа
 }
 
-var (
-	file_api_exchange_exchange_proto_rawDescOnce sync.Once
-	file_api_exchange_exchange_proto_rawDescData = file_api_exchange_exchange_proto_rawDesc
-)
+func (x *CandlesRequest) Reset() {
+	*x = CandlesRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
 
-func file_api_exchange_exchange_proto_rawDescGZIP() []byte {
-	file_api_exchange_exchange_proto_rawDescOnce.Do(func() {
-		file_api_exchange_exchange_proto_rawDescData = protoimpl.X.CompressGZIP(file_api_exchange_exchange_proto_rawDescData)
-	})
-	return file_api_exchange_exchange_proto_rawDescData
+func (x *CandlesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
 }
 
-var file_api_exchange_exchange_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
-var file_api_exchange_exchange_proto_goTypes = []interface{}{
-	(*OHLCV)(nil),        // 0: main.OHLCV
-	(*Deal)(nil),         // 1: main.Deal
-	(*DealID)(nil),       // 2: main.DealID
-	(*BrokerID)(nil),     // 3: main.BrokerID
-	(*CancelResult)(nil), // 4: main.CancelResult
+func (*CandlesRequest) ProtoMessage() {}
+
+func (x *CandlesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
-var file_api_exchange_exchange_proto_depIdxs = []int32{
-	3, // 0: main.Exchange.Statistic:input_type -> main.BrokerID
-	1, // 1: main.Exchange.Create:input_type -> main.Deal
-	2, // 2: main.Exchange.Cancel:input_type -> main.DealID
-	3, // 3: main.Exchange.Results:input_type -> main.BrokerID
-	0, // 4: main.Exchange.Statistic:output_type -> main.OHLCV
-	2, // 5: main.Exchange.Create:output_type -> main.DealID
-	4, // 6: main.Exchange.Cancel:output_type -> main.CancelResult
-	1, // 7: main.Exchange.Results:output_type -> main.Deal
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+
+// Deprecated: Use CandlesRequest.ProtoReflect.Descriptor instead.
+func (*CandlesRequest) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{5}
 }
 
-func init() { file_api_exchange_exchange_proto_init() }
-func file_api_exchange_exchange_proto_init() {
-	if File_api_exchange_exchange_proto != nil {
-		return
+func (x *CandlesRequest) GetTicker() string {
+	if x != nil {
+		return x.Ticker
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_api_exchange_exchange_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*OHLCV); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_api_exchange_exchange_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Deal); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+	return ""
+}
+
+func (x *CandlesRequest) GetInterval() int32 {
+	if x != nil {
+		return x.Interval
+	}
+	return 0
+}
+
+func (x *CandlesRequest) GetFrom() int32 {
+	if x != nil {
+		return x.From
+	}
+	return 0
+}
+
+func (x *CandlesRequest) GetTo() int32 {
+	if x != nil {
+		return x.To
+	}
+	return 0
+}
+
+func (x *CandlesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *CandlesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+type CandlesResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Candles       []*OHLCV `protobuf:"bytes,1,rep,name=Candles,proto3" json:"Candles,omitempty"`             // по возрастанию Time, Time - конец интервала как и в Statistic
+	NextPageToken string   `protobuf:"bytes,2,opt,name=NextPageToken,proto3" json:"NextPageToken,omitempty"` // пустой на последней странице
+}
+
+func (x *CandlesResponse) Reset() {
+	*x = CandlesResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CandlesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CandlesResponse) ProtoMessage() {}
+
+func (x *CandlesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_api_exchange_exchange_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CandlesResponse.ProtoReflect.Descriptor instead.
+func (*CandlesResponse) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CandlesResponse) GetCandles() []*OHLCV {
+	if x != nil {
+		return x.Candles
+	}
+	return nil
+}
+
+func (x *CandlesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+// сделка на ленте, без брокера и клиента
+type Trade struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ID        int64   `protobuf:"varint,1,opt,name=ID,proto3" json:"ID,omitempty"` // сквозной номер сделки на бирже
+	Ticker    string  `protobuf:"bytes,2,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	Price     float32 `protobuf:"fixed32,3,opt,name=Price,proto3" json:"Price,omitempty"`
+	Volume    int32   `protobuf:"varint,4,opt,name=Volume,proto3" json:"Volume,omitempty"`
+	Aggressor Side    `protobuf:"varint,5,opt,name=Aggressor,proto3,enum=main.Side" json:"Aggressor,omitempty"` // сторона заявки брокера, которая забрала ликвидность
+	Time      int32   `protobuf:"varint,6,opt,name=Time,proto3" json:"Time,omitempty"`
+}
+
+func (x *Trade) Reset() {
+	*x = Trade{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Trade) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Trade) ProtoMessage() {}
+
+func (x *Trade) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Trade.ProtoReflect.Descriptor instead.
+func (*Trade) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Trade) GetID() int64 {
+	if x != nil {
+		return x.ID
+	}
+	return 0
+}
+
+func (x *Trade) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *Trade) GetPrice() float32 {
+	if x != nil {
+		return x.Price
+	}
+	return 0
+}
+
+func (x *Trade) GetVolume() int32 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+func (x *Trade) GetAggressor() Side {
+	if x != nil {
+		return x.Aggressor
+	}
+	return Side_SIDE_UNKNOWN
+}
+
+func (x *Trade) GetTime() int32 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+type ClearingRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BrokerID int64  `protobuf:"varint,1,opt,name=BrokerID,proto3" json:"BrokerID,omitempty"`
+	Date     string `protobuf:"bytes,2,opt,name=Date,proto3" json:"Date,omitempty"` // YYYY-MM-DD, пустая - последний закрытый торговый день
+}
+
+func (x *ClearingRequest) Reset() {
+	*x = ClearingRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClearingRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearingRequest) ProtoMessage() {}
+
+func (x *ClearingRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearingRequest.ProtoReflect.Descriptor instead.
+func (*ClearingRequest) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ClearingRequest) GetBrokerID() int64 {
+	if x != nil {
+		return x.BrokerID
+	}
+	return 0
+}
+
+func (x *ClearingRequest) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+// итоги дня по клиенту брокера и инструменту
+type ClearingPosition struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BrokerID        int32   `protobuf:"varint,1,opt,name=BrokerID,proto3" json:"BrokerID,omitempty"`
+	ClientID        int32   `protobuf:"varint,2,opt,name=ClientID,proto3" json:"ClientID,omitempty"`
+	Ticker          string  `protobuf:"bytes,3,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	NetPosition     int64   `protobuf:"varint,4,opt,name=NetPosition,proto3" json:"NetPosition,omitempty"`   // на конец дня, положительная - длинная
+	BoughtVolume    int64   `protobuf:"varint,5,opt,name=BoughtVolume,proto3" json:"BoughtVolume,omitempty"` // куплено за день
+	SoldVolume      int64   `protobuf:"varint,6,opt,name=SoldVolume,proto3" json:"SoldVolume,omitempty"`     // продано за день
+	Turnover        float64 `protobuf:"fixed64,7,opt,name=Turnover,proto3" json:"Turnover,omitempty"`        // сумма цена * объем сделок за день
+	Fees            float64 `protobuf:"fixed64,8,opt,name=Fees,proto3" json:"Fees,omitempty"`                // комиссия биржи за день
+	SettlementPrice float32 `protobuf:"fixed32,9,opt,name=SettlementPrice,proto3" json:"SettlementPrice,omitempty"`
+}
+
+func (x *ClearingPosition) Reset() {
+	*x = ClearingPosition{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClearingPosition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearingPosition) ProtoMessage() {}
+
+func (x *ClearingPosition) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearingPosition.ProtoReflect.Descriptor instead.
+func (*ClearingPosition) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ClearingPosition) GetBrokerID() int32 {
+	if x != nil {
+		return x.BrokerID
+	}
+	return 0
+}
+
+func (x *ClearingPosition) GetClientID() int32 {
+	if x != nil {
+		return x.ClientID
+	}
+	return 0
+}
+
+func (x *ClearingPosition) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *ClearingPosition) GetNetPosition() int64 {
+	if x != nil {
+		return x.NetPosition
+	}
+	return 0
+}
+
+func (x *ClearingPosition) GetBoughtVolume() int64 {
+	if x != nil {
+		return x.BoughtVolume
+	}
+	return 0
+}
+
+func (x *ClearingPosition) GetSoldVolume() int64 {
+	if x != nil {
+		return x.SoldVolume
+	}
+	return 0
+}
+
+func (x *ClearingPosition) GetTurnover() float64 {
+	if x != nil {
+		return x.Turnover
+	}
+	return 0
+}
+
+func (x *ClearingPosition) GetFees() float64 {
+	if x != nil {
+		return x.Fees
+	}
+	return 0
+}
+
+func (x *ClearingPosition) GetSettlementPrice() float32 {
+	if x != nil {
+		return x.SettlementPrice
+	}
+	return 0
+}
+
+type InstrumentSettlement struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ticker          string  `protobuf:"bytes,1,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	SettlementPrice float32 `protobuf:"fixed32,2,opt,name=SettlementPrice,proto3" json:"SettlementPrice,omitempty"` // последняя цена дня
+	Volume          int64   `protobuf:"varint,3,opt,name=Volume,proto3" json:"Volume,omitempty"`                    // объем сделок клиентов за день
+}
+
+func (x *InstrumentSettlement) Reset() {
+	*x = InstrumentSettlement{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *InstrumentSettlement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InstrumentSettlement) ProtoMessage() {}
+
+func (x *InstrumentSettlement) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InstrumentSettlement.ProtoReflect.Descriptor instead.
+func (*InstrumentSettlement) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *InstrumentSettlement) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *InstrumentSettlement) GetSettlementPrice() float32 {
+	if x != nil {
+		return x.SettlementPrice
+	}
+	return 0
+}
+
+func (x *InstrumentSettlement) GetVolume() int64 {
+	if x != nil {
+		return x.Volume
+	}
+	return 0
+}
+
+type ClearingReport struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Date        string                  `protobuf:"bytes,1,opt,name=Date,proto3" json:"Date,omitempty"`
+	Time        int32                   `protobuf:"varint,2,opt,name=Time,proto3" json:"Time,omitempty"`          // unix time закрытия дня
+	Positions   []*ClearingPosition     `protobuf:"bytes,3,rep,name=Positions,proto3" json:"Positions,omitempty"` // только позиции брокера из запроса
+	Instruments []*InstrumentSettlement `protobuf:"bytes,4,rep,name=Instruments,proto3" json:"Instruments,omitempty"`
+}
+
+func (x *ClearingReport) Reset() {
+	*x = ClearingReport{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClearingReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClearingReport) ProtoMessage() {}
+
+func (x *ClearingReport) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClearingReport.ProtoReflect.Descriptor instead.
+func (*ClearingReport) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ClearingReport) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *ClearingReport) GetTime() int32 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *ClearingReport) GetPositions() []*ClearingPosition {
+	if x != nil {
+		return x.Positions
+	}
+	return nil
+}
+
+func (x *ClearingReport) GetInstruments() []*InstrumentSettlement {
+	if x != nil {
+		return x.Instruments
+	}
+	return nil
+}
+
+type OrdersRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BrokerID int64  `protobuf:"varint,1,opt,name=BrokerID,proto3" json:"BrokerID,omitempty"`
+	Ticker   string `protobuf:"bytes,2,opt,name=Ticker,proto3" json:"Ticker,omitempty"` // пустой - все тикеры
+	ID       int64  `protobuf:"varint,3,opt,name=ID,proto3" json:"ID,omitempty"`        // 0 - все заявки брокера
+}
+
+func (x *OrdersRequest) Reset() {
+	*x = OrdersRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OrdersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrdersRequest) ProtoMessage() {}
+
+func (x *OrdersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrdersRequest.ProtoReflect.Descriptor instead.
+func (*OrdersRequest) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *OrdersRequest) GetBrokerID() int64 {
+	if x != nil {
+		return x.BrokerID
+	}
+	return 0
+}
+
+func (x *OrdersRequest) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *OrdersRequest) GetID() int64 {
+	if x != nil {
+		return x.ID
+	}
+	return 0
+}
+
+type OrdersResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Orders []*Deal `protobuf:"bytes,1,rep,name=Orders,proto3" json:"Orders,omitempty"` // заявки в стакане, Volume - неисполненный остаток
+}
+
+func (x *OrdersResponse) Reset() {
+	*x = OrdersResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OrdersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OrdersResponse) ProtoMessage() {}
+
+func (x *OrdersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OrdersResponse.ProtoReflect.Descriptor instead.
+func (*OrdersResponse) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *OrdersResponse) GetOrders() []*Deal {
+	if x != nil {
+		return x.Orders
+	}
+	return nil
+}
+
+type JournalTick struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ticker string  `protobuf:"bytes,1,opt,name=Ticker,proto3" json:"Ticker,omitempty"`
+	Time   int64   `protobuf:"varint,2,opt,name=Time,proto3" json:"Time,omitempty"` // unix время в наносекундах
+	Last   float32 `protobuf:"fixed32,3,opt,name=Last,proto3" json:"Last,omitempty"`
+	Vol    int32   `protobuf:"varint,4,opt,name=Vol,proto3" json:"Vol,omitempty"`
+}
+
+func (x *JournalTick) Reset() {
+	*x = JournalTick{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *JournalTick) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JournalTick) ProtoMessage() {}
+
+func (x *JournalTick) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JournalTick.ProtoReflect.Descriptor instead.
+func (*JournalTick) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *JournalTick) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *JournalTick) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *JournalTick) GetLast() float32 {
+	if x != nil {
+		return x.Last
+	}
+	return 0
+}
+
+func (x *JournalTick) GetVol() int32 {
+	if x != nil {
+		return x.Vol
+	}
+	return 0
+}
+
+// запись журнала заявок и исполнений, по которому резервная биржа повторяет состояние основной,
+// а exchange-replay повторяет сессию из файла
+type JournalEntry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Seq  int64            `protobuf:"varint,1,opt,name=Seq,proto3" json:"Seq,omitempty"` // сквозной номер без пропусков, у TICK - номер записи, после которой пришел тик
+	Type JournalEntryType `protobuf:"varint,2,opt,name=Type,proto3,enum=main.JournalEntryType" json:"Type,omitempty"`
+	Deal *Deal            `protobuf:"bytes,3,opt,name=Deal,proto3" json:"Deal,omitempty"`
+	Side Side             `protobuf:"varint,4,opt,name=Side,proto3,enum=main.Side" json:"Side,omitempty"`
+	Time int64            `protobuf:"varint,5,opt,name=Time,proto3" json:"Time,omitempty"` // часы биржи при записи, unix время в наносекундах
+	Tick *JournalTick     `protobuf:"bytes,6,opt,name=Tick,proto3" json:"Tick,omitempty"`
+}
+
+func (x *JournalEntry) Reset() {
+	*x = JournalEntry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *JournalEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JournalEntry) ProtoMessage() {}
+
+func (x *JournalEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JournalEntry.ProtoReflect.Descriptor instead.
+func (*JournalEntry) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *JournalEntry) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *JournalEntry) GetType() JournalEntryType {
+	if x != nil {
+		return x.Type
+	}
+	return JournalEntryType_ENTRY_UNKNOWN
+}
+
+func (x *JournalEntry) GetDeal() *Deal {
+	if x != nil {
+		return x.Deal
+	}
+	return nil
+}
+
+func (x *JournalEntry) GetSide() Side {
+	if x != nil {
+		return x.Side
+	}
+	return Side_SIDE_UNKNOWN
+}
+
+func (x *JournalEntry) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *JournalEntry) GetTick() *JournalTick {
+	if x != nil {
+		return x.Tick
+	}
+	return nil
+}
+
+type ReplicateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	AfterSeq int64 `protobuf:"varint,1,opt,name=AfterSeq,proto3" json:"AfterSeq,omitempty"` // последняя запись, которая уже есть у резервной биржи
+}
+
+func (x *ReplicateRequest) Reset() {
+	*x = ReplicateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[16]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReplicateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicateRequest) ProtoMessage() {}
+
+func (x *ReplicateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[16]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicateRequest.ProtoReflect.Descriptor instead.
+func (*ReplicateRequest) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *ReplicateRequest) GetAfterSeq() int64 {
+	if x != nil {
+		return x.AfterSeq
+	}
+	return 0
+}
+
+type PromoteRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *PromoteRequest) Reset() {
+	*x = PromoteRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PromoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromoteRequest) ProtoMessage() {}
+
+func (x *PromoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromoteRequest.ProtoReflect.Descriptor instead.
+func (*PromoteRequest) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{17}
+}
+
+type PromoteResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Seq int64 `protobuf:"varint,1,opt,name=Seq,proto3" json:"Seq,omitempty"` // последняя запись журнала, с которой биржа стала основной
+}
+
+func (x *PromoteResponse) Reset() {
+	*x = PromoteResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PromoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PromoteResponse) ProtoMessage() {}
+
+func (x *PromoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PromoteResponse.ProtoReflect.Descriptor instead.
+func (*PromoteResponse) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *PromoteResponse) GetSeq() int64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+// режим сбоев для проверки устойчивости брокера, вероятности от 0 до 1, нули выключают сбой
+type ChaosSettings struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	DelayProbability        float64 `protobuf:"fixed64,1,opt,name=DelayProbability,proto3" json:"DelayProbability,omitempty"`               // сообщение Results или Statistic задерживается
+	MaxDelayMs              int32   `protobuf:"varint,2,opt,name=MaxDelayMs,proto3" json:"MaxDelayMs,omitempty"`                            // задержка случайная, до MaxDelayMs миллисекунд
+	DropProbability         float64 `protobuf:"fixed64,3,opt,name=DropProbability,proto3" json:"DropProbability,omitempty"`                 // сообщение Results или Statistic теряется
+	DuplicateProbability    float64 `protobuf:"fixed64,4,opt,name=DuplicateProbability,proto3" json:"DuplicateProbability,omitempty"`       // исполнение в Results отправляется дважды
+	CreateRejectProbability float64 `protobuf:"fixed64,5,opt,name=CreateRejectProbability,proto3" json:"CreateRejectProbability,omitempty"` // Create отвечает Unavailable
+	AbortProbability        float64 `protobuf:"fixed64,6,opt,name=AbortProbability,proto3" json:"AbortProbability,omitempty"`               // поток Results или Statistic обрывается вместо отправки сообщения
+	Seed                    int64   `protobuf:"varint,7,opt,name=Seed,proto3" json:"Seed,omitempty"`                                        // одинаковый seed дает одинаковую последовательность сбоев
+}
+
+func (x *ChaosSettings) Reset() {
+	*x = ChaosSettings{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ChaosSettings) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChaosSettings) ProtoMessage() {}
+
+func (x *ChaosSettings) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChaosSettings.ProtoReflect.Descriptor instead.
+func (*ChaosSettings) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *ChaosSettings) GetDelayProbability() float64 {
+	if x != nil {
+		return x.DelayProbability
+	}
+	return 0
+}
+
+func (x *ChaosSettings) GetMaxDelayMs() int32 {
+	if x != nil {
+		return x.MaxDelayMs
+	}
+	return 0
+}
+
+func (x *ChaosSettings) GetDropProbability() float64 {
+	if x != nil {
+		return x.DropProbability
+	}
+	return 0
+}
+
+func (x *ChaosSettings) GetDuplicateProbability() float64 {
+	if x != nil {
+		return x.DuplicateProbability
+	}
+	return 0
+}
+
+func (x *ChaosSettings) GetCreateRejectProbability() float64 {
+	if x != nil {
+		return x.CreateRejectProbability
+	}
+	return 0
+}
+
+func (x *ChaosSettings) GetAbortProbability() float64 {
+	if x != nil {
+		return x.AbortProbability
+	}
+	return 0
+}
+
+func (x *ChaosSettings) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+type ContractsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ticker string `protobuf:"bytes,1,opt,name=Ticker,proto3" json:"Ticker,omitempty"` // серия или непрерывный тикер, пустой - все серии
+}
+
+func (x *ContractsRequest) Reset() {
+	*x = ContractsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ContractsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContractsRequest) ProtoMessage() {}
+
+func (x *ContractsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContractsRequest.ProtoReflect.Descriptor instead.
+func (*ContractsRequest) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *ContractsRequest) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+// серия фьючерса
+type Contract struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Ticker          string  `protobuf:"bytes,1,opt,name=Ticker,proto3" json:"Ticker,omitempty"`                    // тикер серии
+	Underlying      string  `protobuf:"bytes,2,opt,name=Underlying,proto3" json:"Underlying,omitempty"`            // непрерывный тикер, заявки по нему идут в ближайшую серию
+	LastTradingTime int32   `protobuf:"varint,3,opt,name=LastTradingTime,proto3" json:"LastTradingTime,omitempty"` // unix time окончания торгов, заявки снимаются, позиции рассчитываются
+	Front           bool    `protobuf:"varint,4,opt,name=Front,proto3" json:"Front,omitempty"`                     // ближайшая торгуемая серия непрерывного тикера
+	Expired         bool    `protobuf:"varint,5,opt,name=Expired,proto3" json:"Expired,omitempty"`
+	SettlementPrice float32 `protobuf:"fixed32,6,opt,name=SettlementPrice,proto3" json:"SettlementPrice,omitempty"` // цена финального расчета, если серия уже истекла
+}
+
+func (x *Contract) Reset() {
+	*x = Contract{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Contract) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Contract) ProtoMessage() {}
+
+func (x *Contract) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Contract.ProtoReflect.Descriptor instead.
+func (*Contract) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Contract) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *Contract) GetUnderlying() string {
+	if x != nil {
+		return x.Underlying
+	}
+	return ""
+}
+
+func (x *Contract) GetLastTradingTime() int32 {
+	if x != nil {
+		return x.LastTradingTime
+	}
+	return 0
+}
+
+func (x *Contract) GetFront() bool {
+	if x != nil {
+		return x.Front
+	}
+	return false
+}
+
+func (x *Contract) GetExpired() bool {
+	if x != nil {
+		return x.Expired
+	}
+	return false
+}
+
+func (x *Contract) GetSettlementPrice() float32 {
+	if x != nil {
+		return x.SettlementPrice
+	}
+	return 0
+}
+
+type ContractsResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Contracts []*Contract `protobuf:"bytes,1,rep,name=Contracts,proto3" json:"Contracts,omitempty"` // по непрерывному тикеру, затем по дате экспирации
+}
+
+func (x *ContractsResponse) Reset() {
+	*x = ContractsResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_api_exchange_exchange_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ContractsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ContractsResponse) ProtoMessage() {}
+
+func (x *ContractsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_exchange_exchange_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ContractsResponse.ProtoReflect.Descriptor instead.
+func (*ContractsResponse) Descriptor() ([]byte, []int) {
+	return file_api_exchange_exchange_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ContractsResponse) GetContracts() []*Contract {
+	if x != nil {
+		return x.Contracts
+	}
+	return nil
+}
+
+var File_api_exchange_exchange_proto protoreflect.FileDescriptor
+
+var file_api_exchange_exchange_proto_rawDesc = []byte{
+	0x0a, 0x1b, 0x61, 0x70, 0x69, 0x2f, 0x65, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x2f, 0x65,
+	0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x04, 0x6d,
+	0x61, 0x69, 0x6e, 0x22, 0xe9, 0x02, 0x0a, 0x05, 0x4f, 0x48, 0x4c, 0x43, 0x56, 0x12, 0x0e, 0x0a,
+	0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x12, 0x0a,
+	0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x54, 0x69, 0x6d,
+	0x65, 0x12, 0x1a, 0x0a, 0x08, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x08, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x12, 0x0a,
+	0x04, 0x4f, 0x70, 0x65, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x02, 0x52, 0x04, 0x4f, 0x70, 0x65,
+	0x6e, 0x12, 0x12, 0x0a, 0x04, 0x48, 0x69, 0x67, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x02, 0x52,
+	0x04, 0x48, 0x69, 0x67, 0x68, 0x12, 0x10, 0x0a, 0x03, 0x4c, 0x6f, 0x77, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x02, 0x52, 0x03, 0x4c, 0x6f, 0x77, 0x12, 0x14, 0x0a, 0x05, 0x43, 0x6c, 0x6f, 0x73, 0x65,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x12, 0x16, 0x0a,
+	0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x56,
+	0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18,
+	0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x18, 0x0a,
+	0x07, 0x42, 0x65, 0x73, 0x74, 0x42, 0x69, 0x64, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x02, 0x52, 0x07,
+	0x42, 0x65, 0x73, 0x74, 0x42, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x42, 0x65, 0x73, 0x74, 0x41,
+	0x73, 0x6b, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x02, 0x52, 0x07, 0x42, 0x65, 0x73, 0x74, 0x41, 0x73,
+	0x6b, 0x12, 0x12, 0x0a, 0x04, 0x56, 0x57, 0x41, 0x50, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x02, 0x52,
+	0x04, 0x56, 0x57, 0x41, 0x50, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x72, 0x61, 0x64, 0x65, 0x73, 0x18,
+	0x0d, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x54, 0x72, 0x61, 0x64, 0x65, 0x73, 0x12, 0x22, 0x0a,
+	0x0c, 0x4f, 0x70, 0x65, 0x6e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x65, 0x73, 0x74, 0x18, 0x0e, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0c, 0x4f, 0x70, 0x65, 0x6e, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x65, 0x73,
+	0x74, 0x12, 0x1c, 0x0a, 0x09, 0x43, 0x6f, 0x6e, 0x66, 0x6c, 0x61, 0x74, 0x65, 0x64, 0x18, 0x0f,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x43, 0x6f, 0x6e, 0x66, 0x6c, 0x61, 0x74, 0x65, 0x64, 0x22,
+	0x8b, 0x03, 0x0a, 0x04, 0x44, 0x65, 0x61, 0x6c, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b,
+	0x65, 0x72, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b,
+	0x65, 0x72, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x44,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x44,
+	0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75,
+	0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x50, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x07, 0x50, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x69,
+	0x6d, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x02, 0x52, 0x05, 0x50,
+	0x72, 0x69, 0x63, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x43, 0x75, 0x6d, 0x56, 0x6f, 0x6c, 0x75, 0x6d,
+	0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x43, 0x75, 0x6d, 0x56, 0x6f, 0x6c, 0x75,
+	0x6d, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x4c, 0x65, 0x61, 0x76, 0x65, 0x73, 0x56, 0x6f, 0x6c, 0x75,
+	0x6d, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x4c, 0x65, 0x61, 0x76, 0x65, 0x73,
+	0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08, 0x41, 0x76, 0x67, 0x50, 0x72, 0x69,
+	0x63, 0x65, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x02, 0x52, 0x08, 0x41, 0x76, 0x67, 0x50, 0x72, 0x69,
+	0x63, 0x65, 0x12, 0x29, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x0c, 0x20, 0x01,
+	0x28, 0x0e, 0x32, 0x11, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x24, 0x0a,
+	0x0d, 0x44, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x0d,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x44, 0x69, 0x73, 0x70, 0x6c, 0x61, 0x79, 0x56, 0x6f, 0x6c,
+	0x75, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x45, 0x78, 0x65, 0x63, 0x53, 0x65, 0x71, 0x18, 0x0e,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x45, 0x78, 0x65, 0x63, 0x53, 0x65, 0x71, 0x22, 0x34, 0x0a,
+	0x06, 0x44, 0x65, 0x61, 0x6c, 0x49, 0x44, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65,
+	0x72, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65,
+	0x72, 0x49, 0x44, 0x22, 0x3c, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x12,
+	0x0e, 0x0a, 0x02, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12,
+	0x20, 0x0a, 0x0b, 0x4c, 0x61, 0x73, 0x74, 0x45, 0x78, 0x65, 0x63, 0x53, 0x65, 0x71, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x4c, 0x61, 0x73, 0x74, 0x45, 0x78, 0x65, 0x63, 0x53, 0x65,
+	0x71, 0x22, 0x28, 0x0a, 0x0c, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x22, 0xa2, 0x01, 0x0a, 0x0e,
+	0x43, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16,
+	0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76,
+	0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76,
+	0x61, 0x6c, 0x12, 0x12, 0x0a, 0x04, 0x46, 0x72, 0x6f, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x04, 0x46, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x54, 0x6f, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x02, 0x54, 0x6f, 0x12, 0x1a, 0x0a, 0x08, 0x50, 0x61, 0x67, 0x65, 0x53, 0x69,
+	0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x50, 0x61, 0x67, 0x65, 0x53, 0x69,
+	0x7a, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x50, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x50, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e,
+	0x22, 0x5e, 0x0a, 0x0f, 0x43, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x12, 0x25, 0x0a, 0x07, 0x43, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4f, 0x48, 0x4c, 0x43,
+	0x56, 0x52, 0x07, 0x43, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x73, 0x12, 0x24, 0x0a, 0x0d, 0x4e, 0x65,
+	0x78, 0x74, 0x50, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0d, 0x4e, 0x65, 0x78, 0x74, 0x50, 0x61, 0x67, 0x65, 0x54, 0x6f, 0x6b, 0x65, 0x6e,
+	0x22, 0x9b, 0x01, 0x0a, 0x05, 0x54, 0x72, 0x61, 0x64, 0x65, 0x12, 0x0e, 0x0a, 0x02, 0x49, 0x44,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69,
+	0x63, 0x6b, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b,
+	0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x02, 0x52, 0x05, 0x50, 0x72, 0x69, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75,
+	0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65,
+	0x12, 0x28, 0x0a, 0x09, 0x41, 0x67, 0x67, 0x72, 0x65, 0x73, 0x73, 0x6f, 0x72, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x0a, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x69, 0x64, 0x65, 0x52,
+	0x09, 0x41, 0x67, 0x67, 0x72, 0x65, 0x73, 0x73, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x69,
+	0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x22, 0x41,
+	0x0a, 0x0f, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x12, 0x12, 0x0a,
+	0x04, 0x44, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x44, 0x61, 0x74,
+	0x65, 0x22, 0xa2, 0x02, 0x0a, 0x10, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x69, 0x6e, 0x67, 0x50, 0x6f,
+	0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72,
+	0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72,
+	0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x08, 0x43, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x12, 0x16,
+	0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x20, 0x0a, 0x0b, 0x4e, 0x65, 0x74, 0x50, 0x6f, 0x73,
+	0x69, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0b, 0x4e, 0x65, 0x74,
+	0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x22, 0x0a, 0x0c, 0x42, 0x6f, 0x75, 0x67,
+	0x68, 0x74, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0c,
+	0x42, 0x6f, 0x75, 0x67, 0x68, 0x74, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x1e, 0x0a, 0x0a,
+	0x53, 0x6f, 0x6c, 0x64, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x0a, 0x53, 0x6f, 0x6c, 0x64, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x54, 0x75, 0x72, 0x6e, 0x6f, 0x76, 0x65, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01, 0x52, 0x08,
+	0x54, 0x75, 0x72, 0x6e, 0x6f, 0x76, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x46, 0x65, 0x65, 0x73,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x46, 0x65, 0x65, 0x73, 0x12, 0x28, 0x0a, 0x0f,
+	0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18,
+	0x09, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0f, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e,
+	0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x22, 0x70, 0x0a, 0x14, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75,
+	0x6d, 0x65, 0x6e, 0x74, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x12, 0x16,
+	0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x28, 0x0a, 0x0f, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65,
+	0x6d, 0x65, 0x6e, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x02, 0x52,
+	0x0f, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x06, 0x56, 0x6f, 0x6c, 0x75, 0x6d, 0x65, 0x22, 0xac, 0x01, 0x0a, 0x0e, 0x43, 0x6c, 0x65,
+	0x61, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x44,
+	0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x44, 0x61, 0x74, 0x65, 0x12,
+	0x12, 0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x54,
+	0x69, 0x6d, 0x65, 0x12, 0x34, 0x0a, 0x09, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x43, 0x6c,
+	0x65, 0x61, 0x72, 0x69, 0x6e, 0x67, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09,
+	0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x3c, 0x0a, 0x0b, 0x49, 0x6e, 0x73,
+	0x74, 0x72, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a,
+	0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x49, 0x6e, 0x73, 0x74, 0x72, 0x75, 0x6d, 0x65, 0x6e, 0x74,
+	0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x52, 0x0b, 0x49, 0x6e, 0x73, 0x74,
+	0x72, 0x75, 0x6d, 0x65, 0x6e, 0x74, 0x73, 0x22, 0x53, 0x0a, 0x0d, 0x4f, 0x72, 0x64, 0x65, 0x72,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x42, 0x72, 0x6f, 0x6b,
+	0x65, 0x72, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x42, 0x72, 0x6f, 0x6b,
+	0x65, 0x72, 0x49, 0x44, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x0e, 0x0a, 0x02,
+	0x49, 0x44, 0x18, 0x03, 0x20, 0x01, 0x28, 0x03, 0x52, 0x02, 0x49, 0x44, 0x22, 0x34, 0x0a, 0x0e,
+	0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x22,
+	0x0a, 0x06, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0a,
+	0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x52, 0x06, 0x4f, 0x72, 0x64, 0x65,
+	0x72, 0x73, 0x22, 0x5f, 0x0a, 0x0b, 0x4a, 0x6f, 0x75, 0x72, 0x6e, 0x61, 0x6c, 0x54, 0x69, 0x63,
+	0x6b, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x69, 0x6d,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x12, 0x0a,
+	0x04, 0x4c, 0x61, 0x73, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x02, 0x52, 0x04, 0x4c, 0x61, 0x73,
+	0x74, 0x12, 0x10, 0x0a, 0x03, 0x56, 0x6f, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03,
+	0x56, 0x6f, 0x6c, 0x22, 0xc7, 0x01, 0x0a, 0x0c, 0x4a, 0x6f, 0x75, 0x72, 0x6e, 0x61, 0x6c, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x53, 0x65, 0x71, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x03, 0x53, 0x65, 0x71, 0x12, 0x2a, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0e, 0x32, 0x16, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4a, 0x6f, 0x75, 0x72,
+	0x6e, 0x61, 0x6c, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x54, 0x79,
+	0x70, 0x65, 0x12, 0x1e, 0x0a, 0x04, 0x44, 0x65, 0x61, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0a, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x52, 0x04, 0x44, 0x65,
+	0x61, 0x6c, 0x12, 0x1e, 0x0a, 0x04, 0x53, 0x69, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x0a, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x53, 0x69, 0x64, 0x65, 0x52, 0x04, 0x53, 0x69,
+	0x64, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x04, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x25, 0x0a, 0x04, 0x54, 0x69, 0x63, 0x6b, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4a, 0x6f, 0x75, 0x72,
+	0x6e, 0x61, 0x6c, 0x54, 0x69, 0x63, 0x6b, 0x52, 0x04, 0x54, 0x69, 0x63, 0x6b, 0x22, 0x2e, 0x0a,
+	0x10, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x1a, 0x0a, 0x08, 0x41, 0x66, 0x74, 0x65, 0x72, 0x53, 0x65, 0x71, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x08, 0x41, 0x66, 0x74, 0x65, 0x72, 0x53, 0x65, 0x71, 0x22, 0x10, 0x0a,
+	0x0e, 0x50, 0x72, 0x6f, 0x6d, 0x6f, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22,
+	0x23, 0x0a, 0x0f, 0x50, 0x72, 0x6f, 0x6d, 0x6f, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
+	0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x53, 0x65, 0x71, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x03, 0x53, 0x65, 0x71, 0x22, 0xb3, 0x02, 0x0a, 0x0d, 0x43, 0x68, 0x61, 0x6f, 0x73, 0x53, 0x65,
+	0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x2a, 0x0a, 0x10, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x50,
+	0x72, 0x6f, 0x62, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x10, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x50, 0x72, 0x6f, 0x62, 0x61, 0x62, 0x69, 0x6c, 0x69,
+	0x74, 0x79, 0x12, 0x1e, 0x0a, 0x0a, 0x4d, 0x61, 0x78, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x4d, 0x73,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x4d, 0x61, 0x78, 0x44, 0x65, 0x6c, 0x61, 0x79,
+	0x4d, 0x73, 0x12, 0x28, 0x0a, 0x0f, 0x44, 0x72, 0x6f, 0x70, 0x50, 0x72, 0x6f, 0x62, 0x61, 0x62,
+	0x69, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0f, 0x44, 0x72, 0x6f,
+	0x70, 0x50, 0x72, 0x6f, 0x62, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x32, 0x0a, 0x14,
+	0x44, 0x75, 0x70, 0x6c, 0x69, 0x63, 0x61, 0x74, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x61, 0x62, 0x69,
+	0x6c, 0x69, 0x74, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x01, 0x52, 0x14, 0x44, 0x75, 0x70, 0x6c,
+	0x69, 0x63, 0x61, 0x74, 0x65, 0x50, 0x72, 0x6f, 0x62, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79,
+	0x12, 0x38, 0x0a, 0x17, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74,
+	0x50, 0x72, 0x6f, 0x62, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x17, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x52, 0x65, 0x6a, 0x65, 0x63, 0x74, 0x50,
+	0x72, 0x6f, 0x62, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x2a, 0x0a, 0x10, 0x41, 0x62,
+	0x6f, 0x72, 0x74, 0x50, 0x72, 0x6f, 0x62, 0x61, 0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x01, 0x52, 0x10, 0x41, 0x62, 0x6f, 0x72, 0x74, 0x50, 0x72, 0x6f, 0x62, 0x61,
+	0x62, 0x69, 0x6c, 0x69, 0x74, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x53, 0x65, 0x65, 0x64, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x53, 0x65, 0x65, 0x64, 0x22, 0x2a, 0x0a, 0x10, 0x43, 0x6f,
+	0x6e, 0x74, 0x72, 0x61, 0x63, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16,
+	0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06,
+	0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x22, 0xc6, 0x01, 0x0a, 0x08, 0x43, 0x6f, 0x6e, 0x74, 0x72,
+	0x61, 0x63, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x06, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x72, 0x12, 0x1e, 0x0a, 0x0a, 0x55,
+	0x6e, 0x64, 0x65, 0x72, 0x6c, 0x79, 0x69, 0x6e, 0x67, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0a, 0x55, 0x6e, 0x64, 0x65, 0x72, 0x6c, 0x79, 0x69, 0x6e, 0x67, 0x12, 0x28, 0x0a, 0x0f, 0x4c,
+	0x61, 0x73, 0x74, 0x54, 0x72, 0x61, 0x64, 0x69, 0x6e, 0x67, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x4c, 0x61, 0x73, 0x74, 0x54, 0x72, 0x61, 0x64, 0x69, 0x6e,
+	0x67, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x46, 0x72, 0x6f, 0x6e, 0x74, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x46, 0x72, 0x6f, 0x6e, 0x74, 0x12, 0x18, 0x0a, 0x07, 0x45,
+	0x78, 0x70, 0x69, 0x72, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x45, 0x78,
+	0x70, 0x69, 0x72, 0x65, 0x64, 0x12, 0x28, 0x0a, 0x0f, 0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d,
+	0x65, 0x6e, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x02, 0x52, 0x0f,
+	0x53, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x50, 0x72, 0x69, 0x63, 0x65, 0x22,
+	0x41, 0x0a, 0x11, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x61, 0x63, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2c, 0x0a, 0x09, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x61, 0x63, 0x74,
+	0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x43,
+	0x6f, 0x6e, 0x74, 0x72, 0x61, 0x63, 0x74, 0x52, 0x09, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x61, 0x63,
+	0x74, 0x73, 0x2a, 0x5d, 0x0a, 0x0b, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x12, 0x12, 0x0a, 0x0e, 0x53, 0x54, 0x41, 0x54, 0x55, 0x53, 0x5f, 0x55, 0x4e, 0x4b, 0x4e,
+	0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x50, 0x41, 0x52, 0x54, 0x49, 0x41, 0x4c,
+	0x4c, 0x59, 0x5f, 0x46, 0x49, 0x4c, 0x4c, 0x45, 0x44, 0x10, 0x01, 0x12, 0x0a, 0x0a, 0x06, 0x46,
+	0x49, 0x4c, 0x4c, 0x45, 0x44, 0x10, 0x02, 0x12, 0x0b, 0x0a, 0x07, 0x45, 0x58, 0x50, 0x49, 0x52,
+	0x45, 0x44, 0x10, 0x03, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x45, 0x54, 0x54, 0x4c, 0x45, 0x44, 0x10,
+	0x04, 0x2a, 0x2b, 0x0a, 0x04, 0x53, 0x69, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x0c, 0x53, 0x49, 0x44,
+	0x45, 0x5f, 0x55, 0x4e, 0x4b, 0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x42,
+	0x55, 0x59, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x53, 0x45, 0x4c, 0x4c, 0x10, 0x02, 0x2a, 0xa1,
+	0x01, 0x0a, 0x10, 0x4a, 0x6f, 0x75, 0x72, 0x6e, 0x61, 0x6c, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x54,
+	0x79, 0x70, 0x65, 0x12, 0x11, 0x0a, 0x0d, 0x45, 0x4e, 0x54, 0x52, 0x59, 0x5f, 0x55, 0x4e, 0x4b,
+	0x4e, 0x4f, 0x57, 0x4e, 0x10, 0x00, 0x12, 0x11, 0x0a, 0x0d, 0x4f, 0x52, 0x44, 0x45, 0x52, 0x5f,
+	0x43, 0x52, 0x45, 0x41, 0x54, 0x45, 0x44, 0x10, 0x01, 0x12, 0x13, 0x0a, 0x0f, 0x4f, 0x52, 0x44,
+	0x45, 0x52, 0x5f, 0x43, 0x41, 0x4e, 0x43, 0x45, 0x4c, 0x4c, 0x45, 0x44, 0x10, 0x02, 0x12, 0x10,
+	0x0a, 0x0c, 0x4f, 0x52, 0x44, 0x45, 0x52, 0x5f, 0x46, 0x49, 0x4c, 0x4c, 0x45, 0x44, 0x10, 0x03,
+	0x12, 0x0d, 0x0a, 0x09, 0x48, 0x45, 0x41, 0x52, 0x54, 0x42, 0x45, 0x41, 0x54, 0x10, 0x04, 0x12,
+	0x08, 0x0a, 0x04, 0x54, 0x49, 0x43, 0x4b, 0x10, 0x05, 0x12, 0x11, 0x0a, 0x0d, 0x4f, 0x52, 0x44,
+	0x45, 0x52, 0x5f, 0x45, 0x58, 0x50, 0x49, 0x52, 0x45, 0x44, 0x10, 0x06, 0x12, 0x14, 0x0a, 0x10,
+	0x50, 0x4f, 0x53, 0x49, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x53, 0x45, 0x54, 0x54, 0x4c, 0x45, 0x44,
+	0x10, 0x07, 0x32, 0x80, 0x05, 0x0a, 0x08, 0x45, 0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x12,
+	0x2c, 0x0a, 0x09, 0x53, 0x74, 0x61, 0x74, 0x69, 0x73, 0x74, 0x69, 0x63, 0x12, 0x0e, 0x2e, 0x6d,
+	0x61, 0x69, 0x6e, 0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x1a, 0x0b, 0x2e, 0x6d,
+	0x61, 0x69, 0x6e, 0x2e, 0x4f, 0x48, 0x4c, 0x43, 0x56, 0x22, 0x00, 0x30, 0x01, 0x12, 0x24, 0x0a,
+	0x06, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x12, 0x0a, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x44,
+	0x65, 0x61, 0x6c, 0x1a, 0x0c, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x49,
+	0x44, 0x22, 0x00, 0x12, 0x2c, 0x0a, 0x06, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x12, 0x0c, 0x2e,
+	0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x49, 0x44, 0x1a, 0x12, 0x2e, 0x6d, 0x61,
+	0x69, 0x6e, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x22,
+	0x00, 0x12, 0x29, 0x0a, 0x07, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x0e, 0x2e, 0x6d,
+	0x61, 0x69, 0x6e, 0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65, 0x72, 0x49, 0x44, 0x1a, 0x0a, 0x2e, 0x6d,
+	0x61, 0x69, 0x6e, 0x2e, 0x44, 0x65, 0x61, 0x6c, 0x22, 0x00, 0x30, 0x01, 0x12, 0x3b, 0x0a, 0x0a,
+	0x47, 0x65, 0x74, 0x43, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x73, 0x12, 0x14, 0x2e, 0x6d, 0x61, 0x69,
+	0x6e, 0x2e, 0x43, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x15, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x43, 0x61, 0x6e, 0x64, 0x6c, 0x65, 0x73, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x29, 0x0a, 0x06, 0x54, 0x72, 0x61,
+	0x64, 0x65, 0x73, 0x12, 0x0e, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x42, 0x72, 0x6f, 0x6b, 0x65,
+	0x72, 0x49, 0x44, 0x1a, 0x0b, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x54, 0x72, 0x61, 0x64, 0x65,
+	0x22, 0x00, 0x30, 0x01, 0x12, 0x39, 0x0a, 0x08, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x69, 0x6e, 0x67,
+	0x12, 0x15, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x43, 0x6c, 0x65, 0x61, 0x72, 0x69, 0x6e, 0x67,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x43,
+	0x6c, 0x65, 0x61, 0x72, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x22, 0x00, 0x12,
+	0x35, 0x0a, 0x06, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x12, 0x13, 0x2e, 0x6d, 0x61, 0x69, 0x6e,
+	0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x14,
+	0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x4f, 0x72, 0x64, 0x65, 0x72, 0x73, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x3b, 0x0a, 0x09, 0x52, 0x65, 0x70, 0x6c, 0x69, 0x63,
+	0x61, 0x74, 0x65, 0x12, 0x16, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x52, 0x65, 0x70, 0x6c, 0x69,
+	0x63, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x6d, 0x61,
+	0x69, 0x6e, 0x2e, 0x4a, 0x6f, 0x75, 0x72, 0x6e, 0x61, 0x6c, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x22,
+	0x00, 0x30, 0x01, 0x12, 0x38, 0x0a, 0x07, 0x50, 0x72, 0x6f, 0x6d, 0x6f, 0x74, 0x65, 0x12, 0x14,
+	0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x50, 0x72, 0x6f, 0x6d, 0x6f, 0x74, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x15, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x50, 0x72, 0x6f, 0x6d,
+	0x6f, 0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x22, 0x00, 0x12, 0x36, 0x0a,
+	0x08, 0x53, 0x65, 0x74, 0x43, 0x68, 0x61, 0x6f, 0x73, 0x12, 0x13, 0x2e, 0x6d, 0x61, 0x69, 0x6e,
+	0x2e, 0x43, 0x68, 0x61, 0x6f, 0x73, 0x53, 0x65, 0x74, 0x74, 0x69, 0x6e, 0x67, 0x73, 0x1a, 0x13,
+	0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x43, 0x68, 0x61, 0x6f, 0x73, 0x53, 0x65, 0x74, 0x74, 0x69,
+	0x6e, 0x67, 0x73, 0x22, 0x00, 0x12, 0x3e, 0x0a, 0x09, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x61, 0x63,
+	0x74, 0x73, 0x12, 0x16, 0x2e, 0x6d, 0x61, 0x69, 0x6e, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x61,
+	0x63, 0x74, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x6d, 0x61, 0x69,
+	0x6e, 0x2e, 0x43, 0x6f, 0x6e, 0x74, 0x72, 0x61, 0x63, 0x74, 0x73, 0x52, 0x65, 0x73, 0x70, 0x6f,
+	0x6e, 0x73, 0x65, 0x22, 0x00, 0x42, 0x10, 0x5a, 0x0e, 0x2e, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x65,
+	0x78, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_api_exchange_exchange_proto_rawDescOnce sync.Once
+	file_api_exchange_exchange_proto_rawDescData = file_api_exchange_exchange_proto_rawDesc
+)
+
+func file_api_exchange_exchange_proto_rawDescGZIP() []byte {
+	file_api_exchange_exchange_proto_rawDescOnce.Do(func() {
+		file_api_exchange_exchange_proto_rawDescData = protoimpl.X.CompressGZIP(file_api_exchange_exchange_proto_rawDescData)
+	})
+	return file_api_exchange_exchange_proto_rawDescData
+}
+
+var file_api_exchange_exchange_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_api_exchange_exchange_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_api_exchange_exchange_proto_goTypes = []interface{}{
+	(OrderStatus)(0),             // 0: main.OrderStatus
+	(Side)(0),                    // 1: main.Side
+	(JournalEntryType)(0),        // 2: main.JournalEntryType
+	(*OHLCV)(nil),                // 3: main.OHLCV
+	(*Deal)(nil),                 // 4: main.Deal
+	(*DealID)(nil),               // 5: main.DealID
+	(*BrokerID)(nil),             // 6: main.BrokerID
+	(*CancelResult)(nil),         // 7: main.CancelResult
+	(*CandlesRequest)(nil),       // 8: main.CandlesRequest
+	(*CandlesResponse)(nil),      // 9: main.CandlesResponse
+	(*Trade)(nil),                // 10: main.Trade
+	(*ClearingRequest)(nil),      // 11: main.ClearingRequest
+	(*ClearingPosition)(nil),     // 12: main.ClearingPosition
+	(*InstrumentSettlement)(nil), // 13: main.InstrumentSettlement
+	(*ClearingReport)(nil),       // 14: main.ClearingReport
+	(*OrdersRequest)(nil),        // 15: main.OrdersRequest
+	(*OrdersResponse)(nil),       // 16: main.OrdersResponse
+	(*JournalTick)(nil),          // 17: main.JournalTick
+	(*JournalEntry)(nil),         // 18: main.JournalEntry
+	(*ReplicateRequest)(nil),     // 19: main.ReplicateRequest
+	(*PromoteRequest)(nil),       // 20: main.PromoteRequest
+	(*PromoteResponse)(nil),      // 21: main.PromoteResponse
+	(*ChaosSettings)(nil),        // 22: main.ChaosSettings
+	(*ContractsRequest)(nil),     // 23: main.ContractsRequest
+	(*Contract)(nil),             // 24: main.Contract
+	(*ContractsResponse)(nil),    // 25: main.ContractsResponse
+}
+var file_api_exchange_exchange_proto_depIdxs = []int32{
+	0,  // 0: main.Deal.Status:type_name -> main.OrderStatus
+	3,  // 1: main.CandlesResponse.Candles:type_name -> main.OHLCV
+	1,  // 2: main.Trade.Aggressor:type_name -> main.Side
+	12, // 3: main.ClearingReport.Positions:type_name -> main.ClearingPosition
+	13, // 4: main.ClearingReport.Instruments:type_name -> main.InstrumentSettlement
+	4,  // 5: main.OrdersResponse.Orders:type_name -> main.Deal
+	2,  // 6: main.JournalEntry.Type:type_name -> main.JournalEntryType
+	4,  // 7: main.JournalEntry.Deal:type_name -> main.Deal
+	1,  // 8: main.JournalEntry.Side:type_name -> main.Side
+	17, // 9: main.JournalEntry.Tick:type_name -> main.JournalTick
+	24, // 10: main.ContractsResponse.Contracts:type_name -> main.Contract
+	6,  // 11: main.Exchange.Statistic:input_type -> main.BrokerID
+	4,  // 12: main.Exchange.Create:input_type -> main.Deal
+	5,  // 13: main.Exchange.Cancel:input_type -> main.DealID
+	6,  // 14: main.Exchange.Results:input_type -> main.BrokerID
+	8,  // 15: main.Exchange.GetCandles:input_type -> main.CandlesRequest
+	6,  // 16: main.Exchange.Trades:input_type -> main.BrokerID
+	11, // 17: main.Exchange.Clearing:input_type -> main.ClearingRequest
+	15, // 18: main.Exchange.Orders:input_type -> main.OrdersRequest
+	19, // 19: main.Exchange.Replicate:input_type -> main.ReplicateRequest
+	20, // 20: main.Exchange.Promote:input_type -> main.PromoteRequest
+	22, // 21: main.Exchange.SetChaos:input_type -> main.ChaosSettings
+	23, // 22: main.Exchange.Contracts:input_type -> main.ContractsRequest
+	3,  // 23: main.Exchange.Statistic:output_type -> main.OHLCV
+	5,  // 24: main.Exchange.Create:output_type -> main.DealID
+	7,  // 25: main.Exchange.Cancel:output_type -> main.CancelResult
+	4,  // 26: main.Exchange.Results:output_type -> main.Deal
+	9,  // 27: main.Exchange.GetCandles:output_type -> main.CandlesResponse
+	10, // 28: main.Exchange.Trades:output_type -> main.Trade
+	14, // 29: main.Exchange.Clearing:output_type -> main.ClearingReport
+	16, // 30: main.Exchange.Orders:output_type -> main.OrdersResponse
+	18, // 31: main.Exchange.Replicate:output_type -> main.JournalEntry
+	21, // 32: main.Exchange.Promote:output_type -> main.PromoteResponse
+	22, // 33: main.Exchange.SetChaos:output_type -> main.ChaosSettings
+	25, // 34: main.Exchange.Contracts:output_type -> main.ContractsResponse
+	23, // [23:35] is the sub-list for method output_type
+	11, // [11:23] is the sub-list for method input_type
+	11, // [11:11] is the sub-list for extension type_name
+	11, // [11:11] is the sub-list for extension extendee
+	0,  // [0:11] is the sub-list for field type_name
+}
+
+func init() { file_api_exchange_exchange_proto_init() }
+func file_api_exchange_exchange_proto_init() {
+	if File_api_exchange_exchange_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_api_exchange_exchange_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OHLCV); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Deal); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*DealID); i {
 			case 0:
 				return &v.state
@@ -539,19 +2289,236 @@ func file_api_exchange_exchange_proto_init() {
 				return nil
 			}
 		}
+		file_api_exchange_exchange_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CandlesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CandlesResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Trade); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClearingRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClearingPosition); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InstrumentSettlement); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClearingReport); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OrdersRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*OrdersResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*JournalTick); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*JournalEntry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReplicateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PromoteRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PromoteResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ChaosSettings); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ContractsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Contract); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_api_exchange_exchange_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ContractsResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_api_exchange_exchange_proto_rawDesc,
-			NumEnums:      0,
-			NumMessages:   5,
+			NumEnums:      3,
+			NumMessages:   23,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_api_exchange_exchange_proto_goTypes,
 		DependencyIndexes: file_api_exchange_exchange_proto_depIdxs,
+		EnumInfos:         file_api_exchange_exchange_proto_enumTypes,
 		MessageInfos:      file_api_exchange_exchange_proto_msgTypes,
 	}.Build()
 	File_api_exchange_exchange_proto = out.File
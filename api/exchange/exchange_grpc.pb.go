@@ -33,6 +33,25 @@ type ExchangeClient interface {
 	// исполнение заявок от биржи к брокеру
 	// устанавливается 1 раз брокером и при исполнении какой-то заявки
 	Results(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_ResultsClient, error)
+	// исторические свечи, которые биржа хранит по каждому тикеру и интервалу
+	// нужны брокеру чтобы заполнить пропуски после простоя
+	GetCandles(ctx context.Context, in *CandlesRequest, opts ...grpc.CallOption) (*CandlesResponse, error)
+	// публичная лента всех сделок биржи (time and sales)
+	// медленный подписчик отключается, а не тормозит остальных
+	Trades(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_TradesClient, error)
+	// клиринговый отчет за торговый день для сверки позиций брокера
+	Clearing(ctx context.Context, in *ClearingRequest, opts ...grpc.CallOption) (*ClearingReport, error)
+	// активные заявки брокера в стаканах биржи
+	Orders(ctx context.Context, in *OrdersRequest, opts ...grpc.CallOption) (*OrdersResponse, error)
+	// журнал заявок и исполнений для резервной биржи, сначала записи после AfterSeq, потом новые
+	Replicate(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (Exchange_ReplicateClient, error)
+	// резервная биржа становится основной: перестает читать журнал, запускает торги
+	// и принимает заявки и Results
+	Promote(ctx context.Context, in *PromoteRequest, opts ...grpc.CallOption) (*PromoteResponse, error)
+	// включает, меняет или выключает (все нули) режим сбоев, возвращает примененные настройки
+	SetChaos(ctx context.Context, in *ChaosSettings, opts ...grpc.CallOption) (*ChaosSettings, error)
+	// серии фьючерсов с датами экспирации и текущими ближайшими сериями непрерывных тикеров
+	Contracts(ctx context.Context, in *ContractsRequest, opts ...grpc.CallOption) (*ContractsResponse, error)
 }
 
 type exchangeClient struct {
@@ -125,6 +144,124 @@ func (x *exchangeResultsClient) Recv() (*Deal, error) {
 	return m, nil
 }
 
+func (c *exchangeClient) GetCandles(ctx context.Context, in *CandlesRequest, opts ...grpc.CallOption) (*CandlesResponse, error) {
+	out := new(CandlesResponse)
+	err := c.cc.Invoke(ctx, "/main.Exchange/GetCandles", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeClient) Trades(ctx context.Context, in *BrokerID, opts ...grpc.CallOption) (Exchange_TradesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Exchange_ServiceDesc.Streams[2], "/main.Exchange/Trades", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exchangeTradesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Exchange_TradesClient interface {
+	Recv() (*Trade, error)
+	grpc.ClientStream
+}
+
+type exchangeTradesClient struct {
+	grpc.ClientStream
+}
+
+func (x *exchangeTradesClient) Recv() (*Trade, error) {
+	m := new(Trade)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *exchangeClient) Clearing(ctx context.Context, in *ClearingRequest, opts ...grpc.CallOption) (*ClearingReport, error) {
+	out := new(ClearingReport)
+	err := c.cc.Invoke(ctx, "/main.Exchange/Clearing", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeClient) Orders(ctx context.Context, in *OrdersRequest, opts ...grpc.CallOption) (*OrdersResponse, error) {
+	out := new(OrdersResponse)
+	err := c.cc.Invoke(ctx, "/main.Exchange/Orders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeClient) Replicate(ctx context.Context, in *ReplicateRequest, opts ...grpc.CallOption) (Exchange_ReplicateClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Exchange_ServiceDesc.Streams[3], "/main.Exchange/Replicate", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &exchangeReplicateClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Exchange_ReplicateClient interface {
+	Recv() (*JournalEntry, error)
+	grpc.ClientStream
+}
+
+type exchangeReplicateClient struct {
+	grpc.ClientStream
+}
+
+func (x *exchangeReplicateClient) Recv() (*JournalEntry, error) {
+	m := new(JournalEntry)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *exchangeClient) Promote(ctx context.Context, in *PromoteRequest, opts ...grpc.CallOption) (*PromoteResponse, error) {
+	out := new(PromoteResponse)
+	err := c.cc.Invoke(ctx, "/main.Exchange/Promote", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeClient) SetChaos(ctx context.Context, in *ChaosSettings, opts ...grpc.CallOption) (*ChaosSettings, error) {
+	out := new(ChaosSettings)
+	err := c.cc.Invoke(ctx, "/main.Exchange/SetChaos", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exchangeClient) Contracts(ctx context.Context, in *ContractsRequest, opts ...grpc.CallOption) (*ContractsResponse, error) {
+	out := new(ContractsResponse)
+	err := c.cc.Invoke(ctx, "/main.Exchange/Contracts", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ExchangeServer is the server API for Exchange service.
 // All implementations must embed UnimplementedExchangeServer
 // for forward compatibility
@@ -140,6 +277,25 @@ type ExchangeServer interface {
 	// исполнение заявок от биржи к брокеру
 	// устанавливается 1 раз брокером и при исполнении какой-то заявки
 	Results(*BrokerID, Exchange_ResultsServer) error
+	// исторические свечи, которые биржа хранит по каждому тикеру и интервалу
+	// нужны брокеру чтобы заполнить пропуски после простоя
+	GetCandles(context.Context, *CandlesRequest) (*CandlesResponse, error)
+	// публичная лента всех сделок биржи (time and sales)
+	// медленный подписчик отключается, а не тормозит остальных
+	Trades(*BrokerID, Exchange_TradesServer) error
+	// клиринговый отчет за торговый день для сверки позиций брокера
+	Clearing(context.Context, *ClearingRequest) (*ClearingReport, error)
+	// активные заявки брокера в стаканах биржи
+	Orders(context.Context, *OrdersRequest) (*OrdersResponse, error)
+	// журнал заявок и исполнений для резервной биржи, сначала записи после AfterSeq, потом новые
+	Replicate(*ReplicateRequest, Exchange_ReplicateServer) error
+	// резервная биржа становится основной: перестает читать журнал, запускает торги
+	// и принимает заявки и Results
+	Promote(context.Context, *PromoteRequest) (*PromoteResponse, error)
+	// включает, меняет или выключает (все нули) режим сбоев, возвращает примененные настройки
+	SetChaos(context.Context, *ChaosSettings) (*ChaosSettings, error)
+	// серии фьючерсов с датами экспирации и текущими ближайшими сериями непрерывных тикеров
+	Contracts(context.Context, *ContractsRequest) (*ContractsResponse, error)
 	mustEmbedUnimplementedExchangeServer()
 }
 
@@ -159,6 +315,30 @@ func (UnimplementedExchangeServer) Cancel(context.Context, *DealID) (*CancelResu
 func (UnimplementedExchangeServer) Results(*BrokerID, Exchange_ResultsServer) error {
 	return status.Errorf(codes.Unimplemented, "method Results not implemented")
 }
+func (UnimplementedExchangeServer) GetCandles(context.Context, *CandlesRequest) (*CandlesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCandles not implemented")
+}
+func (UnimplementedExchangeServer) Trades(*BrokerID, Exchange_TradesServer) error {
+	return status.Errorf(codes.Unimplemented, "method Trades not implemented")
+}
+func (UnimplementedExchangeServer) Clearing(context.Context, *ClearingRequest) (*ClearingReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Clearing not implemented")
+}
+func (UnimplementedExchangeServer) Orders(context.Context, *OrdersRequest) (*OrdersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Orders not implemented")
+}
+func (UnimplementedExchangeServer) Replicate(*ReplicateRequest, Exchange_ReplicateServer) error {
+	return status.Errorf(codes.Unimplemented, "method Replicate not implemented")
+}
+func (UnimplementedExchangeServer) Promote(context.Context, *PromoteRequest) (*PromoteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Promote not implemented")
+}
+func (UnimplementedExchangeServer) SetChaos(context.Context, *ChaosSettings) (*ChaosSettings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetChaos not implemented")
+}
+func (UnimplementedExchangeServer) Contracts(context.Context, *ContractsRequest) (*ContractsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Contracts not implemented")
+}
 func (UnimplementedExchangeServer) mustEmbedUnimplementedExchangeServer() {}
 
 // UnsafeExchangeServer may be embedded to opt out of forward compatibility for this service.
@@ -250,6 +430,156 @@ func (x *exchangeResultsServer) Send(m *Deal) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Exchange_GetCandles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CandlesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServer).GetCandles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Exchange/GetCandles",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServer).GetCandles(ctx, req.(*CandlesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Exchange_Trades_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BrokerID)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExchangeServer).Trades(m, &exchangeTradesServer{stream})
+}
+
+type Exchange_TradesServer interface {
+	Send(*Trade) error
+	grpc.ServerStream
+}
+
+type exchangeTradesServer struct {
+	grpc.ServerStream
+}
+
+func (x *exchangeTradesServer) Send(m *Trade) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Exchange_Clearing_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServer).Clearing(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Exchange/Clearing",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServer).Clearing(ctx, req.(*ClearingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Exchange_Orders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServer).Orders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Exchange/Orders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServer).Orders(ctx, req.(*OrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Exchange_Replicate_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReplicateRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ExchangeServer).Replicate(m, &exchangeReplicateServer{stream})
+}
+
+type Exchange_ReplicateServer interface {
+	Send(*JournalEntry) error
+	grpc.ServerStream
+}
+
+type exchangeReplicateServer struct {
+	grpc.ServerStream
+}
+
+func (x *exchangeReplicateServer) Send(m *JournalEntry) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Exchange_Promote_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromoteRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServer).Promote(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Exchange/Promote",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServer).Promote(ctx, req.(*PromoteRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Exchange_SetChaos_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChaosSettings)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServer).SetChaos(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Exchange/SetChaos",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServer).SetChaos(ctx, req.(*ChaosSettings))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Exchange_Contracts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ContractsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExchangeServer).Contracts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/main.Exchange/Contracts",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExchangeServer).Contracts(ctx, req.(*ContractsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Exchange_ServiceDesc is the grpc.ServiceDesc for Exchange service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -265,6 +595,30 @@ var Exchange_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Cancel",
 			Handler:    _Exchange_Cancel_Handler,
 		},
+		{
+			MethodName: "GetCandles",
+			Handler:    _Exchange_GetCandles_Handler,
+		},
+		{
+			MethodName: "Clearing",
+			Handler:    _Exchange_Clearing_Handler,
+		},
+		{
+			MethodName: "Orders",
+			Handler:    _Exchange_Orders_Handler,
+		},
+		{
+			MethodName: "Promote",
+			Handler:    _Exchange_Promote_Handler,
+		},
+		{
+			MethodName: "SetChaos",
+			Handler:    _Exchange_SetChaos_Handler,
+		},
+		{
+			MethodName: "Contracts",
+			Handler:    _Exchange_Contracts_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -277,6 +631,16 @@ var Exchange_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Exchange_Results_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "Trades",
+			Handler:       _Exchange_Trades_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Replicate",
+			Handler:       _Exchange_Replicate_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "api/exchange/exchange.proto",
 }
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"crypto/tls"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/KSerditov/Trading/api/exchange"
 	"github.com/KSerditov/Trading/pkg/broker/orders"
 	"github.com/KSerditov/Trading/pkg/broker/router"
 	"github.com/KSerditov/Trading/pkg/broker/session"
 	"github.com/KSerditov/Trading/pkg/broker/user"
+	"github.com/KSerditov/Trading/pkg/tlsutil"
 )
 
 /* TBD FOR BROKER
@@ -20,7 +26,7 @@ import (
 
 6. Missing cookie save
 
-7. No way to obtain missed data due to broker/connection failure
+7. No way to obtain missed deal results due to broker/connection failure
 
 8. Check amounts, balance int overflows
 
@@ -30,7 +36,7 @@ import (
 
 11. Move completed order to order_history
 
-12. Automatic stream reconnect for Statistics/Results listeners
+12. Automatic stream reconnect for Statistics listener
 
 13. Data types are not consistent between broker and exchange
 */
@@ -60,12 +66,51 @@ func main() {
 		)
 	}
 
+	// TLS to exchange is enabled by BROKER_EXCHANGE_CA, BROKER_TLS_CERT and BROKER_TLS_KEY add client certificate for mTLS
+	var exchTLS *tls.Config
+	if ca := os.Getenv("BROKER_EXCHANGE_CA"); ca != "" {
+		var tlserr error
+		exchTLS, tlserr = tlsutil.ClientConfig(ca, os.Getenv("BROKER_TLS_CERT"), os.Getenv("BROKER_TLS_KEY"), os.Getenv("BROKER_EXCHANGE_SERVER_NAME"))
+		if tlserr != nil {
+			// app logger is not initialized yet
+			log.Fatalf("failed to load tls config for exchange connection: %v", tlserr)
+		}
+	}
+	app.ExchangeTLS = exchTLS
+
+	// BROKER_EXCHANGE_ADDRESS lists primary and standby exchanges separated by comma,
+	// orders and Results go to the one which is serving
+	exchAddress := os.Getenv("BROKER_EXCHANGE_ADDRESS")
+	if exchAddress == "" {
+		exchAddress = "127.0.0.1:8082"
+	}
+	app.ExchangeAddress = exchAddress
+
+	// BROKER_SETTLEMENT=futures blocks BROKER_INITIAL_MARGIN part of position value
+	// and pays daily variation margin at exchange clearing instead of full value of each fill
+	var initialMargin float64
+	if v := os.Getenv("BROKER_INITIAL_MARGIN"); v != "" {
+		var perr error
+		initialMargin, perr = strconv.ParseFloat(v, 64)
+		if perr != nil {
+			log.Fatalf("failed to parse BROKER_INITIAL_MARGIN: %v", perr)
+		}
+	}
+	settlement, serr := orders.NewSettlement(os.Getenv("BROKER_SETTLEMENT"), initialMargin)
+	if serr != nil {
+		log.Fatalf("failed to set up settlement: %v", serr)
+	}
+	app.Settlement = settlement
+
 	ol := orders.OrdersListener{
-		ExchServerAddress: "127.0.0.1:8082",
+		ExchServerAddress: exchAddress,
 		BrokerID: &exchange.BrokerID{
 			ID: 123,
 		},
 		OrdersRepository: o,
+		BackfillDepth:    15 * time.Minute,
+		TLS:              exchTLS,
+		Settlement:       settlement,
 	}
 	ol.Start()
 
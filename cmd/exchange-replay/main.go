@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/config"
+	"github.com/KSerditov/Trading/pkg/exchange/server"
+)
+
+// repeats matching of session from journal written by exchange with journal.dir set and compares fills,
+// exits with 1 if replay filled orders differently
+func main() {
+	configPath := flag.String("config", os.Getenv("EXCHANGE_CONFIG"), "config exchange ran with, matching and simulation are taken from it")
+	journalPath := flag.String("journal", "", "journal file of exchange, journal-<start time>.bin")
+	stop := flag.Int64("stop", 0, "stop after entry with this seq and print books, replay whole journal if 0")
+	ticker := flag.String("ticker", "", "print book and differences of this ticker only")
+	flag.Parse()
+	if *journalPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	diffs, err := replay(*configPath, *journalPath, *stop, *ticker)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	if diffs > 0 {
+		os.Exit(1)
+	}
+}
+
+func replay(configPath string, journalPath string, stop int64, ticker string) (int, error) {
+	cfg, err := config.Read(configPath)
+	if err != nil {
+		return 0, err
+	}
+	matchers, err := cfg.Matchers()
+	if err != nil {
+		return 0, err
+	}
+	sim := cfg.SimulationModel()
+	err = sim.Validate()
+	if err != nil {
+		return 0, err
+	}
+
+	journal, err := server.OpenJournal(journalPath)
+	if err != nil {
+		return 0, err
+	}
+	defer journal.Close()
+
+	r := server.NewReplayer(matchers, sim)
+	defer r.Close()
+
+	for {
+		entry, err := journal.Next()
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, server.ErrorJournalTruncated) {
+			fmt.Printf("journal is cut after seq %v, exchange stopped while writing it\n", r.Seq())
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if stop > 0 && entry.Seq > stop {
+			// fills of the last ticks may be written after the stop
+			for more := r.Tail(entry); more; more = r.Tail(entry) {
+				entry, err = journal.Next()
+				if err != nil {
+					break
+				}
+			}
+			break
+		}
+
+		err = r.Apply(entry)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if stop > 0 {
+		fmt.Printf("books after seq %v:\n", r.Seq())
+		for _, t := range r.Tickers() {
+			if ticker != "" && t != ticker {
+				continue
+			}
+			printBook(t, r.Book(t))
+		}
+		fmt.Println()
+	}
+
+	diffs := r.Diff()
+	shown := 0
+	for _, d := range diffs {
+		if ticker != "" && d.Ticker != ticker {
+			continue
+		}
+		shown++
+		fmt.Printf("%v fill %v differs:\n  recorded %v\n  replayed %v\n", d.Ticker, d.N, formatFill(d.Recorded), formatFill(d.Replayed))
+	}
+
+	recorded, replayed := r.Fills()
+	fmt.Printf("replayed %v entries: %v fills recorded, %v replayed, %v differ\n", r.Seq(), recorded, replayed, len(diffs))
+	return shown, nil
+}
+
+func printBook(ticker string, orders []server.ReplayOrder) {
+	fmt.Printf("%v: %v orders\n", ticker, len(orders))
+	for _, o := range orders {
+		d := o.Deal
+		side, price := "BUY", d.Price
+		if price < 0 {
+			side, price = "SELL", -price
+		}
+		line := fmt.Sprintf("  %-4v %v x %v order %v broker %v client %v", side, price, d.Volume, d.ID, d.BrokerID, d.ClientID)
+		if d.DisplayVolume > 0 && d.DisplayVolume < d.Volume {
+			line += fmt.Sprintf(" display %v", d.DisplayVolume)
+		}
+		if d.CumVolume > 0 {
+			line += fmt.Sprintf(" filled %v avg %v", d.CumVolume, d.AvgPrice)
+		}
+		if o.Ahead > 0 {
+			line += fmt.Sprintf(" ahead %v", o.Ahead)
+		}
+		if !o.ActiveAt.IsZero() {
+			line += fmt.Sprintf(" active from %v", o.ActiveAt.Format("15:04:05.000"))
+		}
+		fmt.Println(line)
+	}
+}
+
+func formatFill(e *exchange.JournalEntry) string {
+	if e == nil {
+		return "none"
+	}
+	d := e.Deal
+	return fmt.Sprintf("seq %v %v order %v broker %v client %v: %v @ %v at %v, filled %v leaves %v %v",
+		e.Seq, e.Side, d.ID, d.BrokerID, d.ClientID, d.Volume, d.Price, time.Unix(int64(d.Time), 0).Format("15:04:05"),
+		d.CumVolume, d.LeavesVolume, d.Status)
+}
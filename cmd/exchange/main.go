@@ -2,48 +2,257 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/candles"
+	"github.com/KSerditov/Trading/pkg/exchange/clearing"
+	"github.com/KSerditov/Trading/pkg/exchange/config"
+	"github.com/KSerditov/Trading/pkg/exchange/metrics"
 	"github.com/KSerditov/Trading/pkg/exchange/server"
 	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"github.com/KSerditov/Trading/pkg/tlsutil"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 /* TBD FOR EXCHANGE
 1. Use channel to translate new tickers from tickers_inmem to trader and exchange statistics sender
 (tickers_inmem has infinite cycle that sends slice elements only if it fits time)
 
-2. Add authentication (brokerid - key based?)
-
-3. Add logging
-
 4. Validate nonunique broker id connections
 
 5. Write tests (consider separationg of trader layer from grpc server)
 
-6. Add configurations
-
-7. Last partial deal should return partial = false
-
 8. Initialize DB instance and store everything there
 
 9. Data types are not consistent between broker and exchange
 */
 
 func main() {
-	tickers := &tickers.TickersSourceInMem{
-		FilePaths:    []string{`.\assets\SPFB.RTS_190517_190517.txt`, `.\assets\SPFB.Si_190517_190517.txt`},
-		UseTodayDate: true,
+	configPath := flag.String("config", os.Getenv("EXCHANGE_CONFIG"), "path to exchange config file, yaml or json")
+	promote := flag.Bool("promote", false, "promote standby listening on config address to primary and exit")
+	chaos := flag.String("chaos", "", `set chaos settings of exchange listening on config address and exit, json like '{"DropProbability": 0.1}', '{}' turns chaos off`)
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	err := tickers.Init()
+
+	logger, err := cfg.NewLogger()
 	if err != nil {
 		fmt.Println(err)
+		os.Exit(1)
+	}
+	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
+
+	tickers := newTickersSource(cfg, logger.Sugar())
+	var tlsConfig, primaryTLS *tls.Config
+	if cfg.TLS.Cert != "" {
+		tlsConfig, err = tlsutil.ServerConfig(cfg.TLS.Cert, cfg.TLS.Key, cfg.TLS.ClientCA)
+		if err != nil {
+			logger.Fatal("failed to load tls config", zap.Error(err))
+		}
+		// standby shows its own certificate to primary
+		primaryTLS, err = tlsutil.ClientConfig(cfg.TLS.ClientCA, cfg.TLS.Cert, cfg.TLS.Key, "")
+		if err != nil {
+			logger.Fatal("failed to load tls config for primary", zap.Error(err))
+		}
+	}
+
+	if *promote {
+		err = promoteStandby(cfg.Listen, primaryTLS, cfg.Replication.Consumer)
+		if err != nil {
+			logger.Fatal("failed to promote standby", zap.Error(err))
+		}
+		return
+	}
+	if *chaos != "" {
+		err = setChaos(cfg.Listen, primaryTLS, cfg.Replication.Consumer, *chaos)
+		if err != nil {
+			logger.Fatal("failed to set chaos settings", zap.Error(err))
+		}
+		return
+	}
+
+	// tickers are loaded in background, health reports serving when done
+	tickers.Prepare()
+
+	intervals := make([]time.Duration, 0, len(cfg.Candles.Intervals))
+	for _, iv := range cfg.Candles.Intervals {
+		intervals = append(intervals, time.Duration(iv)*time.Second)
+	}
+	history := &candles.CandlesInMem{
+		BarIntervals: intervals,
+		Retention:    cfg.Candles.Retention,
+	}
+	err = history.Init()
+	if err != nil {
+		logger.Fatal("failed to init candles storage", zap.Error(err))
+	}
+	history.Start(tickers.GetFeedChannel())
+
+	matchers, err := cfg.Matchers()
+	if err != nil {
+		logger.Fatal("failed to build matching", zap.Error(err))
+	}
+
+	schedule, err := cfg.ContractSchedule()
+	if err != nil {
+		logger.Fatal("failed to build contracts schedule", zap.Error(err))
+	}
+
+	closeAt, _ := cfg.ClearingCloseAt()
+	clearingHouse := &clearing.ClearingInMem{
+		Dir:     cfg.Clearing.Dir,
+		CloseAt: closeAt,
+		Fees:    cfg.Fees(),
+		Logger:  logger.Sugar(),
 	}
+	err = clearingHouse.Init()
+	if err != nil {
+		logger.Fatal("failed to init clearing", zap.Error(err))
+	}
+	clearingHouse.Start()
 
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
-	err = server.Start(ctx, `127.0.0.1:8082`, ``, tickers)
+	go func() {
+		err := tickers.Load()
+		if err != nil {
+			logger.Error("failed to load tickers", zap.Error(err))
+			cancel()
+		}
+	}()
+
+	if cfg.MetricsListen != "" {
+		go func() {
+			logger.Info("serving metrics", zap.String("addr", cfg.MetricsListen))
+			err := metrics.Serve(ctx, cfg.MetricsListen)
+			if err != nil {
+				logger.Error("metrics server stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	err = server.StartWithConfig(ctx, server.Config{
+		ListenAddr:       cfg.Listen,
+		BufferSize:       cfg.BufferSize,
+		StatisticMaxRate: cfg.Statistic.MaxRate,
+		ACL:              cfg.ACL,
+		Logger:           logger.Sugar(),
+		Candles:          history,
+		DrainTimeout:     time.Duration(cfg.DrainTimeout) * time.Second,
+		TLS:              tlsConfig,
+		BrokerCerts:      cfg.TLS.Brokers,
+		AdminCerts:       cfg.TLS.Admins,
+		Matchers:         matchers,
+		Contracts:        schedule,
+		Clearing:         clearingHouse,
+		Simulation:       cfg.SimulationModel(),
+		HTTPListen:       cfg.HTTPListen,
+		ConsoleListen:    cfg.ConsoleListen,
+
+		Primary:         cfg.Replication.Primary,
+		PrimaryTLS:      primaryTLS,
+		PrimaryConsumer: cfg.Replication.Consumer,
+		Lease:           time.Duration(cfg.Replication.Lease) * time.Second,
+		Chaos:           cfg.ChaosSettings(),
+
+		JournalDir: cfg.Journal.Dir,
+	}, tickers)
 	if err != nil {
-		fmt.Println(err)
+		logger.Error("exchange server stopped", zap.Error(err))
+	}
+}
+
+// tickers source which loads its data in background after Prepare
+type tickersSource interface {
+	tickers.TickersSource
+	Prepare()
+	Load() error
+}
+
+func newTickersSource(cfg *config.Config, logger *zap.SugaredLogger) tickersSource {
+	if cfg.Tickers.Source == config.SourceBinary {
+		return &tickers.TickersSourceBinary{
+			FilePaths:    cfg.Tickers.Files,
+			UseTodayDate: cfg.Tickers.Replay.UseTodayDate,
+			Speed:        cfg.Tickers.Replay.Speed,
+			BufferSize:   cfg.Tickers.FeedBufferSize,
+			Logger:       logger,
+		}
+	}
+	return &tickers.TickersSourceInMem{
+		FilePaths:    cfg.Tickers.Files,
+		UseTodayDate: cfg.Tickers.Replay.UseTodayDate,
+		Speed:        cfg.Tickers.Replay.Speed,
+		BufferSize:   cfg.Tickers.FeedBufferSize,
+		Cleaning:     cfg.TickCleaning(),
+		Logger:       logger,
+	}
+}
+
+// promoteStandby calls Promote on exchange at addr
+func promoteStandby(addr string, tlsConfig *tls.Config, consumer string) error {
+	return adminCall(addr, tlsConfig, consumer, func(ctx context.Context, exch exchange.ExchangeClient) error {
+		res, err := exch.Promote(ctx, &exchange.PromoteRequest{})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("exchange %v is primary, journal seq %v\n", addr, res.Seq)
+		return nil
+	})
+}
+
+// setChaos calls SetChaos on exchange at addr with settings in json
+func setChaos(addr string, tlsConfig *tls.Config, consumer string, settings string) error {
+	req := &exchange.ChaosSettings{}
+	err := protojson.Unmarshal([]byte(settings), req)
+	if err != nil {
+		return err
+	}
+	return adminCall(addr, tlsConfig, consumer, func(ctx context.Context, exch exchange.ExchangeClient) error {
+		res, err := exch.SetChaos(ctx, req)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("exchange %v chaos settings: %v\n", addr, protojson.Format(res))
+		return nil
+	})
+}
+
+// adminCall connects to exchange at addr and runs call, consumer is passed for ACL
+func adminCall(addr string, tlsConfig *tls.Config, consumer string, call func(context.Context, exchange.ExchangeClient) error) error {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if consumer != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "consumer", consumer)
 	}
+	return call(ctx, exchange.NewExchangeClient(conn))
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/fix"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	logger, err := zap.NewProduction()
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer logger.Sync()
+
+	grcpConn, err := grpc.Dial(
+		`127.0.0.1:8082`,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		logger.Error("failed to connect to exchange", zap.Error(err))
+		return
+	}
+	defer grcpConn.Close()
+
+	g := &fix.Gateway{
+		ListenAddr:   `127.0.0.1:9878`,
+		SenderCompID: "EXCHANGE",
+		BrokerID:     124,
+		Clients: map[string]int32{
+			"LEGACY": 1,
+		},
+		Exchange: exchange.NewExchangeClient(grcpConn),
+		Logger:   logger.Sugar(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = g.Start(ctx)
+	if err != nil {
+		logger.Error("FIX gateway stopped", zap.Error(err))
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/KSerditov/Trading/pkg/tlsutil"
+)
+
+// generates development CA with exchange and broker certificates for TLS/mTLS between broker and exchange
+func main() {
+	out := flag.String("out", "./certs", "output directory")
+	hosts := flag.String("hosts", "127.0.0.1,localhost", "comma separated exchange hosts")
+	brokers := flag.String("brokers", "broker123", "comma separated broker certificate names, exchange maps them to BrokerID")
+	flag.Parse()
+
+	err := tlsutil.GenerateTestCA(*out, strings.Split(*hosts, ","), strings.Split(*brokers, ","))
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("test CA and certificates written to %v\n", *out)
+}
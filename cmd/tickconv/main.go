@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+)
+
+// converts text tick files to binary tick file for "binary" tickers source of exchange,
+// files of one ticker must be given in time order, like days of a month
+func main() {
+	out := flag.String("out", "ticks.tks", "binary tick file to write")
+	clean := flag.Bool("clean", false, "remove duplicates, zero volume ticks and outliers, fix order of ticks, report gaps")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %v -out ticks.tks SPFB.RTS_190517_190517.txt ...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var cleaning *tickers.Cleaning
+	if *clean {
+		c := tickers.DefaultCleaning()
+		cleaning = &c
+	}
+
+	err := convert(*out, flag.Args(), cleaning)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+func convert(out string, inputs []string, cleaning *tickers.Cleaning) error {
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w, err := tickers.NewTickWriter(file)
+	if err != nil {
+		return err
+	}
+
+	var count int
+	var inSize int64
+	for _, in := range inputs {
+		ticks, err := tickers.ReadCSV(in)
+		if err != nil {
+			return fmt.Errorf("%v: %w", in, err)
+		}
+		if cleaning != nil {
+			var report tickers.CleaningReport
+			ticks, report = cleaning.Clean(in, ticks)
+			fmt.Println(report)
+			for _, g := range report.Gaps {
+				fmt.Printf("  gap %v %v - %v\n", g.Ticker, g.From.Format("2006-01-02 15:04:05"), g.To.Format("15:04:05"))
+			}
+		} else {
+			sort.SliceStable(ticks, func(i, j int) bool {
+				return ticks[i].Timestamp.Before(ticks[j].Timestamp)
+			})
+		}
+		for _, t := range ticks {
+			err = w.Write(t)
+			if err != nil {
+				return fmt.Errorf("%v: %w", in, err)
+			}
+		}
+		count += len(ticks)
+
+		if st, err := os.Stat(in); err == nil {
+			inSize += st.Size()
+		}
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	st, err := os.Stat(out)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%v ticks from %v files written to %v, %v bytes (%v bytes of text)\n", count, len(inputs), out, st.Size(), inSize)
+	return nil
+}
@@ -35,6 +35,26 @@ CREATE TABLE `positions` (
     `user_id` BINARY(16) NOT NULL,
     `ticker` varchar(300) NOT NULL,
     `volume` int NOT NULL,
+    `margin` int NOT NULL DEFAULT 0, -- фьючерсы: заблокированное гарантийное обеспечение
+    `settlement_price` float NOT NULL DEFAULT 0, -- фьючерсы: цена последнего клиринга или открытия
+    `variation` float NOT NULL DEFAULT 0, -- фьючерсы: вариационная маржа сделок с последнего клиринга
+    `settled_at` int NOT NULL DEFAULT 0, -- фьючерсы: время последнего клиринга или открытия
+    KEY user_id(user_id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+DROP TABLE IF EXISTS `settlements`;
+CREATE TABLE `settlements` ( -- вариационная маржа по позициям на клирингах
+    `id` int NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    `user_id` BINARY(16) NOT NULL,
+    `date` varchar(10) NOT NULL, -- торговый день YYYY-MM-DD
+    `time` int NOT NULL,
+    `ticker` varchar(300) NOT NULL,
+    `volume` int NOT NULL,
+    `settlement_price` float NOT NULL,
+    `variation` int NOT NULL,
+    `margin` int NOT NULL,
+    `final` int NOT NULL DEFAULT 0, -- 1 - экспирация, позиция закрыта
     KEY user_id(user_id),
     FOREIGN KEY (user_id) REFERENCES users(id)
 );
@@ -75,6 +95,11 @@ CREATE TABLE `stat` ( -- запросы
     `close` float,
     `volume` int,
     `ticker` varchar(300),
+    `best_bid` float NOT NULL DEFAULT 0,
+    `best_ask` float NOT NULL DEFAULT 0,
+    `vwap` float NOT NULL DEFAULT 0,
+    `trades` int NOT NULL DEFAULT 0,
+    `open_interest` bigint NOT NULL DEFAULT 0,
     KEY id(id)
 );
 
@@ -0,0 +1,57 @@
+package exchclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/KSerditov/Trading/api/exchange"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	_ "google.golang.org/grpc/health" // client side health checking
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// calls go only to exchange reporting its service as serving, standby does it after promotion
+var serviceConfig = fmt.Sprintf(`{
+	"loadBalancingConfig": [{"round_robin": {}}],
+	"healthCheckConfig": {"serviceName": %q}
+}`, exchange.Exchange_ServiceDesc.ServiceName)
+
+// Dial connects to exchange at comma separated addresses, primary and standby ones
+func Dial(addresses string, tlsConfig *tls.Config) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+
+	state := resolver.State{}
+	for _, addr := range strings.Split(addresses, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		// certificate is checked against each exchange host, not the common target name
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: addr, ServerName: host})
+	}
+	if len(state.Addresses) == 0 {
+		return nil, fmt.Errorf("no exchange address in %q", addresses)
+	}
+	r := manual.NewBuilderWithScheme("exchange")
+	r.InitialState(state)
+
+	return grpc.Dial(
+		r.Scheme()+":///exchange",
+		grpc.WithResolvers(r),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithDefaultServiceConfig(serviceConfig),
+	)
+}
@@ -5,4 +5,5 @@ import "github.com/KSerditov/Trading/api/exchange"
 type OrderExchClient interface {
 	CreateDeal(ticker string, volume int32, price float32, clientid int32) (*exchange.DealID, error)
 	CancelDeal(dealid int64) (bool, error)
+	ResolveTicker(ticker string) (string, error) // front series for continuous futures ticker, others as is
 }
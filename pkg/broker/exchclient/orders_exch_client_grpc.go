@@ -2,26 +2,31 @@ package exchclient
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/KSerditov/Trading/api/exchange"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
 
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+var (
+	ErrorSeriesExpired = errors.New("futures series is expired")
+	ErrorNoFrontSeries = errors.New("no open series of continuous ticker")
 )
 
 type OrderExchClientGRPC struct {
-	ExchServerAddress string
+	ExchServerAddress string // comma separated primary and standby addresses
 	BrokerID          int32
+	TLS               *tls.Config // plaintext if nil
 
 	client exchange.ExchangeClient
 }
 
 func (o *OrderExchClientGRPC) Init() error {
-	grcpConn, err := grpc.Dial(
-		o.ExchServerAddress,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	grcpConn, err := Dial(o.ExchServerAddress, o.TLS)
 	if err != nil {
 		return err
 	}
@@ -61,3 +66,34 @@ func (o *OrderExchClientGRPC) CancelDeal(dealid int64) (bool, error) {
 
 	return cancel.Success, err
 }
+
+// ResolveTicker asks exchange for series of ticker, exchange without futures series trades all tickers as is
+func (o *OrderExchClientGRPC) ResolveTicker(ticker string) (string, error) {
+	ctx := context.Background()
+	resp, err := o.client.Contracts(ctx, &exchange.ContractsRequest{Ticker: ticker})
+	if status.Code(err) == codes.Unimplemented {
+		return ticker, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Contracts) == 0 {
+		return ticker, nil
+	}
+
+	for _, c := range resp.Contracts {
+		if c.Ticker != ticker {
+			continue
+		}
+		if c.Expired {
+			return "", fmt.Errorf("%w: %v", ErrorSeriesExpired, ticker)
+		}
+		return ticker, nil
+	}
+	for _, c := range resp.Contracts {
+		if c.Front {
+			return c.Ticker, nil
+		}
+	}
+	return "", fmt.Errorf("%w %v", ErrorNoFrontSeries, ticker)
+}
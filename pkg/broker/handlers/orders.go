@@ -24,28 +24,49 @@ type OrderHandlers struct {
 
 	ClientID        int32
 	HistoryDepthMin int32
+
+	// cash if zero, futures positions need only initial margin on balance
+	Settlement orders.Settlement
 }
 
 func (o *OrderHandlers) CreateDeal(userid string, deal *orders.Deal) (*exchange.DealID, int, error) {
-	switch strings.ToLower(deal.Type) {
-	case "buy":
-		balance, err := o.OrdersRepo.GetBalance(userid)
-		if err != nil {
-			return nil, http.StatusInternalServerError, errors.New("unable to retrieve user balance")
-		}
-		if balance < deal.Price*deal.Volume {
-			return nil, http.StatusBadRequest, errors.New("insufficient balance to put buy request")
-		}
-	case "sell":
-		position, err := o.OrdersRepo.GetPositionByUserId(userid, deal.Ticker)
+	// continuous futures ticker is traded as its front series, position is kept by series
+	ticker, err := o.ExchClient.ResolveTicker(deal.Ticker)
+	switch {
+	case errors.Is(err, exchclient.ErrorSeriesExpired), errors.Is(err, exchclient.ErrorNoFrontSeries):
+		return nil, http.StatusBadRequest, err
+	case err != nil:
+		return nil, http.StatusInternalServerError, errors.New("unable to resolve ticker on exchange")
+	}
+	deal.Ticker = ticker
+
+	// futures position of either side needs only initial margin
+	if o.Settlement.Futures {
+		code, err := o.checkMargin(userid, deal)
 		if err != nil {
-			return nil, http.StatusInternalServerError, errors.New("unable to retrieve user positions")
+			return nil, code, err
 		}
-		if position.Volume < deal.Volume {
-			return nil, http.StatusInternalServerError, errors.New("not enough volume for position to put sell request")
+	} else {
+		switch strings.ToLower(deal.Type) {
+		case "buy":
+			balance, err := o.OrdersRepo.GetBalance(userid)
+			if err != nil {
+				return nil, http.StatusInternalServerError, errors.New("unable to retrieve user balance")
+			}
+			if balance < deal.Price*deal.Volume {
+				return nil, http.StatusBadRequest, errors.New("insufficient balance to put buy request")
+			}
+		case "sell":
+			position, err := o.OrdersRepo.GetPositionByUserId(userid, deal.Ticker)
+			if err != nil {
+				return nil, http.StatusInternalServerError, errors.New("unable to retrieve user positions")
+			}
+			if position.Volume < deal.Volume {
+				return nil, http.StatusInternalServerError, errors.New("not enough volume for position to put sell request")
+			}
+		default:
+			return nil, http.StatusBadRequest, errors.New("deal type can be buy or sell only")
 		}
-	default:
-		return nil, http.StatusBadRequest, errors.New("deal type can be buy or sell only")
 	}
 
 	dealid, err := o.ExchClient.CreateDeal(deal.Ticker, deal.Volume, float32(deal.Price), o.ClientID)
@@ -68,6 +89,31 @@ func (o *OrderHandlers) CreateDeal(userid string, deal *orders.Deal) (*exchange.
 	return dealid, http.StatusAccepted, nil
 }
 
+// checkMargin requires initial margin for the part of order which opens or increases position
+func (o *OrderHandlers) checkMargin(userid string, deal *orders.Deal) (int, error) {
+	volume := deal.Volume
+	switch strings.ToLower(deal.Type) {
+	case "buy":
+	case "sell":
+		volume = -volume
+	default:
+		return http.StatusBadRequest, errors.New("deal type can be buy or sell only")
+	}
+
+	position, err := o.OrdersRepo.GetPositionByUserId(userid, deal.Ticker)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("unable to retrieve user positions")
+	}
+	balance, err := o.OrdersRepo.GetBalance(userid)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("unable to retrieve user balance")
+	}
+	if balance < o.Settlement.OpeningMargin(position.Volume, volume, float64(deal.Price)) {
+		return http.StatusBadRequest, errors.New("insufficient balance for initial margin of request")
+	}
+	return http.StatusOK, nil
+}
+
 func (o *OrderHandlers) CreateDealHr(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	sess, _ := o.SessMgr.GetSessionFromContext(ctx)
@@ -214,6 +260,23 @@ func (o *OrderHandlers) GetStatus(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonPost)
 }
 
+// variation margin paid to the user at clearings, the latest first
+func (o *OrderHandlers) GetSettlements(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	sess, _ := o.SessMgr.GetSessionFromContext(ctx)
+
+	settlements, err := o.OrdersRepo.GetSettlementsByUserId(sess.UserID)
+	if err != nil {
+		o.jsonMsg(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	jsonPost, _ := json.Marshal(&orders.SettlementsResponse{Body: settlements})
+	w.Write(jsonPost)
+}
+
 func (o *OrderHandlers) jsonMsg(w http.ResponseWriter, msg string, status int) {
 	w.WriteHeader(status)
 	resp, _ := json.Marshal(map[string]interface{}{
@@ -2,24 +2,48 @@ package orders
 
 import (
 	"context"
+	"crypto/tls"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/KSerditov/Trading/api/exchange"
 	"github.com/KSerditov/Trading/pkg/broker/custlog"
+	"github.com/KSerditov/Trading/pkg/broker/exchclient"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type OrdersListener struct {
-	ExchServerAddress string
+	ExchServerAddress string // comma separated primary and standby addresses
 	Logger            *custlog.Logger
 	BrokerID          *exchange.BrokerID
 	OrdersRepository  OrdersRepository
+
+	// statistics missed while broker was down is loaded from exchange history,
+	// but not older than BackfillDepth (15 minutes if not set)
+	BackfillDepth time.Duration
+
+	// plaintext if nil
+	TLS *tls.Config
+
+	// cash if zero, futures settlement pays variation margin at each exchange clearing
+	Settlement Settlement
+
+	lastClearing string // date of the last clearing variation margin was paid for
 }
 
+const (
+	defaultBackfillDepth = 15 * time.Minute
+	backfillInterval     = 1 // seconds, same bars as Statistic stream sends
+	resultsRetry         = time.Second
+	clearingPoll         = time.Minute
+)
+
 func (o *OrdersListener) Start() error {
 	o.Logger = &custlog.Logger{
 		Zap:   getBaseLogger(),
@@ -27,10 +51,7 @@ func (o *OrdersListener) Start() error {
 	}
 
 	o.Logger.Zap.Info("Setting up gRPC connection to Exchange server...")
-	grcpConn, err := grpc.Dial(
-		o.ExchServerAddress,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-	)
+	grcpConn, err := exchclient.Dial(o.ExchServerAddress, o.TLS)
 	if err != nil {
 		o.Logger.Zap.Fatal("Error initializing gRPC connection to exchange", zap.Error(err))
 	}
@@ -38,15 +59,13 @@ func (o *OrdersListener) Start() error {
 	exch := exchange.NewExchangeClient(grcpConn)
 
 	ctx := context.Background()
-	statistics, err := exch.Statistic(ctx, o.BrokerID)
+	statistics, err := exch.Statistic(ctx, o.BrokerID, grpc.WaitForReady(true))
 	if err != nil {
 		o.Logger.Zap.Fatal("Error initializing gRPC connection to exchange", zap.Error(err))
 	}
 
-	results, err := exch.Results(ctx, o.BrokerID)
-	if err != nil {
-		o.Logger.Zap.Fatal("error initializing gRPC connection to exchange", zap.Error(err))
-	}
+	// live stream is already subscribed, so nothing falls between history and stream
+	o.backfill(ctx, exch)
 
 	go func() {
 		for {
@@ -62,69 +81,284 @@ func (o *OrdersListener) Start() error {
 		}
 	}()
 
-	go func() {
-		for {
-			result, err := results.Recv()
-			if err != nil {
-				o.Logger.Zap.Error("can't receive from grpc statistics stream", zap.String("error", err.Error()))
-				continue
-			}
+	go o.listenResults(ctx, exch)
 
-			o.Logger.Zap.Sugar().Debugw("result received from exchange", "result", result)
-			_, userid, err := o.OrdersRepository.GetDealById(result.ID)
+	if o.Settlement.Futures {
+		go o.listenClearing(ctx, exch)
+	}
+
+	return nil
+}
+
+// listenResults applies execution reports, reconnecting to the exchange serving now (primary or promoted standby)
+// and resuming after the last report received, so failover neither loses nor repeats fills
+func (o *OrdersListener) listenResults(ctx context.Context, exch exchange.ExchangeClient) {
+	var lastSeq int64
+	for {
+		req := &exchange.BrokerID{
+			ID:          o.BrokerID.ID,
+			LastExecSeq: lastSeq,
+		}
+		results, err := exch.Results(ctx, req, grpc.WaitForReady(true))
+		for err == nil {
+			var result *exchange.Deal
+			result, err = results.Recv()
 			if err != nil {
-				o.Logger.Zap.Sugar().Errorw("unable to find local details for deal received for exchange",
-					"result", result,
-					"error", err,
-				)
+				break
+			}
+			if result.ExecSeq != 0 && result.ExecSeq <= lastSeq {
 				continue
 			}
+			o.applyResult(result)
+			if result.ExecSeq > lastSeq {
+				lastSeq = result.ExecSeq
+			}
+		}
 
-			var balanceChange int32
-			var volumeChange int32
+		if status.Code(err) == codes.FailedPrecondition {
+			// standby promoted after failover has not got the last reports from primary,
+			// its journal goes on with other ones, so reports are taken from the new ones
+			o.Logger.Zap.Error("exchange journal is behind reports received, resyncing to new reports",
+				zap.Int64("lastExecSeq", lastSeq),
+				zap.Error(err),
+			)
+			lastSeq = 0
+			continue
+		}
+		o.Logger.Zap.Error("results stream from exchange is broken, reconnecting",
+			zap.Int64("lastExecSeq", lastSeq),
+			zap.Error(err),
+		)
+		time.Sleep(resultsRetry)
+	}
+}
 
-			if result.Volume > 0 {
-				balanceChange = -result.Volume * int32(result.Price)
-				volumeChange = result.Volume
-			} else {
-				balanceChange = result.Volume * int32(result.Price)
-				volumeChange = -result.Volume
-			}
+// applyResult changes client balance and position by execution report
+func (o *OrdersListener) applyResult(result *exchange.Deal) {
+	o.Logger.Zap.Sugar().Debugw("result received from exchange", "result", result)
+	switch result.Status {
+	case exchange.OrderStatus_SETTLED:
+		o.settle(result)
+		return
+	case exchange.OrderStatus_EXPIRED:
+		// rest of order is cancelled by exchange, fills before it are already applied
+		delerr := o.OrdersRepository.DeleteDealById(result.ID)
+		if delerr != nil {
+			o.Logger.Zap.Sugar().Errorw("failed to delete expired order",
+				"result", result,
+				"error", delerr,
+			)
+		}
+		return
+	}
 
-			_, err1 := o.OrdersRepository.ChangeBalance(userid, balanceChange)
-			if err1 != nil {
-				o.Logger.Zap.Sugar().Errorw("failed to change balance",
-					"result", result,
-					"userid", userid,
-					"proposed_change", balanceChange,
-					"error", err,
-				)
-			}
-			_, err2 := o.OrdersRepository.ChangePosition(userid, result.Ticker, volumeChange)
-			if err2 != nil {
-				o.Logger.Zap.Sugar().Errorw("failed to change position",
-					"result", result,
-					"userid", userid,
-					"proposed_change", volumeChange,
-					"error", err,
-				)
-			}
+	deal, userid, err := o.OrdersRepository.GetDealById(result.ID)
+	if err != nil {
+		o.Logger.Zap.Sugar().Errorw("unable to find local details for deal received for exchange",
+			"result", result,
+			"error", err,
+		)
+		return
+	}
+
+	if o.Settlement.Futures {
+		o.applyFuturesFill(result, deal, userid)
+	} else {
+		o.applyCashFill(result, userid)
+	}
+
+	// terminal report, nothing more comes for this order
+	if result.Status == exchange.OrderStatus_FILLED {
+		delerr := o.OrdersRepository.DeleteDealById(result.ID)
+		if delerr != nil {
+			o.Logger.Zap.Sugar().Errorw("failed to delete completed order",
+				"result", result,
+				"userid", userid,
+				"error", delerr,
+			)
+		}
+	}
+}
+
+// applyFuturesFill blocks initial margin for position, price difference is paid at clearing
+func (o *OrdersListener) applyFuturesFill(result *exchange.Deal, deal *Deal, userid string) {
+	volume := result.Volume
+	if strings.ToLower(deal.Type) == "sell" {
+		volume = -volume
+	}
+	_, err := o.OrdersRepository.ChangeFuturesPosition(userid, result.Ticker, volume, result.Price, o.Settlement.InitialMargin, result.Time)
+	if err != nil {
+		o.Logger.Zap.Sugar().Errorw("failed to change futures position",
+			"result", result,
+			"userid", userid,
+			"proposed_change", volume,
+			"error", err,
+		)
+	}
+}
+
+// applyCashFill moves value of deal between balance and position
+func (o *OrdersListener) applyCashFill(result *exchange.Deal, userid string) {
+	var balanceChange int32
+	var volumeChange int32
 
-			if !result.Partial {
-				delerr := o.OrdersRepository.DeleteDealById(result.ID)
-				if delerr != nil {
-					o.Logger.Zap.Sugar().Errorw("failed to delete completed order",
-						"result", result,
-						"userid", userid,
-						"proposed_change", volumeChange,
-						"error", err,
-					)
-				}
+	if result.Volume > 0 {
+		balanceChange = -result.Volume * int32(result.Price)
+		volumeChange = result.Volume
+	} else {
+		balanceChange = result.Volume * int32(result.Price)
+		volumeChange = -result.Volume
+	}
+
+	_, err1 := o.OrdersRepository.ChangeBalance(userid, balanceChange)
+	if err1 != nil {
+		o.Logger.Zap.Sugar().Errorw("failed to change balance",
+			"result", result,
+			"userid", userid,
+			"proposed_change", balanceChange,
+			"error", err1,
+		)
+	}
+	_, err2 := o.OrdersRepository.ChangePosition(userid, result.Ticker, volumeChange)
+	if err2 != nil {
+		o.Logger.Zap.Sugar().Errorw("failed to change position",
+			"result", result,
+			"userid", userid,
+			"proposed_change", volumeChange,
+			"error", err2,
+		)
+	}
+}
+
+// settle closes positions of expired series, exchange reports them per client of broker
+// and the broker trades as one client, so the first report closes positions of all users
+func (o *OrdersListener) settle(result *exchange.Deal) {
+	var closed int64
+	var err error
+	if o.Settlement.Futures {
+		date := time.Unix(int64(result.Time), 0).Format("2006-01-02")
+		closed, err = o.OrdersRepository.SettleVariationMargin(result.Ticker, result.Price, date, result.Time, o.Settlement.InitialMargin, true)
+	} else {
+		closed, err = o.OrdersRepository.SettlePositions(result.Ticker, result.Price)
+	}
+	if err != nil {
+		o.Logger.Zap.Sugar().Errorw("failed to settle positions of expired series",
+			"result", result,
+			"error", err,
+		)
+		return
+	}
+	o.Logger.Zap.Sugar().Infow("positions of expired series settled",
+		"ticker", result.Ticker,
+		"price", result.Price,
+		"positions", closed,
+	)
+}
+
+// listenClearing pays variation margin once for every trading day closed by exchange clearing
+func (o *OrdersListener) listenClearing(ctx context.Context, exch exchange.ExchangeClient) {
+	ticker := time.NewTicker(clearingPoll)
+	defer ticker.Stop()
+
+	for {
+		o.settleDay(ctx, exch)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// settleDay moves futures positions to settlement prices of the last clearing report,
+// positions opened after its close are left to the next one
+func (o *OrdersListener) settleDay(ctx context.Context, exch exchange.ExchangeClient) {
+	report, err := exch.Clearing(ctx, &exchange.ClearingRequest{BrokerID: o.BrokerID.ID})
+	if status.Code(err) == codes.NotFound {
+		return
+	}
+	if err != nil {
+		o.Logger.Zap.Error("can't get clearing report from exchange", zap.Error(err))
+		return
+	}
+	if report.Date == o.lastClearing {
+		return
+	}
+
+	for _, ins := range report.Instruments {
+		settled, err := o.OrdersRepository.SettleVariationMargin(ins.Ticker, ins.SettlementPrice, report.Date, report.Time, o.Settlement.InitialMargin, false)
+		if err != nil {
+			o.Logger.Zap.Sugar().Errorw("failed to pay variation margin",
+				"date", report.Date,
+				"ticker", ins.Ticker,
+				"error", err,
+			)
+			return
+		}
+		if settled > 0 {
+			o.Logger.Zap.Sugar().Infow("variation margin paid",
+				"date", report.Date,
+				"ticker", ins.Ticker,
+				"price", ins.SettlementPrice,
+				"positions", settled,
+			)
+		}
+	}
+	o.lastClearing = report.Date
+}
+
+// loads bars since the last one stored up to now
+func (o *OrdersListener) backfill(ctx context.Context, exch exchange.ExchangeClient) {
+	depth := o.BackfillDepth
+	if depth <= 0 {
+		depth = defaultBackfillDepth
+	}
+
+	last, err := o.OrdersRepository.GetLastStatisticTime()
+	if err != nil {
+		o.Logger.Zap.Error("can't get last statistics time, skipping backfill", zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	from := int32(now.Add(-depth).Unix())
+	if last >= from {
+		from = last + 1
+	}
+
+	req := &exchange.CandlesRequest{
+		Interval: backfillInterval,
+		From:     from,
+		To:       int32(now.Unix()),
+	}
+
+	loaded := 0
+	for {
+		resp, err := exch.GetCandles(ctx, req)
+		if err != nil {
+			o.Logger.Zap.Error("can't load statistics history from exchange", zap.Error(err))
+			return
+		}
+
+		for _, c := range resp.Candles {
+			_, errdb := o.OrdersRepository.AddStatisticsEntity(c)
+			if errdb != nil {
+				o.Logger.Zap.Error("failed to save interval statistics to db", zap.Error(errdb))
+				return
 			}
+			loaded++
 		}
-	}()
 
-	return nil
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
+	}
+
+	o.Logger.Zap.Sugar().Infow("statistics backfilled from exchange",
+		"from", from,
+		"bars", loaded,
+	)
 }
 
 func getBaseLogger() *zap.Logger {
@@ -19,10 +19,18 @@ type OrdersRepository interface {
 
 	AddStatisticsEntity(entity *exchange.OHLCV) (int64, error)
 	GetStatisticSince(since time.Time, ticker string) ([]Ohlcv, error)
+	GetLastStatisticTime() (int32, error)
 
 	GetPositionsByUserId(userid string) ([]Position, error)
 	GetPositionByUserId(userid string, ticker string) (*Position, error)
 	ChangePosition(userid string, ticker string, volumeChange int32) (*Position, error)
+	SettlePositions(ticker string, price float32) (int64, error) // expired series, positions are credited to balance
+
+	// futures settlement: fill blocks or releases initial margin at marginRate, variation is paid by SettleVariationMargin
+	ChangeFuturesPosition(userid string, ticker string, volumeChange int32, price float32, marginRate float64, at int32) (*Position, error)
+	// pays variation margin of positions in ticker not settled since at, final closes them at expiry
+	SettleVariationMargin(ticker string, price float32, date string, at int32, marginRate float64, final bool) (int64, error)
+	GetSettlementsByUserId(userid string) ([]SettlementRecord, error)
 }
 
 type Deal struct {
@@ -52,12 +60,17 @@ type CancelResponse struct {
 }
 
 type Ohlcv struct {
-	Open   float64 `json:"open"`
-	High   float64 `json:"high"`
-	Low    float64 `json:"low"`
-	Close  float64 `json:"close"`
-	Volume int32   `json:"volume"`
-	Time   int32   `json:"time"`
+	Open         float64 `json:"open"`
+	High         float64 `json:"high"`
+	Low          float64 `json:"low"`
+	Close        float64 `json:"close"`
+	Volume       int32   `json:"volume"`
+	Time         int32   `json:"time"`
+	BestBid      float64 `json:"best_bid"` // 0 if there were no buy orders
+	BestAsk      float64 `json:"best_ask"` // 0 if there were no sell orders
+	VWAP         float64 `json:"vwap"`
+	Trades       int32   `json:"trades"`
+	OpenInterest int64   `json:"open_interest"`
 }
 
 type TickerOhlcv struct {
@@ -72,6 +85,26 @@ type HistoryResponse struct {
 type Position struct {
 	Ticker string `json:"ticker"`
 	Volume int32  `json:"volume"`
+
+	// futures settlement only
+	Margin          int32   `json:"margin,omitempty"`           // initial margin blocked from balance
+	SettlementPrice float64 `json:"settlement_price,omitempty"` // price of the last clearing or of opening fill
+}
+
+// SettlementRecord is variation margin of position paid at clearing
+type SettlementRecord struct {
+	Date            string  `json:"date"` // trading day, YYYY-MM-DD
+	Time            int32   `json:"time"` // unix time of clearing
+	Ticker          string  `json:"ticker"`
+	Volume          int32   `json:"volume"` // position at clearing
+	SettlementPrice float64 `json:"settlement_price"`
+	Variation       int32   `json:"variation"` // credited to balance, debited if negative
+	Margin          int32   `json:"margin"`    // initial margin blocked after clearing
+	Final           bool    `json:"final"`     // series expired and position is closed
+}
+
+type SettlementsResponse struct {
+	Body []SettlementRecord `json:"body"`
 }
 
 type StatusBody struct {
@@ -86,4 +119,5 @@ type StatusResponse struct {
 
 var (
 	ErrorDealNotFound = errors.New("deal not found")
+	ErrorNoClient     = errors.New("client has no balance")
 )
@@ -47,8 +47,8 @@ func NewOrdersRepositoryMySql(dsn string) (OrdersRepository, error) {
 
 func (o *OrdersRepositoryMySql) AddStatisticsEntity(e *exchange.OHLCV) (int64, error) {
 	//fmt.Printf("NEW STAT ENTRY: %v\n", e)
-	sql := "INSERT INTO stat(`time`, `interval`, `open`, `high`, `low`, `close`, `volume`, `ticker`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
-	res, err := o.DB.Exec(sql, e.Time, e.Interval, e.Open, e.High, e.Low, e.Close, e.Volume, e.Ticker)
+	sql := "INSERT INTO stat(`time`, `interval`, `open`, `high`, `low`, `close`, `volume`, `ticker`, `best_bid`, `best_ask`, `vwap`, `trades`, `open_interest`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	res, err := o.DB.Exec(sql, e.Time, e.Interval, e.Open, e.High, e.Low, e.Close, e.Volume, e.Ticker, e.BestBid, e.BestAsk, e.VWAP, e.Trades, e.OpenInterest)
 	if err != nil {
 		return -1, err
 	}
@@ -218,7 +218,7 @@ func (o *OrdersRepositoryMySql) GetDealsByUserId(userid string) ([]Deal, error)
 }
 
 func (o *OrdersRepositoryMySql) GetPositionsByUserId(userid string) ([]Position, error) {
-	query := "SELECT `ticker`, `volume` FROM positions WHERE user_id = UUID_TO_BIN(?)"
+	query := "SELECT `ticker`, `volume`, `margin`, `settlement_price` FROM positions WHERE user_id = UUID_TO_BIN(?)"
 	rows, err := o.DB.Query(query, userid)
 	if err != nil {
 		return nil, err
@@ -228,7 +228,7 @@ func (o *OrdersRepositoryMySql) GetPositionsByUserId(userid string) ([]Position,
 	positions := make([]Position, 0, 10)
 	for rows.Next() {
 		var position Position
-		err := rows.Scan(&position.Ticker, &position.Volume)
+		err := rows.Scan(&position.Ticker, &position.Volume, &position.Margin, &position.SettlementPrice)
 		if err != nil {
 			return positions, err
 		}
@@ -260,7 +260,7 @@ func (o *OrdersRepositoryMySql) GetPositionByUserId(userid string, ticker string
 }
 
 func (o *OrdersRepositoryMySql) GetStatisticSince(since time.Time, ticker string) ([]Ohlcv, error) {
-	query := "SELECT `time`, `open`, `high`, `low`, `close`, `volume` FROM stat WHERE time > ? AND ticker = ? ORDER BY time DESC"
+	query := "SELECT `time`, `open`, `high`, `low`, `close`, `volume`, `best_bid`, `best_ask`, `vwap`, `trades`, `open_interest` FROM stat WHERE time > ? AND ticker = ? ORDER BY time DESC"
 	rows, err := o.DB.Query(query, since.Unix(), ticker)
 	if err != nil {
 		return nil, err
@@ -271,7 +271,8 @@ func (o *OrdersRepositoryMySql) GetStatisticSince(since time.Time, ticker string
 
 	for rows.Next() {
 		var ohlcv Ohlcv
-		err := rows.Scan(&ohlcv.Time, &ohlcv.Open, &ohlcv.High, &ohlcv.Low, &ohlcv.Close, &ohlcv.Volume)
+		err := rows.Scan(&ohlcv.Time, &ohlcv.Open, &ohlcv.High, &ohlcv.Low, &ohlcv.Close, &ohlcv.Volume,
+			&ohlcv.BestBid, &ohlcv.BestAsk, &ohlcv.VWAP, &ohlcv.Trades, &ohlcv.OpenInterest)
 		if err != nil {
 			return ohlcvs, err
 		}
@@ -283,6 +284,17 @@ func (o *OrdersRepositoryMySql) GetStatisticSince(since time.Time, ticker string
 	return ohlcvs, nil
 }
 
+// 0 if there is no statistics yet
+func (o *OrdersRepositoryMySql) GetLastStatisticTime() (int32, error) {
+	var last int32
+	row := o.DB.QueryRow("SELECT COALESCE(MAX(`time`), 0) FROM stat")
+	err := row.Scan(&last)
+	if err != nil {
+		return 0, err
+	}
+	return last, nil
+}
+
 func (o *OrdersRepositoryMySql) ChangePosition(userid string, ticker string, volumeChange int32) (*Position, error) {
 	fmt.Println("ChangePosition")
 	ctx := context.TODO()
@@ -337,3 +349,210 @@ func (o *OrdersRepositoryMySql) ChangePosition(userid string, ticker string, vol
 
 	return position, nil
 }
+
+// SettlePositions closes positions of all users in ticker at price, returns number of closed positions
+func (o *OrdersRepositoryMySql) SettlePositions(ticker string, price float32) (int64, error) {
+	ctx := context.TODO()
+	tx, txerr := o.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if txerr != nil {
+		return 0, txerr
+	}
+	defer tx.Rollback()
+
+	update := "UPDATE `clients` INNER JOIN `positions` ON clients.user_id = positions.user_id " +
+		"SET clients.balance = clients.balance + ROUND(positions.volume * ?) WHERE positions.ticker = ?"
+	_, err := tx.Exec(update, price, ticker)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := tx.Exec("DELETE FROM `positions` WHERE `ticker` = ?", ticker)
+	if err != nil {
+		return 0, err
+	}
+	closed, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	commiterr := tx.Commit()
+	if commiterr != nil {
+		return 0, commiterr
+	}
+	return closed, nil
+}
+
+// ChangeFuturesPosition applies fill of signed volume at price. Difference to settlement price of position
+// is kept as variation for the next clearing and initial margin follows position size.
+func (o *OrdersRepositoryMySql) ChangeFuturesPosition(userid string, ticker string, volumeChange int32, price float32, marginRate float64, at int32) (*Position, error) {
+	ctx := context.TODO()
+	tx, txerr := o.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if txerr != nil {
+		return nil, txerr
+	}
+	defer tx.Rollback()
+
+	var positionid int64
+	var variation float64
+	position := &Position{
+		Ticker:          ticker,
+		SettlementPrice: float64(price),
+	}
+	query := "SELECT `id`, `volume`, `margin`, `settlement_price`, `variation` FROM positions WHERE user_id = UUID_TO_BIN(?) AND ticker = ? FOR UPDATE"
+	row := tx.QueryRow(query, userid, ticker)
+	err := row.Scan(&positionid, &position.Volume, &position.Margin, &position.SettlementPrice, &variation)
+	if err == sql.ErrNoRows {
+		// new position is settled from its opening price
+		insert := "INSERT INTO `positions` (`user_id`, `ticker`, `volume`, `margin`, `settlement_price`, `variation`, `settled_at`) VALUES (UUID_TO_BIN(?), ?, 0, 0, ?, 0, ?)"
+		r, err2 := tx.Exec(insert, userid, ticker, price, at)
+		if err2 != nil {
+			return nil, err2
+		}
+		positionid, err2 = r.LastInsertId()
+		if err2 != nil {
+			return nil, err2
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	oldMargin := position.Margin
+	variation += fillVariation(volumeChange, position.SettlementPrice, price)
+	position.Volume += volumeChange
+	position.Margin = initialMargin(marginRate, position.Volume, position.SettlementPrice)
+
+	// closed position stays until clearing pays its variation
+	update := "UPDATE `positions` SET `volume` = ?, `margin` = ?, `variation` = ? WHERE `id` = ?"
+	_, err = tx.Exec(update, position.Volume, position.Margin, variation, positionid)
+	if err != nil {
+		return nil, err
+	}
+	// margin must be blocked, otherwise position is not changed
+	if oldMargin != position.Margin {
+		res, err := tx.Exec("UPDATE `clients` SET `balance` = `balance` + ? WHERE user_id = UUID_TO_BIN(?)", oldMargin-position.Margin, userid)
+		if err != nil {
+			return nil, err
+		}
+		aff, err := res.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if aff != 1 {
+			return nil, fmt.Errorf("%w: %v", ErrorNoClient, userid)
+		}
+	}
+
+	commiterr := tx.Commit()
+	if commiterr != nil {
+		return nil, commiterr
+	}
+	return position, nil
+}
+
+type futuresPosition struct {
+	id              int64
+	userid          string
+	volume          int32
+	margin          int32
+	settlementPrice float64
+	variation       float64
+}
+
+// SettleVariationMargin pays variation margin of positions in ticker opened before at, moves them to price
+// and records settlement history. Final settlement at expiry releases margin and closes positions.
+func (o *OrdersRepositoryMySql) SettleVariationMargin(ticker string, price float32, date string, at int32, marginRate float64, final bool) (int64, error) {
+	ctx := context.TODO()
+	tx, txerr := o.DB.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted, ReadOnly: false})
+	if txerr != nil {
+		return 0, txerr
+	}
+	defer tx.Rollback()
+
+	query := "SELECT `id`, BIN_TO_UUID(user_id), `volume`, `margin`, `settlement_price`, `variation` FROM positions " +
+		"WHERE ticker = ? AND (? OR settled_at < ?) FOR UPDATE"
+	rows, err := tx.Query(query, ticker, final, at)
+	if err != nil {
+		return 0, err
+	}
+	positions := make([]futuresPosition, 0, 10)
+	for rows.Next() {
+		var p futuresPosition
+		err = rows.Scan(&p.id, &p.userid, &p.volume, &p.margin, &p.settlementPrice, &p.variation)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		positions = append(positions, p)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range positions {
+		variation := dayVariation(p.variation, p.volume, p.settlementPrice, price)
+		var margin int32
+		if !final {
+			margin = initialMargin(marginRate, p.volume, float64(price))
+		}
+
+		res, err := tx.Exec("UPDATE `clients` SET `balance` = `balance` + ? WHERE user_id = UUID_TO_BIN(?)", variation+p.margin-margin, p.userid)
+		if err != nil {
+			return 0, err
+		}
+		aff, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if aff != 1 {
+			return 0, fmt.Errorf("%w: %v", ErrorNoClient, p.userid)
+		}
+		insert := "INSERT INTO `settlements` (`user_id`, `date`, `time`, `ticker`, `volume`, `settlement_price`, `variation`, `margin`, `final`) " +
+			"VALUES (UUID_TO_BIN(?), ?, ?, ?, ?, ?, ?, ?, ?)"
+		_, err = tx.Exec(insert, p.userid, date, at, ticker, p.volume, price, variation, margin, final)
+		if err != nil {
+			return 0, err
+		}
+
+		if final || p.volume == 0 {
+			_, err = tx.Exec("DELETE FROM `positions` WHERE `id` = ?", p.id)
+		} else {
+			update := "UPDATE `positions` SET `margin` = ?, `settlement_price` = ?, `variation` = 0, `settled_at` = ? WHERE `id` = ?"
+			_, err = tx.Exec(update, margin, price, at, p.id)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	commiterr := tx.Commit()
+	if commiterr != nil {
+		return 0, commiterr
+	}
+	return int64(len(positions)), nil
+}
+
+func (o *OrdersRepositoryMySql) GetSettlementsByUserId(userid string) ([]SettlementRecord, error) {
+	query := "SELECT `date`, `time`, `ticker`, `volume`, `settlement_price`, `variation`, `margin`, `final` FROM settlements " +
+		"WHERE user_id = UUID_TO_BIN(?) ORDER BY `time` DESC, `ticker`"
+	rows, err := o.DB.Query(query, userid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settlements := make([]SettlementRecord, 0, 10)
+	for rows.Next() {
+		var s SettlementRecord
+		err := rows.Scan(&s.Date, &s.Time, &s.Ticker, &s.Volume, &s.SettlementPrice, &s.Variation, &s.Margin, &s.Final)
+		if err != nil {
+			return settlements, err
+		}
+		settlements = append(settlements, s)
+	}
+	if err = rows.Err(); err != nil {
+		return settlements, err
+	}
+
+	return settlements, nil
+}
@@ -0,0 +1,119 @@
+package orders
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const (
+	selectPositionSQL   = "SELECT `id`, `volume`, `margin`, `settlement_price`, `variation` FROM positions WHERE user_id = UUID_TO_BIN(?) AND ticker = ? FOR UPDATE"
+	insertPositionSQL   = "INSERT INTO `positions` (`user_id`, `ticker`, `volume`, `margin`, `settlement_price`, `variation`, `settled_at`) VALUES (UUID_TO_BIN(?), ?, 0, 0, ?, 0, ?)"
+	updatePositionSQL   = "UPDATE `positions` SET `volume` = ?, `margin` = ?, `variation` = ? WHERE `id` = ?"
+	updateBalanceSQL    = "UPDATE `clients` SET `balance` = `balance` + ? WHERE user_id = UUID_TO_BIN(?)"
+	selectSettlingSQL   = "SELECT `id`, BIN_TO_UUID(user_id), `volume`, `margin`, `settlement_price`, `variation` FROM positions WHERE ticker = ? AND (? OR settled_at < ?) FOR UPDATE"
+	insertSettlementSQL = "INSERT INTO `settlements` (`user_id`, `date`, `time`, `ticker`, `volume`, `settlement_price`, `variation`, `margin`, `final`) " +
+		"VALUES (UUID_TO_BIN(?), ?, ?, ?, ?, ?, ?, ?, ?)"
+	settlePositionSQL = "UPDATE `positions` SET `margin` = ?, `settlement_price` = ?, `variation` = 0, `settled_at` = ? WHERE `id` = ?"
+	deletePositionSQL = "DELETE FROM `positions` WHERE `id` = ?"
+)
+
+func newMockRepo(t *testing.T) (*OrdersRepositoryMySql, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("cant create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &OrdersRepositoryMySql{DB: db}, mock
+}
+
+func TestChangeFuturesPositionSQL(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		affected int64
+		err      error
+	}{
+		{name: "opened", affected: 1},
+		{name: "no client", affected: 0, err: ErrorNoClient},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mock := newMockRepo(t)
+			mock.ExpectBegin()
+			mock.ExpectQuery(selectPositionSQL).WithArgs("alice", "RTS-6.19").WillReturnError(sql.ErrNoRows)
+			mock.ExpectExec(insertPositionSQL).WithArgs("alice", "RTS-6.19", 100.0, 1000).WillReturnResult(sqlmock.NewResult(7, 1))
+			mock.ExpectExec(updatePositionSQL).WithArgs(10, 100, 0.0, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(updateBalanceSQL).WithArgs(-100, "alice").WillReturnResult(sqlmock.NewResult(0, tc.affected))
+			if tc.err == nil {
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			p, err := repo.ChangeFuturesPosition("alice", "RTS-6.19", 10, 100, 0.1, 1000)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("errors dont match\nhave %v\nwant %v", err, tc.err)
+			}
+			if tc.err == nil && (p.Volume != 10 || p.Margin != 100) {
+				t.Fatalf("unexpected position %+v", p)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestSettleVariationMarginSQL(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		final    bool
+		affected int64
+		err      error
+	}{
+		// only positions of ticker opened before clearing close are moved to settlement price
+		{name: "clearing", affected: 1},
+		// expiry closes positions of ticker whenever they were opened
+		{name: "final", final: true, affected: 1},
+		{name: "no client", affected: 0, err: ErrorNoClient},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			repo, mock := newMockRepo(t)
+			mock.ExpectBegin()
+			rows := sqlmock.NewRows([]string{"id", "user_id", "volume", "margin", "settlement_price", "variation"}).
+				AddRow(7, "alice", 6, 60, 100, 40)
+			mock.ExpectQuery(selectSettlingSQL).WithArgs("RTS-6.19", tc.final, 2000).WillReturnRows(rows)
+
+			// variation 40 + 6*5 = 70, margin 60 -> 63 or released at expiry
+			margin := 63
+			if tc.final {
+				margin = 0
+			}
+			mock.ExpectExec(updateBalanceSQL).WithArgs(70+60-margin, "alice").WillReturnResult(sqlmock.NewResult(0, tc.affected))
+			if tc.err != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectExec(insertSettlementSQL).WithArgs("alice", "2019-06-03", 2000, "RTS-6.19", 6, 105.0, 70, margin, tc.final).
+					WillReturnResult(sqlmock.NewResult(1, 1))
+				if tc.final {
+					mock.ExpectExec(deletePositionSQL).WithArgs(7).WillReturnResult(sqlmock.NewResult(0, 1))
+				} else {
+					mock.ExpectExec(settlePositionSQL).WithArgs(63, 105.0, 2000, 7).WillReturnResult(sqlmock.NewResult(0, 1))
+				}
+				mock.ExpectCommit()
+			}
+
+			settled, err := repo.SettleVariationMargin("RTS-6.19", 105, "2019-06-03", 2000, 0.1, tc.final)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("errors dont match\nhave %v\nwant %v", err, tc.err)
+			}
+			if tc.err == nil && settled != 1 {
+				t.Fatalf("settled have %v, want 1", settled)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
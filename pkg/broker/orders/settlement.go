@@ -0,0 +1,69 @@
+package orders
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	SettlementCash    = "cash"    // fill moves full value of deal between balance and position
+	SettlementFutures = "futures" // fill blocks initial margin, variation margin is paid at each clearing
+)
+
+// Settlement is how fills change client balance
+type Settlement struct {
+	Futures       bool
+	InitialMargin float64 // futures only, part of position value blocked from balance while it is open
+}
+
+func NewSettlement(mode string, initialMargin float64) (Settlement, error) {
+	switch mode {
+	case "", SettlementCash:
+		return Settlement{}, nil
+	case SettlementFutures:
+		if initialMargin <= 0 || initialMargin > 1 {
+			return Settlement{}, fmt.Errorf("initial margin must be in (0, 1], got %v", initialMargin)
+		}
+		return Settlement{Futures: true, InitialMargin: initialMargin}, nil
+	}
+	return Settlement{}, fmt.Errorf("unknown settlement %q, supported: %v, %v", mode, SettlementCash, SettlementFutures)
+}
+
+// OpeningMargin is initial margin needed for signed volume at price, closing part of it needs none
+func (s Settlement) OpeningMargin(position int32, volume int32, price float64) int32 {
+	return initialMargin(s.InitialMargin, opening(position, volume), price)
+}
+
+// opening is part of signed volume which opens or increases position, the rest closes it
+func opening(position int32, volume int32) int32 {
+	if position == 0 || (position > 0) == (volume > 0) {
+		return abs(volume)
+	}
+	if abs(volume) <= abs(position) {
+		return 0
+	}
+	return abs(volume) - abs(position)
+}
+
+// fillVariation is what fill of signed volume at price earns against settlement price of position,
+// it is kept with position and paid at the next clearing
+func fillVariation(volume int32, settlementPrice float64, price float32) float64 {
+	return float64(volume) * (settlementPrice - float64(price))
+}
+
+// dayVariation is variation margin paid at clearing: variation of fills since the last one
+// and position moved from its settlement price to the new one
+func dayVariation(variation float64, volume int32, settlementPrice float64, price float32) int32 {
+	return int32(math.Round(variation + float64(volume)*(float64(price)-settlementPrice)))
+}
+
+func initialMargin(rate float64, volume int32, price float64) int32 {
+	return int32(math.Round(rate * float64(abs(volume)) * price))
+}
+
+func abs(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
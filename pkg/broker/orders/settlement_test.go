@@ -0,0 +1,223 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/broker/custlog"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+func TestOpeningMargin(t *testing.T) {
+	s, err := NewSettlement(SettlementFutures, 0.1)
+	if err != nil {
+		t.Fatalf("cant create settlement: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		position int32
+		volume   int32
+		price    float64
+		want     int32
+	}{
+		{"open long", 0, 5, 100, 50},
+		{"open short", 0, -5, 100, 50},
+		{"add to long", 3, 2, 100, 20},
+		{"add to short", -3, -2, 100, 20},
+		{"reduce long", 5, -3, 100, 0},
+		{"close short", -5, 5, 100, 0},
+		{"flip long to short", 5, -8, 100, 30},
+		{"flip short to long", -2, 6, 100, 40},
+		{"rounded", 0, 1, 33.3, 3},
+	} {
+		if have := s.OpeningMargin(tc.position, tc.volume, tc.price); have != tc.want {
+			t.Fatalf("%v: have %v, want %v", tc.name, have, tc.want)
+		}
+	}
+
+	if have := (Settlement{}).OpeningMargin(0, 5, 100); have != 0 {
+		t.Fatalf("cash settlement blocks no margin, have %v", have)
+	}
+}
+
+func TestNewSettlement(t *testing.T) {
+	for _, tc := range []struct {
+		mode    string
+		margin  float64
+		futures bool
+		err     bool
+	}{
+		{"", 0, false, false},
+		{SettlementCash, 0.5, false, false},
+		{SettlementFutures, 1, true, false},
+		{SettlementFutures, 0, false, true},
+		{SettlementFutures, 1.5, false, true},
+		{"margin", 0.1, false, true},
+	} {
+		s, err := NewSettlement(tc.mode, tc.margin)
+		if (err != nil) != tc.err || s.Futures != tc.futures {
+			t.Fatalf("%q %v: have %+v, %v", tc.mode, tc.margin, s, err)
+		}
+	}
+}
+
+type positionKey struct {
+	userid string
+	ticker string
+}
+
+// futuresRepo keeps futures positions in memory for listener tests,
+// SQL of OrdersRepositoryMySql is checked in orders_repo_mysql_test.go
+type futuresRepo struct {
+	OrdersRepository
+
+	balances    map[string]int32
+	positions   map[positionKey]*futuresPosition
+	settledAt   map[positionKey]int32
+	settlements []SettlementRecord
+	fail        bool
+}
+
+func (r *futuresRepo) ChangeFuturesPosition(userid string, ticker string, volumeChange int32, price float32, marginRate float64, at int32) (*Position, error) {
+	key := positionKey{userid: userid, ticker: ticker}
+	p, ok := r.positions[key]
+	if !ok {
+		p = &futuresPosition{userid: userid, settlementPrice: float64(price)}
+		r.positions[key] = p
+		r.settledAt[key] = at
+	}
+	oldMargin := p.margin
+	p.variation += fillVariation(volumeChange, p.settlementPrice, price)
+	p.volume += volumeChange
+	p.margin = initialMargin(marginRate, p.volume, p.settlementPrice)
+	r.balances[userid] += oldMargin - p.margin
+	return &Position{Ticker: ticker, Volume: p.volume, Margin: p.margin, SettlementPrice: p.settlementPrice}, nil
+}
+
+func (r *futuresRepo) SettleVariationMargin(ticker string, price float32, date string, at int32, marginRate float64, final bool) (int64, error) {
+	if r.fail {
+		return 0, errors.New("database is down")
+	}
+	var settled int64
+	for key, p := range r.positions {
+		if key.ticker != ticker || (!final && r.settledAt[key] >= at) {
+			continue
+		}
+		variation := dayVariation(p.variation, p.volume, p.settlementPrice, price)
+		var margin int32
+		if !final {
+			margin = initialMargin(marginRate, p.volume, float64(price))
+		}
+		r.balances[key.userid] += variation + p.margin - margin
+		r.settlements = append(r.settlements, SettlementRecord{Date: date, Ticker: ticker, Volume: p.volume, Variation: variation, Margin: margin})
+
+		p.margin, p.settlementPrice, p.variation = margin, float64(price), 0
+		r.settledAt[key] = at
+		if final || p.volume == 0 {
+			delete(r.positions, key)
+		}
+		settled++
+	}
+	return settled, nil
+}
+
+type clearingExchange struct {
+	exchange.ExchangeClient
+	report *exchange.ClearingReport
+}
+
+func (e *clearingExchange) Clearing(ctx context.Context, in *exchange.ClearingRequest, opts ...grpc.CallOption) (*exchange.ClearingReport, error) {
+	return e.report, nil
+}
+
+func TestVariationMargin(t *testing.T) {
+	repo := &futuresRepo{
+		balances:  map[string]int32{"alice": 10000, "bob": 10000},
+		positions: make(map[positionKey]*futuresPosition),
+		settledAt: make(map[positionKey]int32),
+	}
+	settlement, _ := NewSettlement(SettlementFutures, 0.1)
+	o := &OrdersListener{
+		Logger:           &custlog.Logger{Zap: zap.NewNop()},
+		BrokerID:         &exchange.BrokerID{ID: 123},
+		OrdersRepository: repo,
+		Settlement:       settlement,
+	}
+	exch := &clearingExchange{}
+	fill := func(userid string, typ string, volume int32, price float32, at int32) {
+		o.applyFuturesFill(&exchange.Deal{Ticker: "RTS-6.19", Volume: volume, Price: price, Time: at}, &Deal{Type: typ}, userid)
+	}
+	position := func(userid string) *futuresPosition {
+		return repo.positions[positionKey{userid: userid, ticker: "RTS-6.19"}]
+	}
+	clearing := func(date string, at int32, price float32) {
+		exch.report = &exchange.ClearingReport{
+			Date:        date,
+			Time:        at,
+			Instruments: []*exchange.InstrumentSettlement{{Ticker: "RTS-6.19", SettlementPrice: price}},
+		}
+		o.settleDay(context.Background(), exch)
+	}
+	expect := func(when string, userid string, balance int32, margin int32) {
+		t.Helper()
+		if have := repo.balances[userid]; have != balance {
+			t.Fatalf("%v: %v balance have %v, want %v", when, userid, have, balance)
+		}
+		if p := position(userid); p != nil && p.margin != margin {
+			t.Fatalf("%v: %v margin have %v, want %v", when, userid, p.margin, margin)
+		}
+	}
+
+	// day 1: 10 bought at 100, 4 of them sold at 110, the rest is moved to 105 at clearing
+	fill("alice", "buy", 10, 100, 1000)
+	expect("open", "alice", 9900, 100)
+	fill("alice", "sell", 4, 110, 1100)
+	expect("reduce", "alice", 9940, 60)
+	clearing("2019-06-03", 2000, 105)
+	// variation 4*10 + 6*5 = 70, margin 60 -> 63
+	expect("day 1", "alice", 10007, 63)
+
+	// the same report is paid once, after restart of listener too
+	clearing("2019-06-03", 2000, 105)
+	expect("day 1 again", "alice", 10007, 63)
+	o.lastClearing = ""
+	clearing("2019-06-03", 2000, 105)
+	expect("day 1 after restart", "alice", 10007, 63)
+
+	// position in other ticker is not moved by clearing of this one
+	o.applyFuturesFill(&exchange.Deal{Ticker: "Si-6.19", Volume: 1, Price: 60, Time: 1500}, &Deal{Type: "buy"}, "bob")
+	expect("other ticker", "bob", 9994, 0)
+
+	// day 2: 2 more bought at 104, bob opens after clearing close, all 8 are moved to 100
+	fill("alice", "buy", 2, 104, 2500)
+	expect("add", "alice", 9986, 84)
+	fill("bob", "buy", 1, 100, 3500)
+	repo.fail = true
+	clearing("2019-06-04", 3000, 100)
+	expect("failed day 2", "alice", 9986, 84)
+	repo.fail = false
+	clearing("2019-06-04", 3000, 100)
+	// variation 2*1 + 8*-5 = -38, margin 84 -> 80
+	expect("day 2", "alice", 9952, 80)
+	expect("day 2", "bob", 9984, 10)
+
+	// balance without margin is profit: -1000 + 440 - 208 + 8*100 = 32
+	if have := repo.balances["alice"] + position("alice").margin - 10000; have != 32 {
+		t.Fatalf("profit have %v, want 32", have)
+	}
+	if len(repo.settlements) != 2 || repo.settlements[0].Variation != 70 || repo.settlements[1].Variation != -38 {
+		t.Fatalf("settlements dont match: %+v", repo.settlements)
+	}
+
+	// expiry releases margin and closes position at final price
+	o.settle(&exchange.Deal{Ticker: "RTS-6.19", Price: 101, Time: 4000, Status: exchange.OrderStatus_SETTLED})
+	expect("expiry", "alice", 9952+8+80, 0)
+	expect("expiry", "bob", 9984+1+10, 0)
+	if len(repo.positions) != 1 || repo.positions[positionKey{userid: "bob", ticker: "Si-6.19"}] == nil {
+		t.Fatalf("positions must be closed: %v", repo.positions)
+	}
+}
@@ -1,6 +1,7 @@
 package router
 
 import (
+	"crypto/tls"
 	"html/template"
 	"net/http"
 	"os"
@@ -25,6 +26,14 @@ type BrokerApp struct {
 	UserRepo    *user.UserRepository
 	OrdersRepo  *orders.OrdersRepository
 	Logger      *custlog.Logger
+
+	// comma separated primary and standby exchange addresses
+	ExchangeAddress string
+	// grpc connection to exchange is plaintext if nil
+	ExchangeTLS *tls.Config
+
+	// cash if zero
+	Settlement orders.Settlement
 }
 
 func (a *BrokerApp) Initialize(sessRepo *session.SessionRepository, userRepo *user.UserRepository, ordersRepo *orders.OrdersRepository) {
@@ -47,8 +56,9 @@ func (a *BrokerApp) Initialize(sessRepo *session.SessionRepository, userRepo *us
 	}
 
 	ExchangeClient := &exchclient.OrderExchClientGRPC{
-		ExchServerAddress: "127.0.0.1:8082",
+		ExchServerAddress: a.ExchangeAddress,
 		BrokerID:          123,
+		TLS:               a.ExchangeTLS,
 	}
 	errexch := ExchangeClient.Init()
 	if errexch != nil {
@@ -61,6 +71,7 @@ func (a *BrokerApp) Initialize(sessRepo *session.SessionRepository, userRepo *us
 		ExchClient:      ExchangeClient,
 		ClientID:        11,
 		HistoryDepthMin: 15,
+		Settlement:      a.Settlement,
 	}
 
 	UserHandlers := &handlers.UserHandlers{
@@ -94,6 +105,7 @@ func (a *BrokerApp) Initialize(sessRepo *session.SessionRepository, userRepo *us
 	r1.HandleFunc("/cancel", OrderHandlers.CancelDealHr)
 	r1.HandleFunc("/status", OrderHandlers.GetStatus)
 	r1.HandleFunc("/history", OrderHandlers.GetHistory)
+	r1.HandleFunc("/settlements", OrderHandlers.GetSettlements)
 	r1.Use(AuthMiddlware.Auth)
 
 	r.HandleFunc("/login", UserClientHandlers.Login)
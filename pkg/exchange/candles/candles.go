@@ -0,0 +1,30 @@
+package candles
+
+import (
+	"errors"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+)
+
+// CandlesStorage aggregates tick feed into bars of fixed intervals and keeps completed bars for queries
+type CandlesStorage interface {
+	Start(feed <-chan tickers.Tick)
+	Intervals() []time.Duration
+
+	// completed bars with Time in [from, to] ordered by Time and Ticker, empty ticker means all tickers.
+	// after is cursor returned as next by previous call, next is empty when nothing left.
+	Get(ticker string, interval time.Duration, from time.Time, to time.Time, after string, limit int) (candles []*exchange.OHLCV, next string, err error)
+}
+
+// Market gives order book state which is put into bar on close
+type Market interface {
+	BestBidAsk(ticker string) (bid float32, ask float32)
+	OpenInterest(ticker string) int64
+}
+
+var (
+	ErrorUnknownInterval = errors.New("candles are not collected for this interval")
+	ErrorBadCursor       = errors.New("malformed page token")
+)
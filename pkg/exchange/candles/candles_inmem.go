@@ -0,0 +1,304 @@
+package candles
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+)
+
+// CandlesInMem keeps last Retention bars per ticker and interval in memory.
+// Bars are aligned to interval boundaries, Time of a bar is the end of its interval.
+type CandlesInMem struct {
+	BarIntervals []time.Duration
+	Retention    int
+	Clock        clock.Clock
+
+	// bar is closed by clock this long after its end, so late ticks of the feed still get into it
+	CloseDelay time.Duration
+
+	lock     *sync.RWMutex
+	bars     map[seriesKey][]*exchange.OHLCV
+	current  map[seriesKey]*exchange.OHLCV
+	turnover map[seriesKey]float64 // price * volume of current bar for VWAP
+	lastID   int64
+	market   Market
+}
+
+type seriesKey struct {
+	ticker   string
+	interval time.Duration
+}
+
+func (c *CandlesInMem) Init() error {
+	if len(c.BarIntervals) == 0 {
+		return fmt.Errorf("%w: no intervals configured", ErrorUnknownInterval)
+	}
+	for _, iv := range c.BarIntervals {
+		if iv < time.Second || iv%time.Second != 0 {
+			return fmt.Errorf("%w: %v, whole seconds expected", ErrorUnknownInterval, iv)
+		}
+	}
+	if c.Retention <= 0 {
+		c.Retention = 86400
+	}
+	if c.Clock == nil {
+		c.Clock = clock.Real{}
+	}
+	if c.CloseDelay <= 0 {
+		c.CloseDelay = time.Second
+	}
+
+	c.lock = &sync.RWMutex{}
+	c.bars = make(map[seriesKey][]*exchange.OHLCV, 2*len(c.BarIntervals))
+	c.current = make(map[seriesKey]*exchange.OHLCV, 2*len(c.BarIntervals))
+	c.turnover = make(map[seriesKey]float64, 2*len(c.BarIntervals))
+	return nil
+}
+
+// SetMarket makes closed bars carry best bid/ask and open interest, may be called after Start
+func (c *CandlesInMem) SetMarket(m Market) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.market = m
+}
+
+func (c *CandlesInMem) Intervals() []time.Duration {
+	return c.BarIntervals
+}
+
+func (c *CandlesInMem) Start(feed <-chan tickers.Tick) {
+	smallest := c.BarIntervals[0]
+	for _, iv := range c.BarIntervals {
+		if iv < smallest {
+			smallest = iv
+		}
+	}
+
+	go func() {
+		ticker := c.Clock.NewTicker(smallest)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case t, ok := <-feed:
+				if !ok {
+					c.closeExpired(time.Time{}, true)
+					return
+				}
+				c.add(t)
+			case now := <-ticker.C():
+				c.closeExpired(now, false)
+			}
+		}
+	}()
+}
+
+func (c *CandlesInMem) add(t tickers.Tick) {
+	c.lock.Lock()
+	closed := make([]*exchange.OHLCV, 0, len(c.BarIntervals))
+	for _, iv := range c.BarIntervals {
+		key := seriesKey{ticker: t.Ticker, interval: iv}
+		end := int32(t.Timestamp.Truncate(iv).Add(iv).Unix())
+
+		bar, ok := c.current[key]
+		if ok && end < bar.Time {
+			// tick is older than the bar being built, its bar is already closed
+			continue
+		}
+		if ok && end > bar.Time {
+			closed = append(closed, c.close(key))
+			ok = false
+		}
+		if !ok {
+			c.current[key] = &exchange.OHLCV{
+				Time:     end,
+				Interval: int32(iv / time.Second),
+				Open:     t.Last,
+				High:     t.Last,
+				Low:      t.Last,
+				Close:    t.Last,
+				Volume:   t.Vol,
+				Ticker:   t.Ticker,
+				Trades:   1,
+			}
+			c.turnover[key] = float64(t.Last) * float64(t.Vol)
+			continue
+		}
+
+		if t.Last > bar.High {
+			bar.High = t.Last
+		}
+		if t.Last < bar.Low {
+			bar.Low = t.Last
+		}
+		bar.Close = t.Last
+		bar.Volume += t.Vol
+		bar.Trades++
+		c.turnover[key] += float64(t.Last) * float64(t.Vol)
+	}
+	c.lock.Unlock()
+
+	c.store(closed)
+}
+
+func (c *CandlesInMem) closeExpired(now time.Time, all bool) {
+	c.lock.Lock()
+	closed := make([]*exchange.OHLCV, 0, len(c.current))
+	for key, bar := range c.current {
+		if all || !now.Before(time.Unix(int64(bar.Time), 0).Add(c.CloseDelay)) {
+			closed = append(closed, c.close(key))
+		}
+	}
+	c.lock.Unlock()
+
+	c.store(closed)
+}
+
+// close takes bar out of current ones, lock must be held
+func (c *CandlesInMem) close(key seriesKey) *exchange.OHLCV {
+	bar := c.current[key]
+	delete(c.current, key)
+
+	if bar.Volume > 0 {
+		bar.VWAP = float32(c.turnover[key] / float64(bar.Volume))
+	}
+	delete(c.turnover, key)
+	return bar
+}
+
+// store adds closed bars to history with market snapshot, which is taken without lock
+// as shards answer it. Bars are closed and stored by feed goroutine only, so they keep order.
+func (c *CandlesInMem) store(closed []*exchange.OHLCV) {
+	if len(closed) == 0 {
+		return
+	}
+
+	c.lock.RLock()
+	market := c.market
+	c.lock.RUnlock()
+	if market != nil {
+		for _, bar := range closed {
+			bar.BestBid, bar.BestAsk = market.BestBidAsk(bar.Ticker)
+			bar.OpenInterest = market.OpenInterest(bar.Ticker)
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, bar := range closed {
+		key := seriesKey{ticker: bar.Ticker, interval: time.Duration(bar.Interval) * time.Second}
+		c.lastID++
+		bar.ID = c.lastID
+
+		bars := append(c.bars[key], bar)
+		if len(bars) > c.Retention {
+			bars = bars[len(bars)-c.Retention:]
+		}
+		c.bars[key] = bars
+	}
+}
+
+func (c *CandlesInMem) Get(ticker string, interval time.Duration, from time.Time, to time.Time, after string, limit int) ([]*exchange.OHLCV, string, error) {
+	known := false
+	for _, iv := range c.BarIntervals {
+		if iv == interval {
+			known = true
+		}
+	}
+	if !known {
+		return nil, "", ErrorUnknownInterval
+	}
+
+	afterTime := int32(0)
+	afterTicker := ""
+	if after != "" {
+		parts := strings.SplitN(after, ":", 2)
+		if len(parts) != 2 {
+			return nil, "", ErrorBadCursor
+		}
+		t, err := strconv.ParseInt(parts[0], 10, 32)
+		if err != nil {
+			return nil, "", ErrorBadCursor
+		}
+		afterTime = int32(t)
+		afterTicker = parts[1]
+	}
+
+	fromTs := int32(from.Unix())
+	toTs := int32(to.Unix())
+
+	c.lock.RLock()
+	result := make([]*exchange.OHLCV, 0, limit)
+	for key, bars := range c.bars {
+		if key.interval != interval || ticker != "" && key.ticker != ticker {
+			continue
+		}
+		i := sort.Search(len(bars), func(i int) bool {
+			return bars[i].Time >= fromTs
+		})
+		for ; i < len(bars) && bars[i].Time <= toTs; i++ {
+			result = append(result, bars[i])
+		}
+	}
+	c.lock.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Time != result[j].Time {
+			return result[i].Time < result[j].Time
+		}
+		return result[i].Ticker < result[j].Ticker
+	})
+
+	if after != "" {
+		skip := sort.Search(len(result), func(i int) bool {
+			return result[i].Time > afterTime || result[i].Time == afterTime && result[i].Ticker > afterTicker
+		})
+		result = result[skip:]
+	}
+
+	if limit > 0 && len(result) > limit {
+		last := result[limit-1]
+		return result[:limit], fmt.Sprintf("%d:%s", last.Time, last.Ticker), nil
+	}
+	return result, "", nil
+}
+
+// Current returns copies of bars being built for interval ordered by ticker, VWAP is as of now
+func (c *CandlesInMem) Current(interval time.Duration) []*exchange.OHLCV {
+	c.lock.RLock()
+	result := make([]*exchange.OHLCV, 0, len(c.current))
+	for key, bar := range c.current {
+		if key.interval != interval {
+			continue
+		}
+		live := &exchange.OHLCV{
+			Time:     bar.Time,
+			Interval: bar.Interval,
+			Open:     bar.Open,
+			High:     bar.High,
+			Low:      bar.Low,
+			Close:    bar.Close,
+			Volume:   bar.Volume,
+			Ticker:   bar.Ticker,
+			Trades:   bar.Trades,
+		}
+		if bar.Volume > 0 {
+			live.VWAP = float32(c.turnover[key] / float64(bar.Volume))
+		}
+		result = append(result, live)
+	}
+	c.lock.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Ticker < result[j].Ticker
+	})
+	return result
+}
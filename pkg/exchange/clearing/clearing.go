@@ -0,0 +1,33 @@
+package clearing
+
+import (
+	"errors"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+)
+
+// Clearing accounts client fills during trading day and closes the day into report.
+// Net positions are carried over to the next day, volumes and fees start over.
+type Clearing interface {
+	Start()
+	Mark(t tickers.Tick)                                   // last price of the day becomes settlement price
+	Fill(deal *exchange.Deal, buy bool)                    // execution report sent to broker
+	Expire(ticker string, price float32)                   // final settlement of futures series, open positions are closed at price
+	Close(now time.Time) (*exchange.ClearingReport, error) // closes trading day of now
+	Report(date string) (*exchange.ClearingReport, error)  // empty date means last closed day
+}
+
+// Fee is charged from client on each fill
+type Fee struct {
+	PerLot float64
+	Rate   float64 // part of turnover
+}
+
+const DateFormat = "2006-01-02"
+
+var (
+	ErrorNoReport = errors.New("no clearing report for date")
+	ErrorBadDate  = errors.New("bad date, YYYY-MM-DD expected")
+)
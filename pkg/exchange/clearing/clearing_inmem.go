@@ -0,0 +1,259 @@
+package clearing
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"go.uber.org/zap"
+)
+
+// ClearingInMem closes trading day every day at CloseAt by Clock and keeps reports in memory,
+// each report is also written to Dir as CSV and JSON.
+type ClearingInMem struct {
+	Dir     string        // no files if empty
+	CloseAt time.Duration // since local midnight
+	Fees    map[string]Fee
+	Clock   clock.Clock
+	Logger  *zap.SugaredLogger
+
+	lock      *sync.Mutex
+	accounts  map[accountKey]*exchange.ClearingPosition // today
+	prices    map[string]float32
+	volumes   map[string]int64 // today by ticker
+	expired   map[string]bool  // today, their prices are final
+	reports   map[string]*exchange.ClearingReport
+	lastDate  string
+	nextClose time.Time
+}
+
+type accountKey struct {
+	brokerID int32
+	clientID int32
+	ticker   string
+}
+
+func (c *ClearingInMem) Init() error {
+	if c.CloseAt < 0 || c.CloseAt >= 24*time.Hour {
+		return fmt.Errorf("close time must be within a day, got %v", c.CloseAt)
+	}
+	if c.Clock == nil {
+		c.Clock = clock.Real{}
+	}
+	if c.Logger == nil {
+		c.Logger = zap.NewNop().Sugar()
+	}
+
+	c.lock = &sync.Mutex{}
+	c.accounts = make(map[accountKey]*exchange.ClearingPosition, 10)
+	c.prices = make(map[string]float32, 2)
+	c.volumes = make(map[string]int64, 2)
+	c.expired = make(map[string]bool, 1)
+	c.reports = make(map[string]*exchange.ClearingReport, 1)
+	c.nextClose = c.closeAfter(c.Clock.Now())
+	return nil
+}
+
+// first day close strictly after t
+func (c *ClearingInMem) closeAfter(t time.Time) time.Time {
+	y, m, d := t.Date()
+	at := time.Date(y, m, d, 0, 0, 0, 0, t.Location()).Add(c.CloseAt)
+	if !at.After(t) {
+		at = time.Date(y, m, d+1, 0, 0, 0, 0, t.Location()).Add(c.CloseAt)
+	}
+	return at
+}
+
+func (c *ClearingInMem) Start() {
+	go func() {
+		ticker := c.Clock.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for now := range ticker.C() {
+			c.lock.Lock()
+			due := !now.Before(c.nextClose)
+			at := c.nextClose
+			c.lock.Unlock()
+			if !due {
+				continue
+			}
+
+			report, err := c.Close(at)
+			if err != nil {
+				c.Logger.Errorw("Clearing failed", "error", err)
+				continue
+			}
+			c.Logger.Infow("Trading day closed", "date", report.Date, "positions", len(report.Positions))
+		}
+	}()
+}
+
+func (c *ClearingInMem) Mark(t tickers.Tick) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.expired[t.Ticker] {
+		return
+	}
+	c.prices[t.Ticker] = t.Last
+}
+
+// Expire closes open positions of series at settlement price, they are reported as bought or sold back.
+// Repeated calls do nothing.
+func (c *ClearingInMem) Expire(ticker string, price float32) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.expired[ticker] {
+		return
+	}
+	c.expired[ticker] = true
+	c.prices[ticker] = price
+	for key, acc := range c.accounts {
+		if key.ticker != ticker || acc.NetPosition == 0 {
+			continue
+		}
+		volume := acc.NetPosition
+		if volume > 0 {
+			acc.SoldVolume += volume
+		} else {
+			volume = -volume
+			acc.BoughtVolume += volume
+		}
+		acc.Turnover += float64(price) * float64(volume)
+		acc.NetPosition = 0
+	}
+}
+
+func (c *ClearingInMem) Fill(deal *exchange.Deal, buy bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	key := accountKey{brokerID: deal.BrokerID, clientID: deal.ClientID, ticker: deal.Ticker}
+	acc, ok := c.accounts[key]
+	if !ok {
+		acc = &exchange.ClearingPosition{
+			BrokerID: deal.BrokerID,
+			ClientID: deal.ClientID,
+			Ticker:   deal.Ticker,
+		}
+		c.accounts[key] = acc
+	}
+
+	volume := int64(deal.Volume)
+	turnover := float64(deal.Price) * float64(deal.Volume)
+	if buy {
+		acc.BoughtVolume += volume
+		acc.NetPosition += volume
+	} else {
+		acc.SoldVolume += volume
+		acc.NetPosition -= volume
+	}
+	acc.Turnover += turnover
+	fee := c.Fees[deal.Ticker]
+	acc.Fees += fee.PerLot*float64(volume) + fee.Rate*turnover
+	c.volumes[deal.Ticker] += volume
+}
+
+// Close builds report of trading day of now, writes its files and starts next day
+func (c *ClearingInMem) Close(now time.Time) (*exchange.ClearingReport, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// day closing at or after now, trading after CloseAt belongs to the next day
+	date := c.closeAfter(now.Add(-time.Nanosecond)).Format(DateFormat)
+	report := &exchange.ClearingReport{
+		Date:        date,
+		Time:        int32(now.Unix()),
+		Positions:   make([]*exchange.ClearingPosition, 0, len(c.accounts)),
+		Instruments: make([]*exchange.InstrumentSettlement, 0, len(c.prices)),
+	}
+
+	next := make(map[accountKey]*exchange.ClearingPosition, len(c.accounts))
+	for key, acc := range c.accounts {
+		report.Positions = append(report.Positions, &exchange.ClearingPosition{
+			BrokerID:        acc.BrokerID,
+			ClientID:        acc.ClientID,
+			Ticker:          acc.Ticker,
+			NetPosition:     acc.NetPosition,
+			BoughtVolume:    acc.BoughtVolume,
+			SoldVolume:      acc.SoldVolume,
+			Turnover:        acc.Turnover,
+			Fees:            acc.Fees,
+			SettlementPrice: c.prices[key.ticker],
+		})
+
+		// only open positions go to the next day
+		if acc.NetPosition != 0 {
+			next[key] = &exchange.ClearingPosition{
+				BrokerID:    acc.BrokerID,
+				ClientID:    acc.ClientID,
+				Ticker:      acc.Ticker,
+				NetPosition: acc.NetPosition,
+			}
+		}
+	}
+	sort.Slice(report.Positions, func(i, j int) bool {
+		a, b := report.Positions[i], report.Positions[j]
+		if a.BrokerID != b.BrokerID {
+			return a.BrokerID < b.BrokerID
+		}
+		if a.ClientID != b.ClientID {
+			return a.ClientID < b.ClientID
+		}
+		return a.Ticker < b.Ticker
+	})
+
+	for ticker, price := range c.prices {
+		report.Instruments = append(report.Instruments, &exchange.InstrumentSettlement{
+			Ticker:          ticker,
+			SettlementPrice: price,
+			Volume:          c.volumes[ticker],
+		})
+	}
+	sort.Slice(report.Instruments, func(i, j int) bool {
+		return report.Instruments[i].Ticker < report.Instruments[j].Ticker
+	})
+
+	if c.Dir != "" {
+		err := WriteReport(c.Dir, report)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// expired series are not traded any more
+	for ticker := range c.expired {
+		delete(c.prices, ticker)
+	}
+	c.accounts = next
+	c.volumes = make(map[string]int64, len(c.volumes))
+	c.expired = make(map[string]bool, 1)
+	c.reports[date] = report
+	c.lastDate = date
+	c.nextClose = c.closeAfter(now)
+	return report, nil
+}
+
+func (c *ClearingInMem) Report(date string) (*exchange.ClearingReport, error) {
+	if date != "" {
+		if _, err := time.Parse(DateFormat, date); err != nil {
+			return nil, fmt.Errorf("%w: %q", ErrorBadDate, date)
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if date == "" {
+		date = c.lastDate
+	}
+	report, ok := c.reports[date]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrorNoReport, date)
+	}
+	return report, nil
+}
@@ -0,0 +1,135 @@
+package clearing
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+)
+
+func TestClearingDayClose(t *testing.T) {
+	start := time.Date(2023, 5, 17, 10, 0, 0, 0, time.Local)
+	dir := t.TempDir()
+	c := &ClearingInMem{
+		Dir:     dir,
+		CloseAt: 23*time.Hour + 50*time.Minute,
+		Fees:    map[string]Fee{"SPFB.RTS": {PerLot: 0.5, Rate: 0.001}},
+		Clock:   clock.NewManual(start),
+	}
+	if err := c.Init(); err != nil {
+		t.Fatalf("cant init clearing: %v", err)
+	}
+
+	c.Mark(tickers.Tick{Ticker: "SPFB.RTS", Last: 100})
+	c.Fill(&exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 3, Price: 100}, true)
+	c.Fill(&exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 1, Price: 110}, false)
+	c.Fill(&exchange.Deal{BrokerID: 124, ClientID: 7, Ticker: "SPFB.Si", Volume: 2, Price: 50}, false)
+	c.Mark(tickers.Tick{Ticker: "SPFB.RTS", Last: 105})
+	c.Mark(tickers.Tick{Ticker: "SPFB.Si", Last: 51})
+
+	report, err := c.Close(start.Add(13*time.Hour + 50*time.Minute))
+	if err != nil {
+		t.Fatalf("cant close day: %v", err)
+	}
+	if report.Date != "2023-05-17" {
+		t.Fatalf("report date dont match: have %v", report.Date)
+	}
+
+	expected := []*exchange.ClearingPosition{
+		{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", NetPosition: 2, BoughtVolume: 3, SoldVolume: 1, Turnover: 410, Fees: 2 + 0.41, SettlementPrice: 105},
+		{BrokerID: 124, ClientID: 7, Ticker: "SPFB.Si", NetPosition: -2, SoldVolume: 2, Turnover: 100, SettlementPrice: 51},
+	}
+	if len(report.Positions) != len(expected) {
+		t.Fatalf("positions count dont match: have %v, want %v", len(report.Positions), len(expected))
+	}
+	for i, want := range expected {
+		have := report.Positions[i]
+		if have.BrokerID != want.BrokerID || have.ClientID != want.ClientID || have.Ticker != want.Ticker ||
+			have.NetPosition != want.NetPosition || have.BoughtVolume != want.BoughtVolume || have.SoldVolume != want.SoldVolume ||
+			have.Turnover != want.Turnover || have.Fees != want.Fees || have.SettlementPrice != want.SettlementPrice {
+			t.Fatalf("position %v dont match\nhave %v\nwant %v", i, have, want)
+		}
+	}
+	if len(report.Instruments) != 2 || report.Instruments[0].Volume != 4 || report.Instruments[1].SettlementPrice != 51 {
+		t.Fatalf("instruments dont match: %v", report.Instruments)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "clearing-2023-05-17.csv"))
+	if err != nil {
+		t.Fatalf("cant open csv report: %v", err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("csv report has %v rows, error %v", len(rows), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "clearing-2023-05-17.json")); err != nil {
+		t.Fatalf("no json report: %v", err)
+	}
+
+	// open positions are carried over, volumes start over
+	next, err := c.Close(start.Add(37*time.Hour + 50*time.Minute))
+	if err != nil {
+		t.Fatalf("cant close next day: %v", err)
+	}
+	if next.Date != "2023-05-18" || len(next.Positions) != 2 || next.Positions[0].NetPosition != 2 || next.Positions[0].BoughtVolume != 0 {
+		t.Fatalf("next day report dont match: %v", next)
+	}
+
+	last, err := c.Report("")
+	if err != nil || last.Date != "2023-05-18" {
+		t.Fatalf("last report dont match: %v, %v", last, err)
+	}
+	if _, err := c.Report("2023-05-16"); !errors.Is(err, ErrorNoReport) {
+		t.Fatalf("expected ErrorNoReport, got %v", err)
+	}
+	if _, err := c.Report("yesterday"); !errors.Is(err, ErrorBadDate) {
+		t.Fatalf("expected ErrorBadDate, got %v", err)
+	}
+}
+
+func TestClearingExpire(t *testing.T) {
+	start := time.Date(2023, 6, 15, 10, 0, 0, 0, time.Local)
+	c := &ClearingInMem{CloseAt: 23 * time.Hour, Clock: clock.NewManual(start)}
+	if err := c.Init(); err != nil {
+		t.Fatalf("cant init clearing: %v", err)
+	}
+
+	c.Fill(&exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: "RTS-6.23", Volume: 3, Price: 100}, true)
+	c.Fill(&exchange.Deal{BrokerID: 123, ClientID: 2, Ticker: "RTS-6.23", Volume: 2, Price: 101}, false)
+	c.Expire("RTS-6.23", 104)
+	c.Expire("RTS-6.23", 90)
+	c.Mark(tickers.Tick{Ticker: "RTS-6.23", Last: 95})
+
+	report, err := c.Close(start.Add(13 * time.Hour))
+	if err != nil {
+		t.Fatalf("cant close day: %v", err)
+	}
+	if len(report.Positions) != 2 {
+		t.Fatalf("expected 2 positions, have %v", report.Positions)
+	}
+	long, short := report.Positions[0], report.Positions[1]
+	if long.NetPosition != 0 || long.SoldVolume != 3 || long.Turnover != 300+312 || long.SettlementPrice != 104 {
+		t.Fatalf("long position is not settled: %v", long)
+	}
+	if short.NetPosition != 0 || short.BoughtVolume != 2 || short.Turnover != 202+208 {
+		t.Fatalf("short position is not settled: %v", short)
+	}
+	if len(report.Instruments) != 1 || report.Instruments[0].SettlementPrice != 104 || report.Instruments[0].Volume != 5 {
+		t.Fatalf("instruments dont match: %v", report.Instruments)
+	}
+
+	next, err := c.Close(start.Add(37 * time.Hour))
+	if err != nil {
+		t.Fatalf("cant close next day: %v", err)
+	}
+	if len(next.Positions) != 0 || len(next.Instruments) != 0 {
+		t.Fatalf("expired series must not be carried over: %v", next)
+	}
+}
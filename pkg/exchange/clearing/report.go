@@ -0,0 +1,63 @@
+package clearing
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+var csvHeader = []string{
+	"date", "broker_id", "client_id", "ticker", "net_position",
+	"bought_volume", "sold_volume", "turnover", "fees", "settlement_price",
+}
+
+// WriteReport writes clearing-<date>.csv with positions and clearing-<date>.json with whole report to dir
+func WriteReport(dir string, report *exchange.ClearingReport) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("can't create clearing dir: %w", err)
+	}
+	base := filepath.Join(dir, "clearing-"+report.Date)
+
+	data, err := protojson.MarshalOptions{Multiline: true, EmitUnpopulated: true}.Marshal(report)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(base+".json", data, 0644)
+	if err != nil {
+		return fmt.Errorf("can't write clearing report: %w", err)
+	}
+
+	f, err := os.Create(base + ".csv")
+	if err != nil {
+		return fmt.Errorf("can't write clearing report: %w", err)
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write(csvHeader)
+	for _, p := range report.Positions {
+		w.Write([]string{
+			report.Date,
+			strconv.Itoa(int(p.BrokerID)),
+			strconv.Itoa(int(p.ClientID)),
+			p.Ticker,
+			strconv.FormatInt(p.NetPosition, 10),
+			strconv.FormatInt(p.BoughtVolume, 10),
+			strconv.FormatInt(p.SoldVolume, 10),
+			strconv.FormatFloat(p.Turnover, 'f', -1, 64),
+			strconv.FormatFloat(p.Fees, 'f', -1, 64),
+			strconv.FormatFloat(float64(p.SettlementPrice), 'f', -1, 32),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("can't write clearing report: %w", err)
+	}
+	return f.Close()
+}
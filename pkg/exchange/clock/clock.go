@@ -0,0 +1,15 @@
+package clock
+
+import "time"
+
+// Clock is the source of time for the exchange and tick sources,
+// so simulations can run on manually stepped time instead of wall clock
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
@@ -0,0 +1,135 @@
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Manual clock stands still until Advance or Set is called.
+// Tickers fire in chronological order while time is moved, like time.Ticker slow consumers miss ticks.
+type Manual struct {
+	lock    *sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	tickers []*manualTicker
+}
+
+type manualTicker struct {
+	clock  *Manual
+	c      chan time.Time
+	period time.Duration
+	next   time.Time
+}
+
+func NewManual(now time.Time) *Manual {
+	m := &Manual{
+		lock:    &sync.Mutex{},
+		now:     now,
+		tickers: make([]*manualTicker, 0, 4),
+	}
+	m.changed = sync.NewCond(m.lock)
+	return m
+}
+
+func (m *Manual) Now() time.Time {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.now
+}
+
+func (m *Manual) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for clock.Manual.NewTicker")
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	t := &manualTicker{
+		clock:  m,
+		c:      make(chan time.Time, 1),
+		period: d,
+		next:   m.now.Add(d),
+	}
+	m.tickers = append(m.tickers, t)
+	m.changed.Broadcast()
+	return t
+}
+
+// Advance moves time forward firing every ticker deadline on the way
+func (m *Manual) Advance(d time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.moveTo(m.now.Add(d))
+}
+
+// Set moves time to t, moving backwards does not fire tickers
+func (m *Manual) Set(t time.Time) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if t.Before(m.now) {
+		m.now = t
+		for _, tk := range m.tickers {
+			tk.next = t.Add(tk.period)
+		}
+		return
+	}
+	m.moveTo(t)
+}
+
+// WaitTickers blocks until at least n tickers are running,
+// so time is not advanced before consumer started to wait for it
+func (m *Manual) WaitTickers(n int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for len(m.tickers) < n {
+		m.changed.Wait()
+	}
+}
+
+// lock must be held
+func (m *Manual) moveTo(target time.Time) {
+	for {
+		var next *manualTicker
+		for _, tk := range m.tickers {
+			if tk.next.After(target) {
+				continue
+			}
+			if next == nil || tk.next.Before(next.next) {
+				next = tk
+			}
+		}
+		if next == nil {
+			break
+		}
+
+		m.now = next.next
+		select {
+		case next.c <- m.now:
+		default:
+		}
+		next.next = next.next.Add(next.period)
+	}
+	m.now = target
+}
+
+func (t *manualTicker) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTicker) Stop() {
+	m := t.clock
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for i, tk := range m.tickers {
+		if tk == t {
+			m.tickers = append(m.tickers[:i], m.tickers[i+1:]...)
+			break
+		}
+	}
+	m.changed.Broadcast()
+}
@@ -0,0 +1,152 @@
+package clock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2019, 5, 17, 10, 0, 0, 0, time.UTC)
+
+type advanceTest struct {
+	name     string
+	period   time.Duration
+	advance  []time.Duration
+	expected []time.Duration // tick received after each advance since start, 0 if none
+	now      time.Duration
+}
+
+var advanceTests = []advanceTest{
+	{
+		name:     "before first period",
+		period:   time.Second,
+		advance:  []time.Duration{500 * time.Millisecond},
+		expected: []time.Duration{0},
+		now:      500 * time.Millisecond,
+	},
+	{
+		name:     "exactly one period",
+		period:   time.Second,
+		advance:  []time.Duration{time.Second},
+		expected: []time.Duration{time.Second},
+		now:      time.Second,
+	},
+	{
+		name:     "several periods at once drop ticks nobody waited for",
+		period:   time.Second,
+		advance:  []time.Duration{3500 * time.Millisecond, 500 * time.Millisecond},
+		expected: []time.Duration{time.Second, 4 * time.Second},
+		now:      4 * time.Second,
+	},
+	{
+		name:     "period in small steps",
+		period:   time.Second,
+		advance:  []time.Duration{400 * time.Millisecond, 400 * time.Millisecond, 400 * time.Millisecond, 400 * time.Millisecond},
+		expected: []time.Duration{0, 0, time.Second, 0},
+		now:      1600 * time.Millisecond,
+	},
+	{
+		name:     "zero advance",
+		period:   time.Minute,
+		advance:  []time.Duration{0, time.Minute, 0},
+		expected: []time.Duration{0, time.Minute, 0},
+		now:      time.Minute,
+	},
+}
+
+// received takes tick if there is one, 0 otherwise
+func received(tk Ticker) time.Duration {
+	select {
+	case now := <-tk.C():
+		return now.Sub(start)
+	default:
+		return 0
+	}
+}
+
+func TestManualAdvance(t *testing.T) {
+	for _, tt := range advanceTests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManual(start)
+			tk := m.NewTicker(tt.period)
+			defer tk.Stop()
+
+			have := make([]time.Duration, 0, len(tt.advance))
+			for _, d := range tt.advance {
+				m.Advance(d)
+				have = append(have, received(tk))
+			}
+			if !reflect.DeepEqual(have, tt.expected) {
+				t.Fatalf("ticks dont match\nhave %v\nwant %v", have, tt.expected)
+			}
+			if now := m.Now().Sub(start); now != tt.now {
+				t.Fatalf("have now %v, want %v", now, tt.now)
+			}
+		})
+	}
+}
+
+func TestManualTickers(t *testing.T) {
+	m := NewManual(start)
+	fast := m.NewTicker(time.Second)
+	slow := m.NewTicker(3 * time.Second)
+	stopped := m.NewTicker(time.Second)
+	stopped.Stop()
+
+	// every ticker keeps its own period, stopped one gets nothing
+	m.Advance(2 * time.Second)
+	if have := received(fast); have != time.Second {
+		t.Fatalf("fast ticker have %v, want 1s", have)
+	}
+	m.Advance(time.Second)
+	if have := received(fast); have != 3*time.Second {
+		t.Fatalf("fast ticker have %v, want 3s", have)
+	}
+	if have := received(slow); have != 3*time.Second {
+		t.Fatalf("slow ticker have %v, want 3s", have)
+	}
+	if have := received(stopped); have != 0 {
+		t.Fatalf("stopped ticker fired at %v", have)
+	}
+}
+
+func TestManualSet(t *testing.T) {
+	m := NewManual(start)
+	tk := m.NewTicker(time.Second)
+	defer tk.Stop()
+
+	m.Set(start.Add(2500 * time.Millisecond))
+	if have := received(tk); have != time.Second {
+		t.Fatalf("set forward must fire ticker, have %v", have)
+	}
+
+	// backwards time stands at the new point, next tick is a period after it
+	m.Set(start)
+	if !m.Now().Equal(start) {
+		t.Fatalf("have now %v, want %v", m.Now(), start)
+	}
+	if have := received(tk); have != 0 {
+		t.Fatalf("set backwards must not fire ticker, have %v", have)
+	}
+	m.Advance(999 * time.Millisecond)
+	if have := received(tk); have != 0 {
+		t.Fatalf("ticker fired before its period at %v", have)
+	}
+	m.Advance(time.Millisecond)
+	if have := received(tk); have != time.Second {
+		t.Fatalf("ticker have %v after set backwards, want 1s", have)
+	}
+}
+
+func TestManualNewTickerPanics(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatalf("NewTicker(%v) must panic", d)
+				}
+			}()
+			NewManual(start).NewTicker(d)
+		}()
+	}
+}
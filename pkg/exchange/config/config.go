@@ -0,0 +1,818 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clearing"
+	"github.com/KSerditov/Trading/pkg/exchange/contracts"
+	"github.com/KSerditov/Trading/pkg/exchange/matching"
+	"github.com/KSerditov/Trading/pkg/exchange/simulation"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+type Config struct {
+	Listen     string `json:"listen" yaml:"listen"`
+	BufferSize int    `json:"buffer_size" yaml:"buffer_size"` // per broker Results channel
+
+	MetricsListen string `json:"metrics_listen" yaml:"metrics_listen"` // prometheus /metrics, disabled if empty
+	HTTPListen    string `json:"http_listen" yaml:"http_listen"`       // JSON/SSE gateway, disabled if empty
+	ConsoleListen string `json:"console_listen" yaml:"console_listen"` // read-only web console, disabled if empty
+
+	DrainTimeout int `json:"drain_timeout" yaml:"drain_timeout"` // seconds to deliver pending results on shutdown
+
+	Tickers TickersConfig `json:"tickers" yaml:"tickers"`
+
+	Candles CandlesConfig `json:"candles" yaml:"candles"`
+
+	Statistic StatisticConfig `json:"statistic" yaml:"statistic"`
+
+	// ticker -> instrument definition, not listed tickers use fifo matching
+	Instruments map[string]InstrumentConfig `json:"instruments" yaml:"instruments"`
+
+	// continuous ticker of feed -> its futures series, orders of continuous ticker go to the front one.
+	// Series use instrument settings of continuous ticker unless listed in instruments. Tickers not listed never expire.
+	Contracts map[string][]SeriesConfig `json:"contracts" yaml:"contracts"`
+
+	Clearing ClearingConfig `json:"clearing" yaml:"clearing"`
+
+	Simulation SimulationConfig `json:"simulation" yaml:"simulation"`
+
+	Replication ReplicationConfig `json:"replication" yaml:"replication"`
+
+	Journal JournalConfig `json:"journal" yaml:"journal"`
+
+	Chaos ChaosConfig `json:"chaos" yaml:"chaos"`
+
+	// consumer from "consumer" metadata -> allowed methods like "/main.Exchange/Create" or "/main.Exchange/*"
+	// access is not checked if empty
+	ACL map[string][]string `json:"acl" yaml:"acl"`
+
+	TLS TLSConfig `json:"tls" yaml:"tls"`
+
+	Log LogConfig `json:"log" yaml:"log"`
+}
+
+type TickersConfig struct {
+	Source         string         `json:"source" yaml:"source"` // "inmem" for text files, "binary" for files made by tickconv
+	Files          []string       `json:"files" yaml:"files"`
+	FeedBufferSize int            `json:"feed_buffer_size" yaml:"feed_buffer_size"`
+	Replay         ReplayConfig   `json:"replay" yaml:"replay"`
+	Cleaning       CleaningConfig `json:"cleaning" yaml:"cleaning"`
+}
+
+type ReplayConfig struct {
+	UseTodayDate bool    `json:"use_today_date" yaml:"use_today_date"` // ignore date from files and replay them as today
+	Speed        float64 `json:"speed" yaml:"speed"`                   // 1 is real time
+}
+
+// filters of text files applied on load, binary files are cleaned by tickconv -clean
+type CleaningConfig struct {
+	Enabled          bool    `json:"enabled" yaml:"enabled"`
+	Dedup            bool    `json:"dedup" yaml:"dedup"`
+	DropZeroVolume   bool    `json:"drop_zero_volume" yaml:"drop_zero_volume"`
+	DropOutOfOrder   bool    `json:"drop_out_of_order" yaml:"drop_out_of_order"` // moved in place otherwise
+	OutlierWindow    int     `json:"outlier_window" yaml:"outlier_window"`       // ticks on each side, 0 is off
+	OutlierThreshold float64 `json:"outlier_threshold" yaml:"outlier_threshold"` // median absolute deviations
+	MaxGap           int     `json:"max_gap" yaml:"max_gap"`                     // seconds, 0 is off
+}
+
+// history served by GetCandles
+type CandlesConfig struct {
+	Intervals []int `json:"intervals" yaml:"intervals"` // seconds
+	Retention int   `json:"retention" yaml:"retention"` // bars kept per ticker and interval
+}
+
+// live bars of Statistic stream
+type StatisticConfig struct {
+	MaxRate int `json:"max_rate" yaml:"max_rate"` // bars per second to one subscriber, unlimited if 0
+}
+
+type InstrumentConfig struct {
+	Matching        string  `json:"matching" yaml:"matching"`                   // fifo, pro_rata or hybrid
+	MinAllocation   int32   `json:"min_allocation" yaml:"min_allocation"`       // smallest pro-rata share, lots
+	TopOrderPercent float64 `json:"top_order_percent" yaml:"top_order_percent"` // hybrid only, 0 gives top order all it can take
+
+	FeePerLot float64 `json:"fee_per_lot" yaml:"fee_per_lot"` // exchange fee per traded lot
+	FeeRate   float64 `json:"fee_rate" yaml:"fee_rate"`       // exchange fee as part of turnover
+}
+
+// futures series
+type SeriesConfig struct {
+	Ticker      string `json:"ticker" yaml:"ticker"`
+	LastTrading string `json:"last_trading" yaml:"last_trading"` // YYYY-MM-DD HH:MM local time, orders are cancelled and positions settled then
+}
+
+// hot standby
+type ReplicationConfig struct {
+	Primary  string `json:"primary" yaml:"primary"`   // exchange starts as standby of this primary if set
+	Lease    int    `json:"lease" yaml:"lease"`       // seconds of primary silence before standby promotes itself, 0 - only Promote rpc
+	Consumer string `json:"consumer" yaml:"consumer"` // "consumer" metadata for ACL of primary
+}
+
+// input events for exchange-replay
+type JournalConfig struct {
+	Dir string `json:"dir" yaml:"dir"` // journal-<start time>.bin with orders, cancels, ticks and fills, not written if empty
+}
+
+// faults injected into broker streams and calls to test broker resilience, probabilities in [0, 1]
+type ChaosConfig struct {
+	DelayProbability        float64 `json:"delay_probability" yaml:"delay_probability"`                 // Results or Statistic message is late
+	MaxDelayMs              int     `json:"max_delay_ms" yaml:"max_delay_ms"`                           // random delay up to this
+	DropProbability         float64 `json:"drop_probability" yaml:"drop_probability"`                   // Results or Statistic message is lost
+	DuplicateProbability    float64 `json:"duplicate_probability" yaml:"duplicate_probability"`         // fill is sent twice
+	CreateRejectProbability float64 `json:"create_reject_probability" yaml:"create_reject_probability"` // Create fails with Unavailable
+	AbortProbability        float64 `json:"abort_probability" yaml:"abort_probability"`                 // stream breaks instead of sending message
+	Seed                    int64   `json:"seed" yaml:"seed"`
+}
+
+// end of day clearing
+type ClearingConfig struct {
+	Dir       string `json:"dir" yaml:"dir"`               // clearing-<date>.csv and .json reports, not written if empty
+	CloseTime string `json:"close_time" yaml:"close_time"` // HH:MM local time of trading day close
+}
+
+// fill models for backtests, all disabled by default
+type SimulationConfig struct {
+	LatencyMs              int     `json:"latency_ms" yaml:"latency_ms"`                             // order entry latency in market time
+	QueueAhead             float64 `json:"queue_ahead" yaml:"queue_ahead"`                           // part of volume traded at order price queued ahead of it
+	SlippageRate           float64 `json:"slippage_rate" yaml:"slippage_rate"`                       // price move against order for fill of whole tick volume, part of price
+	PartialFillProbability float64 `json:"partial_fill_probability" yaml:"partial_fill_probability"` // chance of filling only random part of allocated volume
+	Seed                   int64   `json:"seed" yaml:"seed"`                                         // same seed gives the same fills
+}
+
+// plaintext if cert is empty
+type TLSConfig struct {
+	Cert     string `json:"cert" yaml:"cert"`
+	Key      string `json:"key" yaml:"key"`
+	ClientCA string `json:"client_ca" yaml:"client_ca"` // brokers must present certificate signed by it
+
+	// client certificate CommonName -> BrokerID, requires client_ca
+	Brokers map[string]int64 `json:"brokers" yaml:"brokers"`
+
+	// client certificate CommonNames allowed to replicate, promote and set chaos, requires client_ca
+	Admins []string `json:"admins" yaml:"admins"`
+}
+
+type LogConfig struct {
+	Level  string `json:"level" yaml:"level"`   // debug, info, warn, error
+	Format string `json:"format" yaml:"format"` // console or json
+	File   string `json:"file" yaml:"file"`     // stdout if empty
+}
+
+const (
+	SourceInMem  = "inmem"
+	SourceBinary = "binary"
+
+	EnvPrefix = "EXCHANGE_"
+)
+
+var (
+	ErrorUnsupportedFormat = errors.New("unsupported config format, use .yaml, .yml or .json")
+)
+
+func Default() *Config {
+	return &Config{
+		Listen:        "127.0.0.1:8082",
+		BufferSize:    100,
+		MetricsListen: "127.0.0.1:9082",
+		HTTPListen:    "127.0.0.1:8090",
+		ConsoleListen: "127.0.0.1:8091",
+		DrainTimeout:  10,
+		Tickers: TickersConfig{
+			Source:         SourceInMem,
+			FeedBufferSize: 100,
+			Replay: ReplayConfig{
+				UseTodayDate: true,
+				Speed:        1,
+			},
+			Cleaning: defaultCleaning(),
+		},
+		Candles: CandlesConfig{
+			Intervals: []int{1, 60},
+			Retention: 86400,
+		},
+		Clearing: ClearingConfig{
+			Dir:       "./clearing",
+			CloseTime: "23:50",
+		},
+		Simulation: SimulationConfig{
+			Seed: 1,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "console",
+		},
+	}
+}
+
+func defaultCleaning() CleaningConfig {
+	d := tickers.DefaultCleaning()
+	return CleaningConfig{
+		Dedup:            d.Dedup,
+		DropZeroVolume:   d.DropZeroVolume,
+		DropOutOfOrder:   d.DropOutOfOrder,
+		OutlierWindow:    d.OutlierWindow,
+		OutlierThreshold: d.OutlierThreshold,
+		MaxGap:           int(d.MaxGap / time.Second),
+	}
+}
+
+// Load reads config file over defaults, applies EXCHANGE_* environment overrides and validates result.
+// Empty path means defaults and environment only.
+func Load(path string) (*Config, error) {
+	c, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Read is Load without validation, for tools which need only some sections
+func Read(path string) (*Config, error) {
+	c := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("can't read config: %w", err)
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			dec := yaml.NewDecoder(bytes.NewReader(data))
+			dec.KnownFields(true)
+			err = dec.Decode(c)
+		case ".json":
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields()
+			err = dec.Decode(c)
+		default:
+			return nil, ErrorUnsupportedFormat
+		}
+		if err != nil {
+			return nil, fmt.Errorf("can't parse config %v: %w", path, err)
+		}
+	}
+
+	err := c.applyEnv()
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) applyEnv() error {
+	var err error
+
+	if v, ok := os.LookupEnv(EnvPrefix + "LISTEN"); ok {
+		c.Listen = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "BUFFER_SIZE"); ok {
+		c.BufferSize, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%vBUFFER_SIZE: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "METRICS_LISTEN"); ok {
+		c.MetricsListen = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "HTTP_LISTEN"); ok {
+		c.HTTPListen = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "CONSOLE_LISTEN"); ok {
+		c.ConsoleListen = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "DRAIN_TIMEOUT"); ok {
+		c.DrainTimeout, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%vDRAIN_TIMEOUT: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TICKERS_SOURCE"); ok {
+		c.Tickers.Source = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TICKERS_FILES"); ok {
+		c.Tickers.Files = strings.Split(v, ",")
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TICKERS_FEED_BUFFER_SIZE"); ok {
+		c.Tickers.FeedBufferSize, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%vTICKERS_FEED_BUFFER_SIZE: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "REPLAY_USE_TODAY_DATE"); ok {
+		c.Tickers.Replay.UseTodayDate, err = strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("%vREPLAY_USE_TODAY_DATE: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "REPLAY_SPEED"); ok {
+		c.Tickers.Replay.Speed, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%vREPLAY_SPEED: %w", EnvPrefix, err)
+		}
+	}
+	for name, field := range map[string]*bool{
+		"TICKERS_CLEANING_ENABLED":           &c.Tickers.Cleaning.Enabled,
+		"TICKERS_CLEANING_DEDUP":             &c.Tickers.Cleaning.Dedup,
+		"TICKERS_CLEANING_DROP_ZERO_VOLUME":  &c.Tickers.Cleaning.DropZeroVolume,
+		"TICKERS_CLEANING_DROP_OUT_OF_ORDER": &c.Tickers.Cleaning.DropOutOfOrder,
+	} {
+		if v, ok := os.LookupEnv(EnvPrefix + name); ok {
+			*field, err = strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("%v%v: %w", EnvPrefix, name, err)
+			}
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TICKERS_CLEANING_OUTLIER_WINDOW"); ok {
+		c.Tickers.Cleaning.OutlierWindow, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%vTICKERS_CLEANING_OUTLIER_WINDOW: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TICKERS_CLEANING_OUTLIER_THRESHOLD"); ok {
+		c.Tickers.Cleaning.OutlierThreshold, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%vTICKERS_CLEANING_OUTLIER_THRESHOLD: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TICKERS_CLEANING_MAX_GAP"); ok {
+		c.Tickers.Cleaning.MaxGap, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%vTICKERS_CLEANING_MAX_GAP: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "CANDLES_INTERVALS"); ok {
+		c.Candles.Intervals = nil
+		for _, iv := range strings.Split(v, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(iv))
+			if err != nil {
+				return fmt.Errorf("%vCANDLES_INTERVALS: %w", EnvPrefix, err)
+			}
+			c.Candles.Intervals = append(c.Candles.Intervals, n)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "CANDLES_RETENTION"); ok {
+		c.Candles.Retention, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%vCANDLES_RETENTION: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "STATISTIC_MAX_RATE"); ok {
+		c.Statistic.MaxRate, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%vSTATISTIC_MAX_RATE: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "INSTRUMENTS"); ok {
+		c.Instruments = nil
+		err = json.Unmarshal([]byte(v), &c.Instruments)
+		if err != nil {
+			return fmt.Errorf("%vINSTRUMENTS: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "CONTRACTS"); ok {
+		c.Contracts = nil
+		err = json.Unmarshal([]byte(v), &c.Contracts)
+		if err != nil {
+			return fmt.Errorf("%vCONTRACTS: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "CLEARING_DIR"); ok {
+		c.Clearing.Dir = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "CLEARING_CLOSE_TIME"); ok {
+		c.Clearing.CloseTime = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "SIMULATION_LATENCY_MS"); ok {
+		c.Simulation.LatencyMs, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%vSIMULATION_LATENCY_MS: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "SIMULATION_QUEUE_AHEAD"); ok {
+		c.Simulation.QueueAhead, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%vSIMULATION_QUEUE_AHEAD: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "SIMULATION_SLIPPAGE_RATE"); ok {
+		c.Simulation.SlippageRate, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%vSIMULATION_SLIPPAGE_RATE: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "SIMULATION_PARTIAL_FILL_PROBABILITY"); ok {
+		c.Simulation.PartialFillProbability, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%vSIMULATION_PARTIAL_FILL_PROBABILITY: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "SIMULATION_SEED"); ok {
+		c.Simulation.Seed, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%vSIMULATION_SEED: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "REPLICATION_PRIMARY"); ok {
+		c.Replication.Primary = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "REPLICATION_LEASE"); ok {
+		c.Replication.Lease, err = strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%vREPLICATION_LEASE: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "REPLICATION_CONSUMER"); ok {
+		c.Replication.Consumer = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "JOURNAL_DIR"); ok {
+		c.Journal.Dir = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "CHAOS"); ok {
+		err = json.Unmarshal([]byte(v), &c.Chaos)
+		if err != nil {
+			return fmt.Errorf("%vCHAOS: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "ACL"); ok {
+		c.ACL = nil
+		err = json.Unmarshal([]byte(v), &c.ACL)
+		if err != nil {
+			return fmt.Errorf("%vACL: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TLS_CERT"); ok {
+		c.TLS.Cert = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TLS_KEY"); ok {
+		c.TLS.Key = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TLS_CLIENT_CA"); ok {
+		c.TLS.ClientCA = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TLS_BROKERS"); ok {
+		c.TLS.Brokers = nil
+		err = json.Unmarshal([]byte(v), &c.TLS.Brokers)
+		if err != nil {
+			return fmt.Errorf("%vTLS_BROKERS: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "TLS_ADMINS"); ok {
+		c.TLS.Admins = nil
+		err = json.Unmarshal([]byte(v), &c.TLS.Admins)
+		if err != nil {
+			return fmt.Errorf("%vTLS_ADMINS: %w", EnvPrefix, err)
+		}
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "LOG_LEVEL"); ok {
+		c.Log.Level = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "LOG_FORMAT"); ok {
+		c.Log.Format = v
+	}
+	if v, ok := os.LookupEnv(EnvPrefix + "LOG_FILE"); ok {
+		c.Log.File = v
+	}
+
+	return nil
+}
+
+// Validate reports all problems at once, one per line
+func (c *Config) Validate() error {
+	problems := make([]string, 0)
+	add := func(field string, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%v: %v", field, fmt.Sprintf(format, args...)))
+	}
+
+	// optional address is disabled when empty
+	checkListen := func(field string, addr string, optional bool) {
+		if optional && addr == "" {
+			return
+		}
+		_, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			add(field, "%v", err)
+		} else if p, err := strconv.Atoi(port); err != nil || p <= 0 || p > 65535 {
+			add(field, "invalid port %q", port)
+		}
+	}
+	checkListen("listen", c.Listen, false)
+	checkListen("metrics_listen", c.MetricsListen, true)
+	checkListen("http_listen", c.HTTPListen, true)
+	checkListen("console_listen", c.ConsoleListen, true)
+
+	if c.BufferSize <= 0 {
+		add("buffer_size", "must be positive, got %v", c.BufferSize)
+	}
+
+	if c.DrainTimeout <= 0 {
+		add("drain_timeout", "must be positive, got %v", c.DrainTimeout)
+	}
+
+	switch c.Tickers.Source {
+	case SourceInMem, SourceBinary:
+		if len(c.Tickers.Files) == 0 {
+			add("tickers.files", "at least one file is required for %q source", c.Tickers.Source)
+		}
+		for i, f := range c.Tickers.Files {
+			st, err := os.Stat(f)
+			if err != nil {
+				add(fmt.Sprintf("tickers.files[%v]", i), "%v", err)
+			} else if st.IsDir() {
+				add(fmt.Sprintf("tickers.files[%v]", i), "%v is a directory", f)
+			}
+		}
+	default:
+		add("tickers.source", "unknown source %q, supported: %v, %v", c.Tickers.Source, SourceInMem, SourceBinary)
+	}
+	if c.Tickers.FeedBufferSize <= 0 {
+		add("tickers.feed_buffer_size", "must be positive, got %v", c.Tickers.FeedBufferSize)
+	}
+	if c.Tickers.Replay.Speed <= 0 {
+		add("tickers.replay.speed", "must be positive, got %v", c.Tickers.Replay.Speed)
+	}
+	if c.Tickers.Cleaning.Enabled {
+		if c.Tickers.Source == SourceBinary {
+			add("tickers.cleaning", "binary files are cleaned on conversion by tickconv -clean")
+		}
+		if err := c.TickCleaning().Validate(); err != nil {
+			add("tickers.cleaning", "%v", err)
+		}
+	}
+
+	if len(c.Candles.Intervals) == 0 {
+		add("candles.intervals", "at least one interval is required")
+	}
+	seen := make(map[int]bool, len(c.Candles.Intervals))
+	for i, iv := range c.Candles.Intervals {
+		if iv <= 0 {
+			add(fmt.Sprintf("candles.intervals[%v]", i), "must be positive, got %v", iv)
+		} else if seen[iv] {
+			add(fmt.Sprintf("candles.intervals[%v]", i), "duplicate interval %v", iv)
+		}
+		seen[iv] = true
+	}
+	if c.Candles.Retention <= 0 {
+		add("candles.retention", "must be positive, got %v", c.Candles.Retention)
+	}
+
+	if c.Statistic.MaxRate < 0 {
+		add("statistic.max_rate", "must not be negative, got %v", c.Statistic.MaxRate)
+	}
+
+	for ticker, ins := range c.Instruments {
+		if _, err := matching.New(ins.Matching, ins.MinAllocation, ins.TopOrderPercent); err != nil {
+			add("instruments."+ticker, "%v", err)
+		}
+		if ins.MinAllocation < 0 {
+			add("instruments."+ticker+".min_allocation", "must not be negative, got %v", ins.MinAllocation)
+		}
+		if ins.FeePerLot < 0 {
+			add("instruments."+ticker+".fee_per_lot", "must not be negative, got %v", ins.FeePerLot)
+		}
+		if ins.FeeRate < 0 || ins.FeeRate >= 1 {
+			add("instruments."+ticker+".fee_rate", "must be in [0, 1), got %v", ins.FeeRate)
+		}
+	}
+
+	if _, err := c.ContractSchedule(); err != nil {
+		add("contracts", "%v", err)
+	}
+
+	if _, err := c.ClearingCloseAt(); err != nil {
+		add("clearing.close_time", "%v", err)
+	}
+
+	if err := c.SimulationModel().Validate(); err != nil {
+		add("simulation", "%v", err)
+	}
+
+	if c.Replication.Primary != "" {
+		if _, _, err := net.SplitHostPort(c.Replication.Primary); err != nil {
+			add("replication.primary", "%v", err)
+		}
+	}
+	// primary sends heartbeat every second
+	if c.Replication.Lease != 0 && c.Replication.Lease < 3 {
+		add("replication.lease", "must be 0 or at least 3 seconds, got %v", c.Replication.Lease)
+	}
+
+	for field, p := range map[string]float64{
+		"chaos.delay_probability":         c.Chaos.DelayProbability,
+		"chaos.drop_probability":          c.Chaos.DropProbability,
+		"chaos.duplicate_probability":     c.Chaos.DuplicateProbability,
+		"chaos.create_reject_probability": c.Chaos.CreateRejectProbability,
+		"chaos.abort_probability":         c.Chaos.AbortProbability,
+	} {
+		if p < 0 || p > 1 {
+			add(field, "must be in [0, 1], got %v", p)
+		}
+	}
+	if c.Chaos.MaxDelayMs < 0 {
+		add("chaos.max_delay_ms", "must not be negative, got %v", c.Chaos.MaxDelayMs)
+	}
+
+	for consumer, methods := range c.ACL {
+		for _, m := range methods {
+			if !strings.HasPrefix(m, "/") {
+				add("acl."+consumer, "method %q must be full grpc method name like /main.Exchange/Create", m)
+			}
+		}
+	}
+
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		add("tls", "cert and key must be set together")
+	}
+	if c.TLS.ClientCA != "" && c.TLS.Cert == "" {
+		add("tls.client_ca", "requires tls.cert and tls.key")
+	}
+	if len(c.TLS.Brokers) > 0 && c.TLS.ClientCA == "" {
+		add("tls.brokers", "requires tls.client_ca")
+	}
+	if len(c.TLS.Admins) > 0 && c.TLS.ClientCA == "" {
+		add("tls.admins", "requires tls.client_ca")
+	}
+	for field, f := range map[string]string{"tls.cert": c.TLS.Cert, "tls.key": c.TLS.Key, "tls.client_ca": c.TLS.ClientCA} {
+		if f == "" {
+			continue
+		}
+		if _, err := os.Stat(f); err != nil {
+			add(field, "%v", err)
+		}
+	}
+
+	var lvl zapcore.Level
+	if err := lvl.UnmarshalText([]byte(c.Log.Level)); err != nil {
+		add("log.level", "%v", err)
+	}
+	if c.Log.Format != "console" && c.Log.Format != "json" {
+		add("log.format", "must be console or json, got %q", c.Log.Format)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid exchange config:\n\t%v", strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
+
+// instruments with series of continuous tickers which are not configured themselves
+func (c *Config) instruments() map[string]InstrumentConfig {
+	res := make(map[string]InstrumentConfig, len(c.Instruments))
+	for underlying, series := range c.Contracts {
+		ins, ok := c.Instruments[underlying]
+		if !ok {
+			continue
+		}
+		for _, s := range series {
+			res[s.Ticker] = ins
+		}
+	}
+	for ticker, ins := range c.Instruments {
+		res[ticker] = ins
+	}
+	return res
+}
+
+// Matchers builds matching algorithm per configured instrument, config must be validated
+func (c *Config) Matchers() (map[string]matching.Matcher, error) {
+	instruments := c.instruments()
+	matchers := make(map[string]matching.Matcher, len(instruments))
+	for ticker, ins := range instruments {
+		m, err := matching.New(ins.Matching, ins.MinAllocation, ins.TopOrderPercent)
+		if err != nil {
+			return nil, fmt.Errorf("instrument %v: %w", ticker, err)
+		}
+		matchers[ticker] = m
+	}
+	return matchers, nil
+}
+
+// ClearingCloseAt is trading day close as time since local midnight
+func (c *Config) ClearingCloseAt() (time.Duration, error) {
+	t, err := time.Parse("15:04", c.Clearing.CloseTime)
+	if err != nil {
+		return 0, fmt.Errorf("HH:MM expected, got %q", c.Clearing.CloseTime)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ContractSchedule is futures series of contracts section, nil if there are none
+func (c *Config) ContractSchedule() (*contracts.Schedule, error) {
+	if len(c.Contracts) == 0 {
+		return nil, nil
+	}
+	series := make([]contracts.Series, 0, len(c.Contracts)*4)
+	for underlying, list := range c.Contracts {
+		for i, s := range list {
+			last, err := time.ParseInLocation("2006-01-02 15:04", s.LastTrading, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("%v[%v].last_trading: YYYY-MM-DD HH:MM expected, got %q", underlying, i, s.LastTrading)
+			}
+			series = append(series, contracts.Series{
+				Ticker:      s.Ticker,
+				Underlying:  underlying,
+				LastTrading: last,
+			})
+		}
+	}
+	return contracts.NewSchedule(series)
+}
+
+// TickCleaning is cleaning of text tickers files, nil if it is disabled
+func (c *Config) TickCleaning() *tickers.Cleaning {
+	if !c.Tickers.Cleaning.Enabled {
+		return nil
+	}
+	return &tickers.Cleaning{
+		Dedup:            c.Tickers.Cleaning.Dedup,
+		DropZeroVolume:   c.Tickers.Cleaning.DropZeroVolume,
+		DropOutOfOrder:   c.Tickers.Cleaning.DropOutOfOrder,
+		OutlierWindow:    c.Tickers.Cleaning.OutlierWindow,
+		OutlierThreshold: c.Tickers.Cleaning.OutlierThreshold,
+		MaxGap:           time.Duration(c.Tickers.Cleaning.MaxGap) * time.Second,
+	}
+}
+
+// SimulationModel is fill model of simulation section
+func (c *Config) SimulationModel() simulation.Model {
+	return simulation.Model{
+		Latency:                time.Duration(c.Simulation.LatencyMs) * time.Millisecond,
+		QueueAhead:             c.Simulation.QueueAhead,
+		SlippageRate:           c.Simulation.SlippageRate,
+		PartialFillProbability: c.Simulation.PartialFillProbability,
+		Seed:                   c.Simulation.Seed,
+	}
+}
+
+// ChaosSettings are faults injected from start
+func (c *Config) ChaosSettings() *exchange.ChaosSettings {
+	return &exchange.ChaosSettings{
+		DelayProbability:        c.Chaos.DelayProbability,
+		MaxDelayMs:              int32(c.Chaos.MaxDelayMs),
+		DropProbability:         c.Chaos.DropProbability,
+		DuplicateProbability:    c.Chaos.DuplicateProbability,
+		CreateRejectProbability: c.Chaos.CreateRejectProbability,
+		AbortProbability:        c.Chaos.AbortProbability,
+		Seed:                    c.Chaos.Seed,
+	}
+}
+
+// Fees of configured instruments for clearing
+func (c *Config) Fees() map[string]clearing.Fee {
+	instruments := c.instruments()
+	fees := make(map[string]clearing.Fee, len(instruments))
+	for ticker, ins := range instruments {
+		fees[ticker] = clearing.Fee{PerLot: ins.FeePerLot, Rate: ins.FeeRate}
+	}
+	return fees
+}
+
+// NewLogger builds logger according to log section, config must be validated
+func (c *Config) NewLogger() (*zap.Logger, error) {
+	var lvl zapcore.Level
+	err := lvl.UnmarshalText([]byte(c.Log.Level))
+	if err != nil {
+		return nil, err
+	}
+
+	encConfig := zap.NewProductionEncoderConfig()
+	encConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	var encoder zapcore.Encoder
+	if c.Log.Format == "json" {
+		encoder = zapcore.NewJSONEncoder(encConfig)
+	} else {
+		encoder = zapcore.NewConsoleEncoder(encConfig)
+	}
+
+	writer := zapcore.AddSync(os.Stdout)
+	if c.Log.File != "" {
+		logFile, err := os.OpenFile(c.Log.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("can't open log file: %w", err)
+		}
+		writer = zapcore.AddSync(logFile)
+	}
+
+	core := zapcore.NewCore(encoder, writer, lvl)
+	return zap.New(core, zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)), nil
+}
@@ -0,0 +1,188 @@
+package console
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+)
+
+//go:embed index.html
+var indexPage []byte
+
+// Exchange is state of the exchange shown by console
+type Exchange interface {
+	Brokers() []Broker
+	Books(depth int) []Book
+	RecentTrades() []*exchange.Trade
+}
+
+// Bars gives bars being built from tick feed
+type Bars interface {
+	Current(interval time.Duration) []*exchange.OHLCV
+}
+
+// Replay gives position of historical ticks replay
+type Replay interface {
+	Position() tickers.Position
+}
+
+type Broker struct {
+	ID             int64
+	PendingResults int // execution reports waiting for Results stream
+	Orders         int // resting orders
+}
+
+type Level struct {
+	Price  float32
+	Volume int32 // displayed volume
+}
+
+type Book struct {
+	Ticker string
+	Orders int
+	Bids   []Level // from the best one
+	Asks   []Level
+}
+
+type Snapshot struct {
+	Time    time.Time
+	Replay  *tickers.Position `json:",omitempty"`
+	Brokers []Broker
+	Books   []Book
+	Trades  []*exchange.Trade
+	Bars    []*exchange.OHLCV
+}
+
+// Console is read-only web page with exchange state, pushed to browser as Server-Sent Events
+type Console struct {
+	Exchange Exchange
+	Bars     Bars   // live bars are not shown if nil
+	Replay   Replay // replay position is not shown if nil
+
+	BarInterval time.Duration // 1s if not set
+	Depth       int           // price levels per side, 10 if not set
+	Refresh     time.Duration // 1s if not set
+	Clock       clock.Clock   // wall clock if not set
+}
+
+func (c *Console) Init() error {
+	if c.Exchange == nil {
+		return errors.New("console needs exchange to show")
+	}
+	if c.BarInterval <= 0 {
+		c.BarInterval = time.Second
+	}
+	if c.Depth <= 0 {
+		c.Depth = 10
+	}
+	if c.Refresh <= 0 {
+		c.Refresh = time.Second
+	}
+	if c.Clock == nil {
+		c.Clock = clock.Real{}
+	}
+	return nil
+}
+
+func (c *Console) Snapshot() *Snapshot {
+	s := &Snapshot{
+		Time:    c.Clock.Now(),
+		Brokers: c.Exchange.Brokers(),
+		Books:   c.Exchange.Books(c.Depth),
+		Trades:  c.Exchange.RecentTrades(),
+		Bars:    []*exchange.OHLCV{},
+	}
+	if c.Replay != nil {
+		pos := c.Replay.Position()
+		s.Replay = &pos
+	}
+	if c.Bars != nil {
+		s.Bars = c.Bars.Current(c.BarInterval)
+	}
+	return s
+}
+
+func (c *Console) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", c.index)
+	mux.HandleFunc("/snapshot", c.snapshot)
+	mux.HandleFunc("/events", c.events)
+	return mux
+}
+
+func (c *Console) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexPage)
+}
+
+func (c *Console) snapshot(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.Snapshot())
+}
+
+// events sends snapshot every Refresh until browser goes away
+func (c *Console) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ticker := c.Clock.NewTicker(c.Refresh)
+	defer ticker.Stop()
+	for {
+		data, err := json.Marshal(c.Snapshot())
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-ticker.C():
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Serve exposes console until ctx is done
+func (c *Console) Serve(ctx context.Context, addr string) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           c.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
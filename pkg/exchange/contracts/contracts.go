@@ -0,0 +1,137 @@
+package contracts
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Series is futures contract traded until its last trading time
+type Series struct {
+	Ticker      string    // like RTS-6.19
+	Underlying  string    // continuous ticker of feed, like SPFB.RTS
+	LastTrading time.Time // orders are cancelled and positions settled at this time
+}
+
+// Expired reports series is not traded at t
+func (s Series) Expired(t time.Time) bool {
+	return !t.Before(s.LastTrading)
+}
+
+var (
+	ErrorNoFront = errors.New("no open series of continuous ticker")
+	ErrorExpired = errors.New("series is expired")
+)
+
+// Schedule is futures series by their continuous tickers.
+// Ticker which is neither series nor continuous ticker is perpetual, nil schedule has only such tickers.
+type Schedule struct {
+	byUnderlying map[string][]Series // by LastTrading
+	byTicker     map[string]Series
+}
+
+func NewSchedule(series []Series) (*Schedule, error) {
+	s := &Schedule{
+		byUnderlying: make(map[string][]Series, 2),
+		byTicker:     make(map[string]Series, len(series)),
+	}
+	for _, v := range series {
+		if v.Ticker == "" || v.Underlying == "" {
+			return nil, fmt.Errorf("series ticker and continuous ticker are required, got %q of %q", v.Ticker, v.Underlying)
+		}
+		if _, ok := s.byTicker[v.Ticker]; ok {
+			return nil, fmt.Errorf("duplicate series %v", v.Ticker)
+		}
+		s.byTicker[v.Ticker] = v
+		s.byUnderlying[v.Underlying] = append(s.byUnderlying[v.Underlying], v)
+	}
+	for underlying, list := range s.byUnderlying {
+		if _, ok := s.byTicker[underlying]; ok {
+			return nil, fmt.Errorf("continuous ticker %v is also series", underlying)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].LastTrading.Before(list[j].LastTrading)
+		})
+		for i := 1; i < len(list); i++ {
+			if list[i].LastTrading.Equal(list[i-1].LastTrading) {
+				return nil, fmt.Errorf("series %v and %v of %v expire at the same time", list[i-1].Ticker, list[i].Ticker, underlying)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Front is the nearest series of continuous ticker not expired at t
+func (s *Schedule) Front(underlying string, t time.Time) (Series, bool) {
+	if s == nil {
+		return Series{}, false
+	}
+	for _, v := range s.byUnderlying[underlying] {
+		if !v.Expired(t) {
+			return v, true
+		}
+	}
+	return Series{}, false
+}
+
+// Get returns series by its ticker
+func (s *Schedule) Get(ticker string) (Series, bool) {
+	if s == nil {
+		return Series{}, false
+	}
+	v, ok := s.byTicker[ticker]
+	return v, ok
+}
+
+// IsContinuous reports ticker has series
+func (s *Schedule) IsContinuous(ticker string) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.byUnderlying[ticker]
+	return ok
+}
+
+// Resolve gives ticker orders of ticker go to at t: front series for continuous ticker,
+// series itself while it is traded and perpetual ticker as is
+func (s *Schedule) Resolve(ticker string, t time.Time) (string, error) {
+	if v, ok := s.Get(ticker); ok {
+		if v.Expired(t) {
+			return "", fmt.Errorf("%w: %v at %v", ErrorExpired, ticker, v.LastTrading.Format("2006-01-02 15:04"))
+		}
+		return ticker, nil
+	}
+	if s.IsContinuous(ticker) {
+		front, ok := s.Front(ticker, t)
+		if !ok {
+			return "", fmt.Errorf("%w %v", ErrorNoFront, ticker)
+		}
+		return front.Ticker, nil
+	}
+	return ticker, nil
+}
+
+// Route gives ticker matching tick of feed at t: front series for continuous ticker,
+// ticker as is when it has no open series or is not continuous
+func (s *Schedule) Route(ticker string, t time.Time) string {
+	if front, ok := s.Front(ticker, t); ok {
+		return front.Ticker
+	}
+	return ticker
+}
+
+// All returns series by continuous ticker, then by last trading time
+func (s *Schedule) All() []Series {
+	if s == nil {
+		return nil
+	}
+	res := make([]Series, 0, len(s.byTicker))
+	for _, list := range s.byUnderlying {
+		res = append(res, list...)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Underlying < res[j].Underlying
+	})
+	return res
+}
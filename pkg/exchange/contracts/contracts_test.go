@@ -0,0 +1,64 @@
+package contracts
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduleRollover(t *testing.T) {
+	june := time.Date(2023, 6, 15, 18, 50, 0, 0, time.Local)
+	sept := time.Date(2023, 9, 21, 18, 50, 0, 0, time.Local)
+	s, err := NewSchedule([]Series{
+		{Ticker: "RTS-9.23", Underlying: "SPFB.RTS", LastTrading: sept},
+		{Ticker: "RTS-6.23", Underlying: "SPFB.RTS", LastTrading: june},
+	})
+	if err != nil {
+		t.Fatalf("cant build schedule: %v", err)
+	}
+
+	for _, tc := range []struct {
+		ticker string
+		at     time.Time
+		want   string
+		err    error
+	}{
+		{"SPFB.RTS", june.Add(-time.Minute), "RTS-6.23", nil},
+		{"SPFB.RTS", june, "RTS-9.23", nil},
+		{"RTS-9.23", june.Add(-time.Hour), "RTS-9.23", nil},
+		{"RTS-6.23", june, "", ErrorExpired},
+		{"SPFB.RTS", sept, "", ErrorNoFront},
+		{"SPFB.Si", sept, "SPFB.Si", nil},
+	} {
+		have, err := s.Resolve(tc.ticker, tc.at)
+		if !errors.Is(err, tc.err) || have != tc.want {
+			t.Fatalf("%v at %v: have %q, %v, want %q, %v", tc.ticker, tc.at, have, err, tc.want, tc.err)
+		}
+	}
+
+	if have := s.Route("SPFB.RTS", sept); have != "SPFB.RTS" {
+		t.Fatalf("ticks after the last series must stay on continuous ticker, have %v", have)
+	}
+	if all := s.All(); len(all) != 2 || all[0].Ticker != "RTS-6.23" {
+		t.Fatalf("series must go by expiry: %v", all)
+	}
+
+	var none *Schedule
+	if have, err := none.Resolve("SPFB.RTS", june); err != nil || have != "SPFB.RTS" {
+		t.Fatalf("nil schedule must keep ticker: %v, %v", have, err)
+	}
+}
+
+func TestScheduleInvalid(t *testing.T) {
+	at := time.Date(2023, 6, 15, 18, 50, 0, 0, time.Local)
+	for name, series := range map[string][]Series{
+		"no ticker":      {{Underlying: "SPFB.RTS", LastTrading: at}},
+		"duplicate":      {{Ticker: "RTS-6.23", Underlying: "SPFB.RTS", LastTrading: at}, {Ticker: "RTS-6.23", Underlying: "SPFB.RTS", LastTrading: at.Add(time.Hour)}},
+		"same expiry":    {{Ticker: "RTS-6.23", Underlying: "SPFB.RTS", LastTrading: at}, {Ticker: "RTS-9.23", Underlying: "SPFB.RTS", LastTrading: at}},
+		"series of self": {{Ticker: "RTS-6.23", Underlying: "SPFB.RTS", LastTrading: at}, {Ticker: "SPFB.RTS", Underlying: "RTS", LastTrading: at}},
+	} {
+		if _, err := NewSchedule(series); err == nil {
+			t.Fatalf("%v: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package fix
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	BeginString = "FIX.4.4"
+	SOH         = '\x01'
+
+	// SendingTime/TransactTime format, UTC with milliseconds
+	TimestampFormat = "20060102-15:04:05.000"
+)
+
+// tags used by the gateway
+const (
+	TagAccount          = 1
+	TagAvgPx            = 6
+	TagBeginSeqNo       = 7
+	TagBeginString      = 8
+	TagBodyLength       = 9
+	TagCheckSum         = 10
+	TagClOrdID          = 11
+	TagCumQty           = 14
+	TagEndSeqNo         = 16
+	TagExecID           = 17
+	TagLastPx           = 31
+	TagLastQty          = 32
+	TagMsgSeqNum        = 34
+	TagMsgType          = 35
+	TagNewSeqNo         = 36
+	TagOrderID          = 37
+	TagOrderQty         = 38
+	TagOrdStatus        = 39
+	TagOrdType          = 40
+	TagOrigClOrdID      = 41
+	TagPossDupFlag      = 43
+	TagPrice            = 44
+	TagRefSeqNum        = 45
+	TagSenderCompID     = 49
+	TagSendingTime      = 52
+	TagSide             = 54
+	TagSymbol           = 55
+	TagTargetCompID     = 56
+	TagText             = 58
+	TagTransactTime     = 60
+	TagEncryptMethod    = 98
+	TagCxlRejReason     = 102
+	TagOrdRejReason     = 103
+	TagHeartBtInt       = 108
+	TagMaxFloor         = 111
+	TagTestReqID        = 112
+	TagOrigSendingTime  = 122
+	TagGapFillFlag      = 123
+	TagResetSeqNumFlag  = 141
+	TagExecType         = 150
+	TagLeavesQty        = 151
+	TagCxlRejResponseTo = 434
+)
+
+const (
+	MsgTypeHeartbeat                 = "0"
+	MsgTypeTestRequest               = "1"
+	MsgTypeResendRequest             = "2"
+	MsgTypeReject                    = "3"
+	MsgTypeSequenceReset             = "4"
+	MsgTypeLogout                    = "5"
+	MsgTypeExecutionReport           = "8"
+	MsgTypeOrderCancelReject         = "9"
+	MsgTypeLogon                     = "A"
+	MsgTypeNewOrderSingle            = "D"
+	MsgTypeOrderCancelRequest        = "F"
+	MsgTypeOrderCancelReplaceRequest = "G"
+)
+
+const (
+	SideBuy  = "1"
+	SideSell = "2"
+
+	OrdTypeLimit = "2"
+
+	ExecTypeNew      = "0"
+	ExecTypeCanceled = "4"
+	ExecTypeReplaced = "5"
+	ExecTypeRejected = "8"
+	ExecTypeExpired  = "C"
+	ExecTypeTrade    = "F"
+
+	OrdStatusNew             = "0"
+	OrdStatusPartiallyFilled = "1"
+	OrdStatusFilled          = "2"
+	OrdStatusCanceled        = "4"
+	OrdStatusRejected        = "8"
+	OrdStatusExpired         = "C"
+
+	CxlRejResponseToCancel  = "1"
+	CxlRejResponseToReplace = "2"
+
+	CxlRejReasonTooLate      = "0"
+	CxlRejReasonUnknownOrder = "1"
+)
+
+// admin messages are never resent, they are replaced with SequenceReset-GapFill
+var adminMsgTypes = map[string]struct{}{
+	MsgTypeHeartbeat:     {},
+	MsgTypeTestRequest:   {},
+	MsgTypeResendRequest: {},
+	MsgTypeReject:        {},
+	MsgTypeSequenceReset: {},
+	MsgTypeLogout:        {},
+	MsgTypeLogon:         {},
+}
+
+func IsAdmin(msgType string) bool {
+	_, ok := adminMsgTypes[msgType]
+	return ok
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(TimestampFormat)
+}
+
+var (
+	ErrorGarbledMessage         = errors.New("garbled fix message")
+	ErrorBadChecksum            = errors.New("fix message checksum mismatch")
+	ErrorUnsupportedBeginString = errors.New("unsupported fix version, FIX.4.4 expected")
+	ErrorTagNotFound            = errors.New("required tag missing")
+	ErrorNotLoggedOn            = errors.New("fix session is not logged on")
+	ErrorLogonRejected          = errors.New("fix logon rejected")
+	ErrorHeartbeatTimeout       = errors.New("fix counterparty stopped responding")
+	ErrorSeqNumTooLow           = errors.New("fix MsgSeqNum too low")
+)
@@ -0,0 +1,520 @@
+package fix
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Gateway is FIX 4.4 acceptor which acts as a single broker towards the exchange.
+// Orders from all FIX sessions are placed with BrokerID, fills from Results are routed back
+// to the session which placed the order.
+type Gateway struct {
+	ListenAddr   string
+	SenderCompID string
+	BrokerID     int32
+
+	// allowed counterparty SenderCompIDs and exchange ClientID used for their orders
+	Clients map[string]int32
+
+	Exchange exchange.ExchangeClient
+	Logger   *zap.SugaredLogger
+
+	sessionsLock *sync.Mutex
+	sessions     map[string]*Session
+
+	ordersLock *sync.Mutex
+	orders     map[int64]*fixOrder  // by exchange DealID
+	clOrdIDs   map[string]*fixOrder // by session + ClOrdID
+
+	// exchange calls are made without ordersLock, fills of unknown deal wait for
+	// orders being placed so they can't overtake registration
+	placing int
+	placed  *sync.Cond
+
+	execID int64
+}
+
+type fixOrder struct {
+	compID      string
+	clOrdID     string
+	origClOrdID string
+	dealID      int64
+	account     string
+	symbol      string
+	side        string
+	qty         int32
+	maxFloor    int32 // displayed quantity of iceberg, 0 shows whole order
+	price       float64
+	cumQty      int32
+	avgPx       float64
+	status      string
+}
+
+func (o *fixOrder) leavesQty() int32 {
+	if o.status == OrdStatusCanceled || o.status == OrdStatusRejected || o.status == OrdStatusFilled || o.status == OrdStatusExpired {
+		return 0
+	}
+	return o.qty - o.cumQty
+}
+
+func (o *fixOrder) active() bool {
+	return o.status == OrdStatusNew || o.status == OrdStatusPartiallyFilled
+}
+
+// exchange expects positive price for buy and negative for sell
+func (o *fixOrder) exchangePrice() float32 {
+	if o.side == SideSell {
+		return -float32(o.price)
+	}
+	return float32(o.price)
+}
+
+func (g *Gateway) Init() {
+	if g.Logger == nil {
+		g.Logger = zap.S()
+	}
+	g.sessionsLock = &sync.Mutex{}
+	g.sessions = make(map[string]*Session, len(g.Clients))
+	g.ordersLock = &sync.Mutex{}
+	g.placed = sync.NewCond(g.ordersLock)
+	g.orders = make(map[int64]*fixOrder, 100)
+	g.clOrdIDs = make(map[string]*fixOrder, 100)
+}
+
+// Start accepts FIX connections until context is cancelled
+func (g *Gateway) Start(ctx context.Context) error {
+	if g.sessions == nil {
+		g.Init()
+	}
+
+	lis, err := net.Listen("tcp", g.ListenAddr)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		lis.Close()
+
+		g.sessionsLock.Lock()
+		for _, s := range g.sessions {
+			s.Logout("exchange gateway shutdown")
+			s.detach()
+		}
+		g.sessionsLock.Unlock()
+	}()
+
+	go g.listenResults(ctx)
+
+	g.Logger.Infow("Starting FIX gateway", "listen", g.ListenAddr)
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go g.handleConn(conn)
+	}
+}
+
+func (g *Gateway) handleConn(conn net.Conn) {
+	r := bufio.NewReader(conn)
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	logon, err := ReadMessage(r)
+	conn.SetReadDeadline(time.Time{})
+	if err != nil || logon.MsgType() != MsgTypeLogon {
+		g.Logger.Warnw("FIX connection dropped: logon expected", "remote", conn.RemoteAddr())
+		conn.Close()
+		return
+	}
+
+	compID := logon.GetString(TagSenderCompID)
+	_, allowed := g.Clients[compID]
+	if !allowed || logon.GetString(TagTargetCompID) != g.SenderCompID {
+		g.Logger.Warnw("FIX logon rejected", "remote", conn.RemoteAddr(), "compId", compID)
+		conn.Close()
+		return
+	}
+
+	s := g.getSession(compID)
+	if !s.attach(conn) {
+		g.Logger.Warnw("FIX session is already connected", "compId", compID, "remote", conn.RemoteAddr())
+		conn.Close()
+		return
+	}
+
+	g.Logger.Infow("FIX session connected", "compId", compID, "remote", conn.RemoteAddr())
+	err = s.serve(r, logon)
+	if err != nil {
+		g.Logger.Warnw("FIX session disconnected", "compId", compID, "error", err)
+		return
+	}
+	g.Logger.Infow("FIX session logged out", "compId", compID)
+}
+
+func (g *Gateway) getSession(compID string) *Session {
+	g.sessionsLock.Lock()
+	defer g.sessionsLock.Unlock()
+
+	s, ok := g.sessions[compID]
+	if !ok {
+		s = NewSession(g.SenderCompID, compID, 30*time.Second, g.onMessage)
+		s.Logger = g.Logger
+		g.sessions[compID] = s
+	}
+	return s
+}
+
+func (g *Gateway) onMessage(s *Session, m *Message) {
+	switch m.MsgType() {
+	case MsgTypeNewOrderSingle:
+		g.newOrderSingle(s, m)
+	case MsgTypeOrderCancelRequest:
+		g.cancelOrder(s, m)
+	case MsgTypeOrderCancelReplaceRequest:
+		g.replaceOrder(s, m)
+	default:
+		s.reject(m, "unsupported MsgType")
+	}
+}
+
+func (g *Gateway) newOrderSingle(s *Session, m *Message) {
+	o := &fixOrder{
+		compID:  s.TargetCompID,
+		clOrdID: m.GetString(TagClOrdID),
+		account: m.GetString(TagAccount),
+		symbol:  m.GetString(TagSymbol),
+		side:    m.GetString(TagSide),
+		status:  OrdStatusNew,
+	}
+	qty, _ := m.GetInt(TagOrderQty)
+	o.qty = int32(qty)
+	o.price, _ = m.GetFloat(TagPrice)
+	maxFloor, _ := m.GetInt(TagMaxFloor)
+	o.maxFloor = int32(maxFloor)
+
+	g.ordersLock.Lock()
+	defer g.ordersLock.Unlock()
+
+	var reason string
+	switch {
+	case o.clOrdID == "":
+		reason = "ClOrdID is required"
+	case g.clOrdIDs[g.clOrdKey(o.compID, o.clOrdID)] != nil:
+		reason = "duplicate ClOrdID"
+	case o.symbol == "":
+		reason = "Symbol is required"
+	case o.side != SideBuy && o.side != SideSell:
+		reason = "only buy and sell sides are supported"
+	case m.GetString(TagOrdType) != OrdTypeLimit:
+		reason = "only limit orders are supported"
+	case o.qty <= 0:
+		reason = "OrderQty must be positive"
+	case o.price <= 0:
+		reason = "Price must be positive"
+	case o.maxFloor < 0:
+		reason = "MaxFloor must not be negative"
+	}
+	if reason != "" {
+		g.rejectOrder(s, o, reason)
+		return
+	}
+
+	// ClOrdID is taken while order is placed
+	g.clOrdIDs[g.clOrdKey(o.compID, o.clOrdID)] = o
+	dealid, err := g.create(o, o.qty)
+	if err != nil {
+		delete(g.clOrdIDs, g.clOrdKey(o.compID, o.clOrdID))
+		g.rejectOrder(s, o, err.Error())
+		return
+	}
+
+	o.dealID = dealid.ID
+	g.orders[o.dealID] = o
+
+	s.Send(g.executionReport(o, ExecTypeNew))
+}
+
+// create places order on exchange, ordersLock must be held and is released for the call.
+// New DealID must be registered before ordersLock is released again.
+func (g *Gateway) create(o *fixOrder, volume int32) (*exchange.DealID, error) {
+	deal := &exchange.Deal{
+		BrokerID:      g.BrokerID,
+		ClientID:      g.Clients[o.compID],
+		Ticker:        o.symbol,
+		Volume:        volume,
+		Time:          int32(time.Now().Unix()),
+		Price:         o.exchangePrice(),
+		DisplayVolume: o.maxFloor,
+	}
+
+	g.placing++
+	g.ordersLock.Unlock()
+	dealid, err := g.Exchange.Create(context.Background(), deal)
+	g.ordersLock.Lock()
+	g.placing--
+	g.placed.Broadcast()
+
+	return dealid, err
+}
+
+// cancel removes order from exchange, ordersLock must be held and is released for the call.
+// Fills of DealID queued by exchange before the cancel still come after it succeeds,
+// so DealID stays mapped to the order.
+func (g *Gateway) cancel(dealID int64) error {
+	g.ordersLock.Unlock()
+	_, err := g.Exchange.Cancel(context.Background(), &exchange.DealID{
+		ID:       dealID,
+		BrokerID: int64(g.BrokerID),
+	})
+	g.ordersLock.Lock()
+	return err
+}
+
+func (g *Gateway) cancelOrder(s *Session, m *Message) {
+	clOrdID := m.GetString(TagClOrdID)
+	origClOrdID := m.GetString(TagOrigClOrdID)
+
+	g.ordersLock.Lock()
+	defer g.ordersLock.Unlock()
+
+	o := g.clOrdIDs[g.clOrdKey(s.TargetCompID, origClOrdID)]
+	if o == nil || !o.active() {
+		g.cancelReject(s, o, clOrdID, origClOrdID, CxlRejResponseToCancel, CxlRejReasonUnknownOrder, "unknown order")
+		return
+	}
+
+	err := g.cancel(o.dealID)
+	if err != nil {
+		g.cancelReject(s, o, clOrdID, origClOrdID, CxlRejResponseToCancel, CxlRejReasonTooLate, err.Error())
+		return
+	}
+
+	o.origClOrdID = o.clOrdID
+	o.clOrdID = clOrdID
+	o.status = OrdStatusCanceled
+	g.clOrdIDs[g.clOrdKey(o.compID, o.clOrdID)] = o
+
+	s.Send(g.executionReport(o, ExecTypeCanceled))
+}
+
+// exchange has no amend, so replace is cancel of remaining volume and create of new order
+func (g *Gateway) replaceOrder(s *Session, m *Message) {
+	clOrdID := m.GetString(TagClOrdID)
+	origClOrdID := m.GetString(TagOrigClOrdID)
+
+	g.ordersLock.Lock()
+	defer g.ordersLock.Unlock()
+
+	o := g.clOrdIDs[g.clOrdKey(s.TargetCompID, origClOrdID)]
+	if o == nil || !o.active() {
+		g.cancelReject(s, o, clOrdID, origClOrdID, CxlRejResponseToReplace, CxlRejReasonUnknownOrder, "unknown order")
+		return
+	}
+
+	qty, err := m.GetInt(TagOrderQty)
+	if err != nil {
+		qty = int64(o.qty)
+	}
+	price, err := m.GetFloat(TagPrice)
+	if err != nil {
+		price = o.price
+	}
+	if int32(qty) <= o.cumQty || price <= 0 || m.GetString(TagSide) != "" && m.GetString(TagSide) != o.side {
+		g.cancelReject(s, o, clOrdID, origClOrdID, CxlRejResponseToReplace, CxlRejReasonTooLate, "invalid replace parameters")
+		return
+	}
+
+	err = g.cancel(o.dealID)
+	if err != nil {
+		g.cancelReject(s, o, clOrdID, origClOrdID, CxlRejResponseToReplace, CxlRejReasonTooLate, err.Error())
+		return
+	}
+
+	o.qty = int32(qty)
+	o.price = price
+	if o.leavesQty() <= 0 {
+		// filled up to new quantity while cancel was in flight
+		o.status = OrdStatusFilled
+	}
+	var dealid *exchange.DealID
+	if o.active() {
+		dealid, err = g.create(o, o.leavesQty())
+	}
+	if err != nil {
+		// original order is already gone from the book
+		o.origClOrdID = o.clOrdID
+		o.clOrdID = clOrdID
+		o.status = OrdStatusCanceled
+		g.clOrdIDs[g.clOrdKey(o.compID, o.clOrdID)] = o
+		s.Send(g.executionReport(o, ExecTypeCanceled).Set(TagText, err.Error()))
+		return
+	}
+
+	if dealid != nil {
+		o.dealID = dealid.ID
+		g.orders[o.dealID] = o
+	}
+	o.origClOrdID = o.clOrdID
+	o.clOrdID = clOrdID
+	g.clOrdIDs[g.clOrdKey(o.compID, o.clOrdID)] = o
+
+	s.Send(g.executionReport(o, ExecTypeReplaced))
+}
+
+// listenResults resumes after the last report received, so reports dropped by exchange are not lost
+func (g *Gateway) listenResults(ctx context.Context) {
+	var lastSeq int64
+	for ctx.Err() == nil {
+		results, err := g.Exchange.Results(ctx, &exchange.BrokerID{ID: int64(g.BrokerID), LastExecSeq: lastSeq})
+		if err != nil {
+			g.Logger.Warnw("FIX gateway can't subscribe to exchange results", "error", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for {
+			deal, err := results.Recv()
+			if status.Code(err) == codes.FailedPrecondition {
+				// exchange promoted from standby has not got the last reports, new ones are taken
+				g.Logger.Warnw("FIX gateway results are ahead of exchange journal, resyncing", "lastExecSeq", lastSeq, "error", err)
+				lastSeq = 0
+				break
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					g.Logger.Warnw("FIX gateway results stream failed", "error", err)
+					time.Sleep(time.Second)
+				}
+				break
+			}
+			if deal.ExecSeq != 0 && deal.ExecSeq <= lastSeq {
+				continue
+			}
+			g.fill(deal)
+			if deal.ExecSeq > lastSeq {
+				lastSeq = deal.ExecSeq
+			}
+		}
+	}
+}
+
+func (g *Gateway) fill(deal *exchange.Deal) {
+	// settlement closes client position, not an order
+	if deal.Status == exchange.OrderStatus_SETTLED {
+		return
+	}
+
+	g.ordersLock.Lock()
+	defer g.ordersLock.Unlock()
+
+	o, ok := g.orders[deal.ID]
+	for !ok && g.placing > 0 {
+		g.placed.Wait()
+		o, ok = g.orders[deal.ID]
+	}
+	if !ok {
+		g.Logger.Warnw("FIX gateway received fill for unknown deal", "deal", deal)
+		return
+	}
+
+	if deal.Status == exchange.OrderStatus_EXPIRED {
+		o.status = OrdStatusExpired
+		delete(g.orders, deal.ID)
+		g.getSession(o.compID).Send(g.executionReport(o, ExecTypeExpired))
+		return
+	}
+
+	lastQty := deal.Volume
+	lastPx := float64(deal.Price)
+	if lastPx < 0 {
+		lastPx = -lastPx
+	}
+
+	o.avgPx = (o.avgPx*float64(o.cumQty) + lastPx*float64(lastQty)) / float64(o.cumQty+lastQty)
+	o.cumQty += lastQty
+	if o.active() {
+		if o.cumQty >= o.qty {
+			o.status = OrdStatusFilled
+		} else {
+			o.status = OrdStatusPartiallyFilled
+		}
+	}
+	// nothing more comes for DealID, fills of replaced ones go to the same order until they are done
+	if deal.Status == exchange.OrderStatus_FILLED {
+		delete(g.orders, deal.ID)
+	}
+
+	er := g.executionReport(o, ExecTypeTrade)
+	er.SetInt(TagLastQty, int64(lastQty))
+	er.SetFloat(TagLastPx, lastPx)
+
+	g.getSession(o.compID).Send(er)
+}
+
+func (g *Gateway) executionReport(o *fixOrder, execType string) *Message {
+	er := NewMessage(MsgTypeExecutionReport)
+	er.SetInt(TagOrderID, o.dealID)
+	er.Set(TagClOrdID, o.clOrdID)
+	if o.origClOrdID != "" && execType != ExecTypeNew && execType != ExecTypeTrade {
+		er.Set(TagOrigClOrdID, o.origClOrdID)
+	}
+	er.SetInt(TagExecID, atomic.AddInt64(&g.execID, 1))
+	er.Set(TagExecType, execType)
+	er.Set(TagOrdStatus, o.status)
+	if o.account != "" {
+		er.Set(TagAccount, o.account)
+	}
+	er.Set(TagSymbol, o.symbol)
+	er.Set(TagSide, o.side)
+	er.SetInt(TagOrderQty, int64(o.qty))
+	er.Set(TagOrdType, OrdTypeLimit)
+	er.SetFloat(TagPrice, o.price)
+	er.SetInt(TagLeavesQty, int64(o.leavesQty()))
+	er.SetInt(TagCumQty, int64(o.cumQty))
+	er.SetFloat(TagAvgPx, o.avgPx)
+	er.Set(TagTransactTime, timestamp(time.Now()))
+	return er
+}
+
+func (g *Gateway) rejectOrder(s *Session, o *fixOrder, reason string) {
+	o.status = OrdStatusRejected
+	o.dealID = 0
+	er := g.executionReport(o, ExecTypeRejected)
+	er.Set(TagOrderID, "NONE")
+	er.Set(TagText, reason)
+	s.Send(er)
+}
+
+func (g *Gateway) cancelReject(s *Session, o *fixOrder, clOrdID string, origClOrdID string, responseTo string, reason string, text string) {
+	r := NewMessage(MsgTypeOrderCancelReject)
+	r.Set(TagOrderID, "NONE")
+	r.Set(TagOrdStatus, OrdStatusRejected)
+	if o != nil {
+		r.SetInt(TagOrderID, o.dealID)
+		r.Set(TagOrdStatus, o.status)
+	}
+	r.Set(TagClOrdID, clOrdID)
+	r.Set(TagOrigClOrdID, origClOrdID)
+	r.Set(TagCxlRejResponseTo, responseTo)
+	r.Set(TagCxlRejReason, reason)
+	r.Set(TagText, text)
+	s.Send(r)
+}
+
+func (g *Gateway) clOrdKey(compID string, clOrdID string) string {
+	return compID + "/" + clOrdID
+}
@@ -0,0 +1,357 @@
+package fix
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/server"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+const (
+	exchangeAddr string = "127.0.0.1:8083"
+	gatewayAddr  string = "127.0.0.1:9878"
+)
+
+type TickersSourceTest struct {
+	chLock *sync.RWMutex
+	ch     []chan tickers.Tick
+}
+
+func (t *TickersSourceTest) GetFeedChannel() <-chan tickers.Tick {
+	c := make(chan tickers.Tick, 100)
+
+	t.chLock.Lock()
+	t.ch = append(t.ch, c)
+	t.chLock.Unlock()
+
+	return c
+}
+
+func (t *TickersSourceTest) ReleaseFeedChannel(c <-chan tickers.Tick) {
+	t.chLock.Lock()
+	defer t.chLock.Unlock()
+
+	for i, v := range t.ch {
+		if v == c {
+			t.ch = append(t.ch[:i], t.ch[i+1:]...)
+			close(v)
+			return
+		}
+	}
+}
+
+func (t *TickersSourceTest) CloseFeed() {
+	t.chLock.Lock()
+	defer t.chLock.Unlock()
+
+	for _, c := range t.ch {
+		close(c)
+	}
+	t.ch = nil
+}
+
+func (t *TickersSourceTest) Run(tickers []tickers.Tick) {
+	t.chLock.RLock()
+	defer t.chLock.RUnlock()
+
+	for _, v := range tickers {
+		for _, c := range t.ch {
+			c <- v
+		}
+	}
+}
+
+func wait(amout int) {
+	time.Sleep(time.Duration(amout) * 10 * time.Millisecond)
+}
+
+func expectMessage(t *testing.T, i *Initiator, checks map[int]string) *Message {
+	select {
+	case m := <-i.Messages:
+		for tag, want := range checks {
+			if have := m.GetString(tag); have != want {
+				t.Fatalf("tag %v dont match\nhave %v\nwant %v\nmessage %v", tag, have, want, m)
+			}
+		}
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no message received, expected %v", checks)
+	}
+	return nil
+}
+
+func newOrder(clOrdID string, side string, qty int64, price float64) *Message {
+	m := NewMessage(MsgTypeNewOrderSingle)
+	m.Set(TagClOrdID, clOrdID)
+	m.Set(TagSymbol, "SPFB.RTS")
+	m.Set(TagSide, side)
+	m.Set(TagOrdType, OrdTypeLimit)
+	m.SetInt(TagOrderQty, qty)
+	m.SetFloat(TagPrice, price)
+	m.Set(TagTransactTime, timestamp(time.Now()))
+	return m
+}
+
+func TestGateway(t *testing.T) {
+	ts := &TickersSourceTest{
+		chLock: &sync.RWMutex{},
+		ch:     make([]chan tickers.Tick, 0, 2),
+	}
+
+	ctx, finish := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- server.Start(ctx, exchangeAddr, ``, ts)
+	}()
+	wait(10)
+
+	conn, err := grpc.Dial(exchangeAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("cant connect to grpc: %v", err)
+	}
+
+	g := &Gateway{
+		ListenAddr:   gatewayAddr,
+		SenderCompID: "EXCHANGE",
+		BrokerID:     777,
+		Clients:      map[string]int32{"CLIENT1": 11},
+		Exchange:     exchange.NewExchangeClient(conn),
+	}
+	gatewayStopped := make(chan error, 1)
+	go func() {
+		gatewayStopped <- g.Start(ctx)
+	}()
+	wait(10)
+
+	i := &Initiator{
+		Addr:         gatewayAddr,
+		SenderCompID: "CLIENT1",
+		TargetCompID: "EXCHANGE",
+		HeartBtInt:   time.Second,
+	}
+	err = i.Start()
+	if err != nil {
+		t.Fatalf("cant logon: %v", err)
+	}
+
+	// unsupported order type is rejected without reaching exchange
+	market := newOrder("0", SideBuy, 1, 100)
+	market.Set(TagOrdType, "1")
+	i.Send(market)
+	expectMessage(t, i, map[int]string{
+		TagMsgType:   MsgTypeExecutionReport,
+		TagClOrdID:   "0",
+		TagExecType:  ExecTypeRejected,
+		TagOrdStatus: OrdStatusRejected,
+	})
+
+	// new order, partial fill, replace, fill, cancel of filled order
+	i.Send(newOrder("1", SideBuy, 5, 100))
+	accepted := expectMessage(t, i, map[int]string{
+		TagMsgType:   MsgTypeExecutionReport,
+		TagClOrdID:   "1",
+		TagExecType:  ExecTypeNew,
+		TagOrdStatus: OrdStatusNew,
+		TagLeavesQty: "5",
+	})
+	orderID := accepted.GetString(TagOrderID)
+
+	ts.Run([]tickers.Tick{{Ticker: "SPFB.RTS", Timestamp: time.Now(), Last: 90, Vol: 2}})
+	expectMessage(t, i, map[int]string{
+		TagOrderID:   orderID,
+		TagExecType:  ExecTypeTrade,
+		TagOrdStatus: OrdStatusPartiallyFilled,
+		TagLastQty:   "2",
+		TagLastPx:    "90",
+		TagCumQty:    "2",
+		TagLeavesQty: "3",
+	})
+
+	replace := NewMessage(MsgTypeOrderCancelReplaceRequest)
+	replace.Set(TagClOrdID, "2")
+	replace.Set(TagOrigClOrdID, "1")
+	replace.Set(TagSymbol, "SPFB.RTS")
+	replace.Set(TagSide, SideBuy)
+	replace.Set(TagOrdType, OrdTypeLimit)
+	replace.SetInt(TagOrderQty, 4)
+	replace.SetFloat(TagPrice, 80)
+	i.Send(replace)
+	expectMessage(t, i, map[int]string{
+		TagClOrdID:     "2",
+		TagOrigClOrdID: "1",
+		TagExecType:    ExecTypeReplaced,
+		TagOrdStatus:   OrdStatusPartiallyFilled,
+		TagCumQty:      "2",
+		TagLeavesQty:   "2",
+	})
+
+	// above new limit, not executed
+	ts.Run([]tickers.Tick{{Ticker: "SPFB.RTS", Timestamp: time.Now(), Last: 85, Vol: 10}})
+	ts.Run([]tickers.Tick{{Ticker: "SPFB.RTS", Timestamp: time.Now(), Last: 70, Vol: 10}})
+	expectMessage(t, i, map[int]string{
+		TagClOrdID:   "2",
+		TagExecType:  ExecTypeTrade,
+		TagOrdStatus: OrdStatusFilled,
+		TagLastQty:   "2",
+		TagLastPx:    "70",
+		TagCumQty:    "4",
+		TagLeavesQty: "0",
+		TagAvgPx:     "80",
+	})
+	// trader drops completed orders from the book on next tick
+	ts.Run([]tickers.Tick{{Ticker: "SPFB.Si", Timestamp: time.Now(), Last: 1, Vol: 1}})
+
+	cancel := NewMessage(MsgTypeOrderCancelRequest)
+	cancel.Set(TagClOrdID, "3")
+	cancel.Set(TagOrigClOrdID, "2")
+	cancel.Set(TagSymbol, "SPFB.RTS")
+	cancel.Set(TagSide, SideBuy)
+	i.Send(cancel)
+	expectMessage(t, i, map[int]string{
+		TagMsgType:          MsgTypeOrderCancelReject,
+		TagClOrdID:          "3",
+		TagCxlRejResponseTo: CxlRejResponseToCancel,
+	})
+
+	// sell order cancelled before execution
+	i.Send(newOrder("4", SideSell, 3, 200))
+	expectMessage(t, i, map[int]string{
+		TagClOrdID:  "4",
+		TagExecType: ExecTypeNew,
+	})
+	cancel = NewMessage(MsgTypeOrderCancelRequest)
+	cancel.Set(TagClOrdID, "5")
+	cancel.Set(TagOrigClOrdID, "4")
+	i.Send(cancel)
+	expectMessage(t, i, map[int]string{
+		TagClOrdID:     "5",
+		TagOrigClOrdID: "4",
+		TagExecType:    ExecTypeCanceled,
+		TagOrdStatus:   OrdStatusCanceled,
+		TagLeavesQty:   "0",
+	})
+
+	// fill happens while initiator is logged out and is resent after reconnect
+	i.Send(newOrder("6", SideSell, 1, 50))
+	expectMessage(t, i, map[int]string{
+		TagClOrdID:  "6",
+		TagExecType: ExecTypeNew,
+	})
+	err = i.Stop()
+	if err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+
+	ts.Run([]tickers.Tick{{Ticker: "SPFB.RTS", Timestamp: time.Now(), Last: 60, Vol: 1}})
+	wait(20)
+
+	err = i.Start()
+	if err != nil {
+		t.Fatalf("cant logon again: %v", err)
+	}
+	expectMessage(t, i, map[int]string{
+		TagClOrdID:     "6",
+		TagExecType:    ExecTypeTrade,
+		TagOrdStatus:   OrdStatusFilled,
+		TagLastPx:      "60",
+		TagPossDupFlag: "Y",
+	})
+
+	i.Stop()
+
+	finish()
+	<-gatewayStopped
+	conn.Close()
+	<-stopped
+}
+
+func TestGatewayExpiredOrder(t *testing.T) {
+	g := &Gateway{
+		SenderCompID: "EXCHANGE",
+		BrokerID:     777,
+		Clients:      map[string]int32{"CLIENT1": 11},
+	}
+	g.Init()
+	s := g.getSession("CLIENT1")
+
+	o := &fixOrder{compID: "CLIENT1", clOrdID: "1", dealID: 42, symbol: "RTS-6.19", side: SideBuy, qty: 5, price: 100, status: OrdStatusNew}
+	g.orders[o.dealID] = o
+	g.clOrdIDs[g.clOrdKey(o.compID, o.clOrdID)] = o
+
+	// settlement of position has no order and is not reported
+	g.fill(&exchange.Deal{BrokerID: 777, ClientID: 11, Ticker: "RTS-6.19", Price: 100, Volume: 3, Status: exchange.OrderStatus_SETTLED})
+	g.fill(&exchange.Deal{ID: 42, BrokerID: 777, Ticker: "RTS-6.19", Price: 100, LeavesVolume: 5, Status: exchange.OrderStatus_EXPIRED})
+
+	if len(s.sent) != 1 {
+		t.Fatalf("expected one execution report, got %v", s.sent)
+	}
+	er := s.sent[1]
+	for tag, want := range map[int]string{
+		TagMsgType:   MsgTypeExecutionReport,
+		TagOrderID:   "42",
+		TagClOrdID:   "1",
+		TagExecType:  ExecTypeExpired,
+		TagOrdStatus: OrdStatusExpired,
+		TagLeavesQty: "0",
+		TagCumQty:    "0",
+		TagAvgPx:     "0",
+	} {
+		if have := er.GetString(tag); have != want {
+			t.Fatalf("tag %v dont match\nhave %v\nwant %v\nmessage %v", tag, have, want, er)
+		}
+	}
+	if _, ok := g.orders[42]; ok {
+		t.Fatalf("expired order must be dropped")
+	}
+}
+
+func TestGatewayFillOfReplacedDeal(t *testing.T) {
+	g := &Gateway{
+		SenderCompID: "EXCHANGE",
+		BrokerID:     777,
+		Clients:      map[string]int32{"CLIENT1": 11},
+	}
+	g.Init()
+	s := g.getSession("CLIENT1")
+
+	// replaced after 2 of 5 were filled, 42 is cancelled and 43 is placed for the rest of new quantity 4
+	o := &fixOrder{compID: "CLIENT1", clOrdID: "2", origClOrdID: "1", dealID: 43, symbol: "RTS-6.19", side: SideBuy, qty: 4, price: 80, cumQty: 2, avgPx: 90, status: OrdStatusPartiallyFilled}
+	g.orders[42] = o
+	g.orders[43] = o
+	g.clOrdIDs[g.clOrdKey(o.compID, o.clOrdID)] = o
+
+	// fill of 42 was queued before the cancel
+	g.fill(&exchange.Deal{ID: 42, BrokerID: 777, Ticker: "RTS-6.19", Price: 90, Volume: 1, Status: exchange.OrderStatus_FILLED})
+
+	if len(s.sent) != 1 {
+		t.Fatalf("expected one execution report, got %v", s.sent)
+	}
+	er := s.sent[1]
+	for tag, want := range map[int]string{
+		TagOrderID:   "43",
+		TagClOrdID:   "2",
+		TagExecType:  ExecTypeTrade,
+		TagOrdStatus: OrdStatusPartiallyFilled,
+		TagLastQty:   "1",
+		TagCumQty:    "3",
+		TagLeavesQty: "1",
+		TagAvgPx:     "90",
+	} {
+		if have := er.GetString(tag); have != want {
+			t.Fatalf("tag %v dont match\nhave %v\nwant %v\nmessage %v", tag, have, want, er)
+		}
+	}
+	if _, ok := g.orders[42]; ok {
+		t.Fatalf("DealID 42 is filled and must be dropped")
+	}
+	if _, ok := g.orders[43]; !ok {
+		t.Fatalf("DealID 43 is active and must be kept")
+	}
+}
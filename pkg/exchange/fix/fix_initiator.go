@@ -0,0 +1,97 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"time"
+)
+
+// Initiator is minimal FIX client used to test the gateway and to script legacy tools.
+// Application messages received from acceptor are delivered to Messages channel.
+type Initiator struct {
+	Addr         string
+	SenderCompID string
+	TargetCompID string
+	HeartBtInt   time.Duration
+
+	Messages chan *Message
+
+	session *Session
+	done    chan error
+}
+
+// Start connects and logs on, sequence numbers continue from previous connection
+func (i *Initiator) Start() error {
+	if i.session == nil {
+		if i.Messages == nil {
+			i.Messages = make(chan *Message, 100)
+		}
+		i.session = NewSession(i.SenderCompID, i.TargetCompID, i.HeartBtInt, func(s *Session, m *Message) {
+			i.Messages <- m
+		})
+		i.session.initiator = true
+	}
+
+	conn, err := net.Dial("tcp", i.Addr)
+	if err != nil {
+		return err
+	}
+	i.session.attach(conn)
+	loggedOn := i.session.LoggedOn()
+
+	logon := NewMessage(MsgTypeLogon)
+	logon.Set(TagEncryptMethod, "0")
+	logon.SetInt(TagHeartBtInt, int64(i.session.HeartBtInt/time.Second))
+	err = i.session.Send(logon)
+	if err != nil {
+		i.session.detach()
+		return err
+	}
+
+	done := make(chan error, 1)
+	i.done = done
+	go func() {
+		done <- i.session.serve(bufio.NewReader(conn), nil)
+	}()
+
+	select {
+	case <-loggedOn:
+		return nil
+	case err := <-done:
+		if err == nil {
+			err = ErrorLogonRejected
+		}
+		return err
+	case <-time.After(10 * time.Second):
+		i.session.detach()
+		return ErrorLogonRejected
+	}
+}
+
+func (i *Initiator) Send(m *Message) error {
+	if i.session == nil {
+		return ErrorNotLoggedOn
+	}
+	return i.session.Send(m)
+}
+
+// Stop logs out and waits for acceptor confirmation
+func (i *Initiator) Stop() error {
+	if i.session == nil || !i.session.IsLoggedOn() {
+		return ErrorNotLoggedOn
+	}
+
+	err := i.session.Logout("")
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-i.done:
+		return err
+	case <-time.After(10 * time.Second):
+		i.session.detach()
+		return errors.New("fix logout confirmation timeout")
+	}
+}
@@ -0,0 +1,228 @@
+package fix
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// Message keeps fields in order of appearance, header tags are written first on encoding
+type Message struct {
+	Fields []Field
+}
+
+// standard header order after BeginString and BodyLength
+var headerTags = []int{TagMsgType, TagSenderCompID, TagTargetCompID, TagMsgSeqNum, TagPossDupFlag, TagSendingTime, TagOrigSendingTime}
+
+func NewMessage(msgType string) *Message {
+	m := &Message{
+		Fields: make([]Field, 0, 16),
+	}
+	m.Set(TagMsgType, msgType)
+	return m
+}
+
+// Set replaces value of existing tag or appends new one
+func (m *Message) Set(tag int, value string) *Message {
+	for i := range m.Fields {
+		if m.Fields[i].Tag == tag {
+			m.Fields[i].Value = value
+			return m
+		}
+	}
+	m.Fields = append(m.Fields, Field{Tag: tag, Value: value})
+	return m
+}
+
+func (m *Message) SetInt(tag int, value int64) *Message {
+	return m.Set(tag, strconv.FormatInt(value, 10))
+}
+
+func (m *Message) SetFloat(tag int, value float64) *Message {
+	return m.Set(tag, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func (m *Message) Get(tag int) (string, bool) {
+	for _, f := range m.Fields {
+		if f.Tag == tag {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// GetString returns empty string for missing tag
+func (m *Message) GetString(tag int) string {
+	v, _ := m.Get(tag)
+	return v
+}
+
+func (m *Message) GetInt(tag int) (int64, error) {
+	v, ok := m.Get(tag)
+	if !ok {
+		return 0, fmt.Errorf("%w: %v", ErrorTagNotFound, tag)
+	}
+	return strconv.ParseInt(v, 10, 64)
+}
+
+func (m *Message) GetFloat(tag int) (float64, error) {
+	v, ok := m.Get(tag)
+	if !ok {
+		return 0, fmt.Errorf("%w: %v", ErrorTagNotFound, tag)
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+func (m *Message) GetBool(tag int) bool {
+	v, _ := m.Get(tag)
+	return v == "Y"
+}
+
+func (m *Message) MsgType() string {
+	return m.GetString(TagMsgType)
+}
+
+func (m *Message) SeqNum() int {
+	v, _ := m.GetInt(TagMsgSeqNum)
+	return int(v)
+}
+
+func (m *Message) Clone() *Message {
+	c := &Message{
+		Fields: make([]Field, len(m.Fields)),
+	}
+	copy(c.Fields, m.Fields)
+	return c
+}
+
+// Bytes encodes message with BeginString, BodyLength and CheckSum calculated
+func (m *Message) Bytes() []byte {
+	body := &bytes.Buffer{}
+	written := make(map[int]struct{}, len(headerTags))
+	for _, tag := range headerTags {
+		if v, ok := m.Get(tag); ok {
+			fmt.Fprintf(body, "%d=%s%c", tag, v, SOH)
+			written[tag] = struct{}{}
+		}
+	}
+	for _, f := range m.Fields {
+		switch f.Tag {
+		case TagBeginString, TagBodyLength, TagCheckSum:
+			continue
+		}
+		if _, ok := written[f.Tag]; ok {
+			continue
+		}
+		fmt.Fprintf(body, "%d=%s%c", f.Tag, f.Value, SOH)
+	}
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "%d=%s%c%d=%d%c", TagBeginString, BeginString, SOH, TagBodyLength, body.Len(), SOH)
+	out.Write(body.Bytes())
+	fmt.Fprintf(out, "%d=%03d%c", TagCheckSum, checksum(out.Bytes()), SOH)
+	return out.Bytes()
+}
+
+// String is for logging only, SOH is replaced with |
+func (m *Message) String() string {
+	return strings.ReplaceAll(string(m.Bytes()), string(SOH), "|")
+}
+
+func checksum(b []byte) int {
+	var sum int
+	for _, c := range b {
+		sum += int(c)
+	}
+	return sum % 256
+}
+
+// ReadMessage reads one complete message from stream and validates its framing
+func ReadMessage(r *bufio.Reader) (*Message, error) {
+	raw := &bytes.Buffer{}
+
+	begin, err := readField(r, raw)
+	if err != nil {
+		return nil, err
+	}
+	if begin.Tag != TagBeginString {
+		return nil, ErrorGarbledMessage
+	}
+	if begin.Value != BeginString {
+		return nil, ErrorUnsupportedBeginString
+	}
+
+	length, err := readField(r, raw)
+	if err != nil {
+		return nil, err
+	}
+	if length.Tag != TagBodyLength {
+		return nil, ErrorGarbledMessage
+	}
+	bodyLen, err := strconv.Atoi(length.Value)
+	if err != nil || bodyLen <= 0 {
+		return nil, ErrorGarbledMessage
+	}
+
+	body := make([]byte, bodyLen)
+	_, err = io.ReadFull(r, body)
+	if err != nil {
+		return nil, err
+	}
+	raw.Write(body)
+
+	trailer, err := readField(r, &bytes.Buffer{})
+	if err != nil {
+		return nil, err
+	}
+	if trailer.Tag != TagCheckSum {
+		return nil, ErrorGarbledMessage
+	}
+	if fmt.Sprintf("%03d", checksum(raw.Bytes())) != trailer.Value {
+		return nil, ErrorBadChecksum
+	}
+
+	m := &Message{
+		Fields: make([]Field, 0, 16),
+	}
+	for _, kv := range bytes.Split(bytes.TrimSuffix(body, []byte{SOH}), []byte{SOH}) {
+		f, err := parseField(kv)
+		if err != nil {
+			return nil, err
+		}
+		m.Fields = append(m.Fields, f)
+	}
+	if m.MsgType() == "" {
+		return nil, ErrorGarbledMessage
+	}
+
+	return m, nil
+}
+
+func readField(r *bufio.Reader, raw *bytes.Buffer) (Field, error) {
+	b, err := r.ReadBytes(SOH)
+	if err != nil {
+		return Field{}, err
+	}
+	raw.Write(b)
+	return parseField(b[:len(b)-1])
+}
+
+func parseField(b []byte) (Field, error) {
+	i := bytes.IndexByte(b, '=')
+	if i <= 0 {
+		return Field{}, ErrorGarbledMessage
+	}
+	tag, err := strconv.Atoi(string(b[:i]))
+	if err != nil {
+		return Field{}, ErrorGarbledMessage
+	}
+	return Field{Tag: tag, Value: string(b[i+1:])}, nil
+}
@@ -0,0 +1,465 @@
+package fix
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Session keeps FIX session level state: sequence numbers, sent messages for resend and heartbeats.
+// The same session instance survives reconnects of the counterparty so sequence numbers continue.
+type Session struct {
+	SenderCompID string
+	TargetCompID string
+	HeartBtInt   time.Duration
+
+	// application messages handler, called from serving goroutine
+	Handler func(s *Session, m *Message)
+	Logger  *zap.SugaredLogger
+
+	initiator bool
+
+	lock       *sync.Mutex
+	conn       net.Conn
+	loggedOn   bool
+	loggedOnCh chan struct{}
+	logoutSent bool
+	outSeqNum  int
+	lastSent   time.Time
+	sent       map[int]*Message
+
+	// accessed from serving goroutine only
+	inSeqNum     int
+	resendTarget int
+	lastRecv     time.Time
+	testReqID    string
+}
+
+var errSessionEnded = errors.New("fix session ended by logout")
+
+func NewSession(senderCompID string, targetCompID string, heartBtInt time.Duration, handler func(s *Session, m *Message)) *Session {
+	if heartBtInt <= 0 {
+		heartBtInt = 30 * time.Second
+	}
+	return &Session{
+		SenderCompID: senderCompID,
+		TargetCompID: targetCompID,
+		HeartBtInt:   heartBtInt,
+		Handler:      handler,
+		Logger:       zap.S(),
+		lock:         &sync.Mutex{},
+		loggedOnCh:   make(chan struct{}),
+		outSeqNum:    1,
+		inSeqNum:     1,
+		sent:         make(map[int]*Message, 100),
+	}
+}
+
+// Send stamps header with next sequence number and writes message if session is logged on.
+// Application messages are stored and delivered on counterparty ResendRequest otherwise.
+func (s *Session) Send(m *Message) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	m.Set(TagSenderCompID, s.SenderCompID)
+	m.Set(TagTargetCompID, s.TargetCompID)
+	m.SetInt(TagMsgSeqNum, int64(s.outSeqNum))
+	m.Set(TagSendingTime, timestamp(time.Now()))
+	if !IsAdmin(m.MsgType()) {
+		s.sent[s.outSeqNum] = m.Clone()
+	}
+	s.outSeqNum++
+
+	return s.write(m)
+}
+
+// Logout initiates session termination, counterparty is expected to confirm with Logout
+func (s *Session) Logout(text string) error {
+	m := NewMessage(MsgTypeLogout)
+	if text != "" {
+		m.Set(TagText, text)
+	}
+
+	s.lock.Lock()
+	s.logoutSent = true
+	s.lock.Unlock()
+
+	return s.Send(m)
+}
+
+func (s *Session) IsLoggedOn() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.loggedOn
+}
+
+// LoggedOn returns channel closed when current connection completes logon
+func (s *Session) LoggedOn() <-chan struct{} {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.loggedOnCh
+}
+
+// lock must be held
+func (s *Session) write(m *Message) error {
+	if s.conn == nil {
+		return ErrorNotLoggedOn
+	}
+	msgType := m.MsgType()
+	if !s.loggedOn && msgType != MsgTypeLogon && msgType != MsgTypeLogout {
+		return ErrorNotLoggedOn
+	}
+
+	s.lastSent = time.Now()
+	_, err := s.conn.Write(m.Bytes())
+	return err
+}
+
+func (s *Session) attach(conn net.Conn) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn != nil {
+		return false
+	}
+	s.conn = conn
+	s.loggedOn = false
+	s.loggedOnCh = make(chan struct{})
+	s.logoutSent = false
+	return true
+}
+
+func (s *Session) detach() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	s.loggedOn = false
+}
+
+func (s *Session) setLoggedOn() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.loggedOn = true
+	close(s.loggedOnCh)
+}
+
+func (s *Session) resetSeqNums() {
+	s.lock.Lock()
+	s.outSeqNum = 1
+	s.sent = make(map[int]*Message, 100)
+	s.lock.Unlock()
+
+	s.inSeqNum = 1
+	s.resendTarget = 0
+}
+
+// serve processes messages of attached connection until logout or connection failure.
+// first is already read message (Logon on acceptor side) or nil.
+func (s *Session) serve(r *bufio.Reader, first *Message) error {
+	defer s.detach()
+
+	msgs := make(chan *Message)
+	readErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		for {
+			m, err := ReadMessage(r)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case msgs <- m:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	s.lastRecv = time.Now()
+	s.testReqID = ""
+	if first != nil {
+		err := s.process(first)
+		if err != nil {
+			return s.ended(err)
+		}
+	}
+
+	check := time.NewTicker(s.HeartBtInt / 4)
+	defer check.Stop()
+
+	for {
+		select {
+		case m := <-msgs:
+			s.lastRecv = time.Now()
+			s.testReqID = ""
+			err := s.process(m)
+			if err != nil {
+				return s.ended(err)
+			}
+		case err := <-readErr:
+			s.lock.Lock()
+			logoutSent := s.logoutSent
+			s.lock.Unlock()
+			if logoutSent {
+				return nil
+			}
+			return err
+		case now := <-check.C:
+			err := s.checkHeartbeat(now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Session) ended(err error) error {
+	if err == errSessionEnded {
+		return nil
+	}
+	return err
+}
+
+func (s *Session) checkHeartbeat(now time.Time) error {
+	s.lock.Lock()
+	idle := now.Sub(s.lastSent)
+	loggedOn := s.loggedOn
+	s.lock.Unlock()
+
+	if !loggedOn {
+		return nil
+	}
+
+	if idle >= s.HeartBtInt {
+		s.Send(NewMessage(MsgTypeHeartbeat))
+	}
+
+	silent := now.Sub(s.lastRecv)
+	switch {
+	case s.testReqID != "" && silent >= 2*s.HeartBtInt:
+		return ErrorHeartbeatTimeout
+	case s.testReqID == "" && silent >= s.HeartBtInt+s.HeartBtInt/5:
+		s.testReqID = strconv.FormatInt(now.UnixNano(), 10)
+		tr := NewMessage(MsgTypeTestRequest)
+		tr.Set(TagTestReqID, s.testReqID)
+		s.Send(tr)
+	}
+	return nil
+}
+
+func (s *Session) process(m *Message) error {
+	msgType := m.MsgType()
+
+	if !s.IsLoggedOn() {
+		if msgType != MsgTypeLogon {
+			return ErrorNotLoggedOn
+		}
+		return s.processLogon(m)
+	}
+
+	if m.GetString(TagSenderCompID) != s.TargetCompID || m.GetString(TagTargetCompID) != s.SenderCompID {
+		s.reject(m, "CompID problem")
+		s.Logout("CompID problem")
+		return nil
+	}
+
+	// reset mode ignores MsgSeqNum completely
+	if msgType == MsgTypeSequenceReset && !m.GetBool(TagGapFillFlag) {
+		newSeqNum, err := m.GetInt(TagNewSeqNo)
+		if err == nil && int(newSeqNum) > s.inSeqNum {
+			s.inSeqNum = int(newSeqNum)
+		}
+		return nil
+	}
+
+	seq := m.SeqNum()
+	switch {
+	case seq > s.inSeqNum:
+		switch msgType {
+		case MsgTypeResendRequest:
+			s.processResendRequest(m)
+		case MsgTypeLogout:
+			return s.dispatch(m)
+		}
+		s.requestResend(seq)
+		return nil
+	case seq < s.inSeqNum:
+		if m.GetBool(TagPossDupFlag) {
+			return nil
+		}
+		s.Logout(fmt.Sprintf("MsgSeqNum too low, expecting %v but received %v", s.inSeqNum, seq))
+		return ErrorSeqNumTooLow
+	}
+
+	s.inSeqNum++
+	err := s.dispatch(m)
+	if s.resendTarget > 0 && s.inSeqNum > s.resendTarget {
+		s.resendTarget = 0
+	}
+	return err
+}
+
+func (s *Session) processLogon(m *Message) error {
+	reset := m.GetBool(TagResetSeqNumFlag)
+	if reset && !s.initiator {
+		s.resetSeqNums()
+	}
+
+	if !s.initiator {
+		hb, err := m.GetInt(TagHeartBtInt)
+		if err == nil && hb > 0 {
+			s.HeartBtInt = time.Duration(hb) * time.Second
+		}
+	}
+
+	seq := m.SeqNum()
+	if seq < s.inSeqNum {
+		s.Logout(fmt.Sprintf("MsgSeqNum too low, expecting %v but received %v", s.inSeqNum, seq))
+		return ErrorSeqNumTooLow
+	}
+
+	if !s.initiator {
+		reply := NewMessage(MsgTypeLogon)
+		reply.Set(TagEncryptMethod, "0")
+		reply.SetInt(TagHeartBtInt, int64(s.HeartBtInt/time.Second))
+		if reset {
+			reply.Set(TagResetSeqNumFlag, "Y")
+		}
+		err := s.Send(reply)
+		if err != nil {
+			return err
+		}
+	}
+	s.setLoggedOn()
+
+	if seq > s.inSeqNum {
+		s.requestResend(seq)
+	} else {
+		s.inSeqNum++
+	}
+	return nil
+}
+
+func (s *Session) dispatch(m *Message) error {
+	switch m.MsgType() {
+	case MsgTypeHeartbeat:
+	case MsgTypeTestRequest:
+		hb := NewMessage(MsgTypeHeartbeat)
+		hb.Set(TagTestReqID, m.GetString(TagTestReqID))
+		s.Send(hb)
+	case MsgTypeResendRequest:
+		s.processResendRequest(m)
+	case MsgTypeReject:
+		s.Logger.Warnw("FIX session received reject", "compId", s.TargetCompID, "message", m)
+	case MsgTypeSequenceReset:
+		newSeqNum, err := m.GetInt(TagNewSeqNo)
+		if err == nil && int(newSeqNum) > s.inSeqNum {
+			s.inSeqNum = int(newSeqNum)
+		}
+	case MsgTypeLogout:
+		s.lock.Lock()
+		logoutSent := s.logoutSent
+		s.lock.Unlock()
+		if !logoutSent {
+			s.Logout("")
+		}
+		return errSessionEnded
+	case MsgTypeLogon:
+		s.reject(m, "already logged on")
+	default:
+		if s.Handler != nil {
+			s.Handler(s, m)
+		}
+	}
+	return nil
+}
+
+// requestResend asks for everything starting from expected sequence number, only once per gap
+func (s *Session) requestResend(seq int) {
+	if s.resendTarget > 0 {
+		if seq > s.resendTarget {
+			s.resendTarget = seq
+		}
+		return
+	}
+	s.resendTarget = seq
+
+	r := NewMessage(MsgTypeResendRequest)
+	r.SetInt(TagBeginSeqNo, int64(s.inSeqNum))
+	r.SetInt(TagEndSeqNo, 0)
+	s.Send(r)
+}
+
+func (s *Session) processResendRequest(m *Message) {
+	begin, _ := m.GetInt(TagBeginSeqNo)
+	end, _ := m.GetInt(TagEndSeqNo)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	last := int64(s.outSeqNum - 1)
+	if begin < 1 {
+		begin = 1
+	}
+	if end == 0 || end > last {
+		end = last
+	}
+
+	var gapStart int64
+	for seq := begin; seq <= end; seq++ {
+		orig, ok := s.sent[int(seq)]
+		if !ok {
+			if gapStart == 0 {
+				gapStart = seq
+			}
+			continue
+		}
+		if gapStart > 0 {
+			s.writeGapFill(gapStart, seq)
+			gapStart = 0
+		}
+
+		r := orig.Clone()
+		r.Set(TagPossDupFlag, "Y")
+		r.Set(TagOrigSendingTime, orig.GetString(TagSendingTime))
+		r.Set(TagSendingTime, timestamp(time.Now()))
+		s.write(r)
+	}
+	if gapStart > 0 {
+		s.writeGapFill(gapStart, end+1)
+	}
+}
+
+// lock must be held
+func (s *Session) writeGapFill(seq int64, newSeqNum int64) {
+	m := NewMessage(MsgTypeSequenceReset)
+	m.Set(TagSenderCompID, s.SenderCompID)
+	m.Set(TagTargetCompID, s.TargetCompID)
+	m.SetInt(TagMsgSeqNum, seq)
+	m.Set(TagPossDupFlag, "Y")
+	m.Set(TagSendingTime, timestamp(time.Now()))
+	m.Set(TagGapFillFlag, "Y")
+	m.SetInt(TagNewSeqNo, newSeqNum)
+	s.write(m)
+}
+
+func (s *Session) reject(m *Message, text string) {
+	r := NewMessage(MsgTypeReject)
+	r.SetInt(TagRefSeqNum, int64(m.SeqNum()))
+	r.Set(TagText, text)
+	s.Send(r)
+}
@@ -0,0 +1,66 @@
+package matching
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Order is a resting order which may trade with the tick
+type Order struct {
+	ID     int64
+	Volume int32   // leaves volume
+	Price  float32 // limit price, positive for both sides
+}
+
+// Matcher splits tick volume between orders of one side.
+// Orders come in price-time priority, allocation is returned in the same order
+// and never exceeds order volume or available volume in total.
+type Matcher interface {
+	Allocate(orders []Order, available int32) []int32
+}
+
+const (
+	AlgorithmFIFO    = "fifo"
+	AlgorithmProRata = "pro_rata"
+	AlgorithmHybrid  = "hybrid"
+)
+
+var (
+	ErrorUnknownAlgorithm = errors.New("unknown matching algorithm")
+)
+
+// New builds matcher by algorithm name, empty name means FIFO.
+// minAllocation is used by pro-rata and hybrid, topOrderPercent by hybrid only.
+func New(algorithm string, minAllocation int32, topOrderPercent float64) (Matcher, error) {
+	switch algorithm {
+	case "", AlgorithmFIFO:
+		return FIFO{}, nil
+	case AlgorithmProRata:
+		return ProRata{MinAllocation: minAllocation}, nil
+	case AlgorithmHybrid:
+		if topOrderPercent < 0 || topOrderPercent > 100 {
+			return nil, fmt.Errorf("top order percent must be in [0, 100], got %v", topOrderPercent)
+		}
+		return Hybrid{TopOrderPercent: topOrderPercent, MinAllocation: minAllocation}, nil
+	default:
+		return nil, fmt.Errorf("%w %q, supported: %v, %v, %v", ErrorUnknownAlgorithm, algorithm, AlgorithmFIFO, AlgorithmProRata, AlgorithmHybrid)
+	}
+}
+
+// fillInOrder gives remaining volume to orders in priority order on top of what they already got
+func fillInOrder(orders []Order, alloc []int32, available int32) int32 {
+	for i, o := range orders {
+		if available <= 0 {
+			break
+		}
+		add := o.Volume - alloc[i]
+		if add > available {
+			add = available
+		}
+		if add > 0 {
+			alloc[i] += add
+			available -= add
+		}
+	}
+	return available
+}
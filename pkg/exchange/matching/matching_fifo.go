@@ -0,0 +1,10 @@
+package matching
+
+// FIFO is pure price-time priority: best and oldest order is filled first
+type FIFO struct{}
+
+func (FIFO) Allocate(orders []Order, available int32) []int32 {
+	alloc := make([]int32, len(orders))
+	fillInOrder(orders, alloc, available)
+	return alloc
+}
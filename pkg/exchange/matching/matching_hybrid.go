@@ -0,0 +1,30 @@
+package matching
+
+// Hybrid gives the top priority order up to TopOrderPercent of available volume first
+// (whole volume if 0), the rest is split pro-rata between all orders.
+type Hybrid struct {
+	TopOrderPercent float64
+	MinAllocation   int32
+}
+
+func (h Hybrid) Allocate(orders []Order, available int32) []int32 {
+	if len(orders) == 0 {
+		return []int32{}
+	}
+
+	top := available
+	if h.TopOrderPercent > 0 {
+		top = int32(float64(available) * h.TopOrderPercent / 100)
+	}
+	if top > orders[0].Volume {
+		top = orders[0].Volume
+	}
+
+	rest := make([]Order, len(orders))
+	copy(rest, orders)
+	rest[0].Volume -= top
+
+	alloc := ProRata{MinAllocation: h.MinAllocation}.Allocate(rest, available-top)
+	alloc[0] += top
+	return alloc
+}
@@ -0,0 +1,47 @@
+package matching
+
+// ProRata splits volume proportionally to order size, rounding down.
+// Shares below MinAllocation are not given, leftover goes in price-time priority.
+// Only the best price level is shared pro-rata, worse levels get what is left after it.
+type ProRata struct {
+	MinAllocation int32
+}
+
+func (p ProRata) Allocate(orders []Order, available int32) []int32 {
+	alloc := make([]int32, len(orders))
+
+	for start := 0; start < len(orders) && available > 0; {
+		end := start + 1
+		for end < len(orders) && orders[end].Price == orders[start].Price {
+			end++
+		}
+		available = p.allocateLevel(orders[start:end], alloc[start:end], available)
+		start = end
+	}
+	return alloc
+}
+
+func (p ProRata) allocateLevel(orders []Order, alloc []int32, available int32) int32 {
+	var total int64
+	for _, o := range orders {
+		total += int64(o.Volume)
+	}
+	if total <= int64(available) {
+		for i, o := range orders {
+			alloc[i] = o.Volume
+		}
+		return available - int32(total)
+	}
+
+	left := available
+	for i, o := range orders {
+		share := int32(int64(available) * int64(o.Volume) / total)
+		if share < p.MinAllocation {
+			continue
+		}
+		alloc[i] = share
+		left -= share
+	}
+
+	return fillInOrder(orders, alloc, left)
+}
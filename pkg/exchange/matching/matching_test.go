@@ -0,0 +1,148 @@
+package matching
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type allocationTest struct {
+	name      string
+	matcher   Matcher
+	orders    []Order
+	available int32
+	expected  []int32
+}
+
+var allocationTests = []allocationTest{
+	{
+		name:      "fifo fills oldest first",
+		matcher:   FIFO{},
+		orders:    []Order{{ID: 1, Volume: 3, Price: 100}, {ID: 2, Volume: 5, Price: 100}, {ID: 3, Volume: 2, Price: 100}},
+		available: 6,
+		expected:  []int32{3, 3, 0},
+	},
+	{
+		name:      "fifo all filled",
+		matcher:   FIFO{},
+		orders:    []Order{{ID: 1, Volume: 3, Price: 101}, {ID: 2, Volume: 5, Price: 100}},
+		available: 20,
+		expected:  []int32{3, 5},
+	},
+	{
+		name:      "fifo nothing available",
+		matcher:   FIFO{},
+		orders:    []Order{{ID: 1, Volume: 3, Price: 100}},
+		available: 0,
+		expected:  []int32{0},
+	},
+	{
+		name:      "pro-rata proportional",
+		matcher:   ProRata{},
+		orders:    []Order{{ID: 1, Volume: 10, Price: 100}, {ID: 2, Volume: 30, Price: 100}},
+		available: 20,
+		expected:  []int32{5, 15},
+	},
+	{
+		name:      "pro-rata rounding leftover goes by priority",
+		matcher:   ProRata{},
+		orders:    []Order{{ID: 1, Volume: 1, Price: 100}, {ID: 2, Volume: 1, Price: 100}, {ID: 3, Volume: 1, Price: 100}},
+		available: 2,
+		expected:  []int32{1, 1, 0},
+	},
+	{
+		name:      "pro-rata min allocation",
+		matcher:   ProRata{MinAllocation: 2},
+		orders:    []Order{{ID: 1, Volume: 2, Price: 100}, {ID: 2, Volume: 18, Price: 100}},
+		available: 10,
+		expected:  []int32{1, 9}, // share of the first is 1 < 2, it gets leftover by priority only
+	},
+	{
+		name:      "pro-rata min allocation leftover",
+		matcher:   ProRata{MinAllocation: 3},
+		orders:    []Order{{ID: 1, Volume: 4, Price: 100}, {ID: 2, Volume: 4, Price: 100}, {ID: 3, Volume: 12, Price: 100}},
+		available: 10,
+		expected:  []int32{4, 0, 6}, // shares 2, 2, 6: first two are below minimum, 4 left go to the oldest
+	},
+	{
+		name:      "pro-rata better price level first",
+		matcher:   ProRata{},
+		orders:    []Order{{ID: 1, Volume: 4, Price: 101}, {ID: 2, Volume: 10, Price: 100}, {ID: 3, Volume: 30, Price: 100}},
+		available: 12,
+		expected:  []int32{4, 2, 6},
+	},
+	{
+		name:      "hybrid top order takes all it can",
+		matcher:   Hybrid{},
+		orders:    []Order{{ID: 1, Volume: 4, Price: 100}, {ID: 2, Volume: 10, Price: 100}, {ID: 3, Volume: 30, Price: 100}},
+		available: 12,
+		expected:  []int32{4, 2, 6},
+	},
+	{
+		name:      "hybrid top order percent",
+		matcher:   Hybrid{TopOrderPercent: 40},
+		orders:    []Order{{ID: 1, Volume: 10, Price: 100}, {ID: 2, Volume: 10, Price: 100}, {ID: 3, Volume: 20, Price: 100}},
+		available: 10,
+		expected:  []int32{6, 1, 3}, // top gets 4, rest 6 pro-rata over 6, 10, 20: 1, 1, 3, leftover 1 to the top
+	},
+	{
+		name:      "hybrid single order",
+		matcher:   Hybrid{TopOrderPercent: 50},
+		orders:    []Order{{ID: 1, Volume: 10, Price: 100}},
+		available: 6,
+		expected:  []int32{6},
+	},
+	{
+		name:      "hybrid no orders",
+		matcher:   Hybrid{},
+		orders:    []Order{},
+		available: 6,
+		expected:  []int32{},
+	},
+}
+
+func TestAllocate(t *testing.T) {
+	for _, tt := range allocationTests {
+		t.Run(tt.name, func(t *testing.T) {
+			have := tt.matcher.Allocate(tt.orders, tt.available)
+			if !reflect.DeepEqual(have, tt.expected) {
+				t.Fatalf("allocation dont match\nhave %v\nwant %v", have, tt.expected)
+			}
+
+			var total int32
+			for i, a := range have {
+				if a < 0 || a > tt.orders[i].Volume {
+					t.Fatalf("allocation %v of order %v is out of its volume %v", a, tt.orders[i].ID, tt.orders[i].Volume)
+				}
+				total += a
+			}
+			if total > tt.available {
+				t.Fatalf("allocated %v of %v available", total, tt.available)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		expected  Matcher
+		err       error
+	}{
+		{algorithm: "", expected: FIFO{}},
+		{algorithm: AlgorithmFIFO, expected: FIFO{}},
+		{algorithm: AlgorithmProRata, expected: ProRata{MinAllocation: 2}},
+		{algorithm: AlgorithmHybrid, expected: Hybrid{TopOrderPercent: 40, MinAllocation: 2}},
+		{algorithm: "auction", err: ErrorUnknownAlgorithm},
+	}
+
+	for _, tt := range tests {
+		have, err := New(tt.algorithm, 2, 40)
+		if !errors.Is(err, tt.err) {
+			t.Fatalf("%q: unexpected error %v", tt.algorithm, err)
+		}
+		if !reflect.DeepEqual(have, tt.expected) {
+			t.Fatalf("%q: matcher dont match\nhave %#v\nwant %#v", tt.algorithm, have, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "exchange"
+
+// order outcomes
+const (
+	OrderCreated         = "created"
+	OrderCancelled       = "cancelled"
+	OrderCancelNotFound  = "cancel_not_found"
+	OrderFilled          = "filled"
+	OrderPartiallyFilled = "partially_filled"
+	OrderDropped         = "dropped" // zero price
+)
+
+// book sides
+const (
+	SideBid = "bid"
+	SideAsk = "ask"
+)
+
+// streams brokers connect to
+const (
+	StreamStatistic = "statistic"
+	StreamResults   = "results"
+	StreamTrades    = "trades"
+)
+
+// faults injected by chaos mode
+const (
+	FaultDelay     = "delay"
+	FaultDrop      = "drop"
+	FaultDuplicate = "duplicate"
+	FaultReject    = "reject"
+	FaultAbort     = "abort"
+)
+
+var (
+	Orders = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_total",
+		Help:      "Orders by outcome.",
+	}, []string{"outcome"})
+
+	MatchLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "match_duration_seconds",
+		Help:      "Time to match one tick against the order book.",
+		Buckets:   prometheus.ExponentialBuckets(0.00001, 4, 10),
+	})
+
+	BookDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "book_depth",
+		Help:      "Resting orders per ticker.",
+	}, []string{"ticker"})
+
+	BookVolume = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "book_displayed_volume",
+		Help:      "Resting volume visible in the book per ticker and side, iceberg reserve excluded.",
+	}, []string{"ticker", "side"})
+
+	ConnectedBrokers = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "connected_brokers",
+		Help:      "Open broker streams.",
+	}, []string{"stream"})
+
+	Conflated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "conflated_messages_total",
+		Help:      "Messages replaced by newer ones before a slow subscriber got them.",
+	}, []string{"stream"})
+
+	ChaosFaults = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "chaos_faults_total",
+		Help:      "Faults injected into broker streams and calls by chaos mode.",
+	}, []string{"fault"})
+
+	FeedLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "tick_feed_lag_seconds",
+		Help:      "How late the last tick was pushed to consumers relative to its replay time.",
+	})
+
+	backlog = &backlogCollector{
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "channel_backlog"),
+			"Messages waiting in subscriber channel.",
+			[]string{"channel", "subscriber"}, nil,
+		),
+		lock:    &sync.RWMutex{},
+		sources: make(map[string]func() map[string]int, 4),
+	}
+)
+
+func init() {
+	prometheus.MustRegister(backlog)
+}
+
+// RegisterBacklog makes f report channel lengths per subscriber on every scrape,
+// registering the same channel name again replaces previous source
+func RegisterBacklog(channel string, f func() map[string]int) {
+	backlog.lock.Lock()
+	defer backlog.lock.Unlock()
+	backlog.sources[channel] = f
+}
+
+type backlogCollector struct {
+	desc    *prometheus.Desc
+	lock    *sync.RWMutex
+	sources map[string]func() map[string]int
+}
+
+func (b *backlogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- b.desc
+}
+
+func (b *backlogCollector) Collect(ch chan<- prometheus.Metric) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	for channel, f := range b.sources {
+		for subscriber, n := range f() {
+			ch <- prometheus.MustNewConstMetric(b.desc, prometheus.GaugeValue, float64(n), channel, subscriber)
+		}
+	}
+}
+
+// SubscriberID formats numeric subscriber id for backlog label
+func SubscriberID(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// Serve exposes /metrics until ctx is done
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	err := srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
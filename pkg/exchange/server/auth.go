@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator checks that consumer passed in "consumer" metadata may call the method.
+// Allowed methods are full names like "/main.Exchange/Create" or prefix with "*" like "/main.Exchange/*".
+type Authenticator struct {
+	accessList map[string][]string
+}
+
+func (a *Authenticator) AuthInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	err := a.checkAccess(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *Authenticator) AuthStreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	err := a.checkAccess(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+func (a *Authenticator) checkAccess(ctx context.Context, method string) error {
+	if len(a.accessList) == 0 {
+		return nil
+	}
+
+	// health checks are open for probes
+	if strings.HasPrefix(method, "/grpc.health.v1.Health/") {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	consumer := md.Get("consumer")
+	if len(consumer) == 0 {
+		return status.Error(codes.Unauthenticated, "consumer is not provided")
+	}
+
+	allowed, ok := a.accessList[consumer[0]]
+	if !ok {
+		return status.Errorf(codes.Unauthenticated, "unknown consumer %v", consumer[0])
+	}
+
+	for _, m := range allowed {
+		if m == method || strings.HasSuffix(m, "*") && strings.HasPrefix(method, strings.TrimSuffix(m, "*")) {
+			return nil
+		}
+	}
+	return status.Errorf(codes.PermissionDenied, "method %v is not allowed for %v", method, consumer[0])
+}
@@ -0,0 +1,202 @@
+package server
+
+import (
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/metrics"
+	"github.com/google/btree"
+)
+
+const bookDegree = 32
+
+// bookOrder is resting order with its time priority
+type bookOrder struct {
+	deal *exchange.Deal
+	seq  int64 // time priority inside price level
+
+	// iceberg peak and what is left of it, peak is 0 for plain order
+	peak  int32
+	shown int32
+
+	// simulation: order does not trade with ticks before activeAt
+	// and waits for ahead volume to trade at its price first
+	activeAt time.Time
+	ahead    int64
+}
+
+// orderBook is resting orders of one ticker in price-time priority.
+// It is owned by shard goroutine and is not locked.
+type orderBook struct {
+	ticker string
+	bids   *btree.BTreeG[*bookOrder] // highest price first
+	asks   *btree.BTreeG[*bookOrder] // lowest price first
+	orders map[int64]*bookOrder
+	seq    int64
+
+	// displayed volume for metrics
+	bidVolume int64
+	askVolume int64
+}
+
+func newOrderBook(ticker string) *orderBook {
+	return &orderBook{
+		ticker: ticker,
+		bids:   btree.NewG(bookDegree, priority),
+		asks:   btree.NewG(bookDegree, priority),
+		orders: make(map[int64]*bookOrder, 16),
+	}
+}
+
+// better price first, then older order.
+// sell prices are negative, so greater price is better for both sides
+func priority(a, b *bookOrder) bool {
+	if a.deal.Price != b.deal.Price {
+		return a.deal.Price > b.deal.Price
+	}
+	return a.seq < b.seq
+}
+
+func (b *orderBook) side(o *bookOrder) *btree.BTreeG[*bookOrder] {
+	if o.deal.Price > 0 {
+		return b.bids
+	}
+	return b.asks
+}
+
+func (b *orderBook) add(deal *exchange.Deal) *bookOrder {
+	b.seq++
+	o := &bookOrder{
+		deal: deal,
+		seq:  b.seq,
+	}
+	// order with display volume not less than its volume is a plain order
+	if deal.DisplayVolume > 0 && deal.DisplayVolume < deal.Volume {
+		o.peak = deal.DisplayVolume
+		o.shown = deal.DisplayVolume
+	}
+
+	b.orders[deal.ID] = o
+	b.side(o).ReplaceOrInsert(o)
+	b.countVolume(o, 1)
+	return o
+}
+
+func (b *orderBook) remove(id int64) bool {
+	o, ok := b.orders[id]
+	if !ok {
+		return false
+	}
+	b.countVolume(o, -1)
+	b.side(o).Delete(o)
+	delete(b.orders, id)
+	return true
+}
+
+// fill applies execution report to resting order, completed order leaves the book
+// and iceberg with new peak goes behind other orders at its price
+func (b *orderBook) fill(o *bookOrder, deal *exchange.Deal) {
+	b.countVolume(o, -1)
+	fillOrder(o.deal, deal)
+	refreshed := o.fillIceberg(deal.Volume)
+
+	if o.deal.Volume == 0 {
+		b.side(o).Delete(o)
+		delete(b.orders, o.deal.ID)
+		return
+	}
+	if refreshed {
+		b.requeue(o)
+	}
+	b.countVolume(o, 1)
+}
+
+// requeue gives order the last time priority at its price
+func (b *orderBook) requeue(o *bookOrder) {
+	tree := b.side(o)
+	tree.Delete(o)
+	b.seq++
+	o.seq = b.seq
+	tree.ReplaceOrInsert(o)
+}
+
+// eligible returns orders of one side which trade at price last, in price-time priority
+func (b *orderBook) eligible(last float32, buy bool) []*bookOrder {
+	res := make([]*bookOrder, 0, 4)
+	if buy {
+		b.bids.Ascend(func(o *bookOrder) bool {
+			if o.deal.Price < last {
+				return false
+			}
+			res = append(res, o)
+			return true
+		})
+	} else {
+		b.asks.Ascend(func(o *bookOrder) bool {
+			if -o.deal.Price > last {
+				return false
+			}
+			res = append(res, o)
+			return true
+		})
+	}
+	return res
+}
+
+// each calls f for bids then asks in priority order
+func (b *orderBook) each(f func(o *bookOrder)) {
+	for _, tree := range []*btree.BTreeG[*bookOrder]{b.bids, b.asks} {
+		tree.Ascend(func(o *bookOrder) bool {
+			f(o)
+			return true
+		})
+	}
+}
+
+// best resting buy and sell prices, 0 if side is empty
+func (b *orderBook) bestBidAsk() (float32, float32) {
+	var bid, ask float32
+	if o, ok := b.bids.Min(); ok {
+		bid = o.deal.Price
+	}
+	if o, ok := b.asks.Min(); ok {
+		ask = -o.deal.Price
+	}
+	return bid, ask
+}
+
+// depth aggregates displayed volume by price, bids and asks from the best one
+func (b *orderBook) depth() ([]DepthLevel, []DepthLevel) {
+	return levels(b.bids), levels(b.asks)
+}
+
+func levels(tree *btree.BTreeG[*bookOrder]) []DepthLevel {
+	res := make([]DepthLevel, 0, 4)
+	tree.Ascend(func(o *bookOrder) bool {
+		price := o.deal.Price
+		if price < 0 {
+			price = -price
+		}
+		if n := len(res); n > 0 && res[n-1].Price == price {
+			res[n-1].Volume += o.displayed()
+		} else {
+			res = append(res, DepthLevel{Price: price, Volume: o.displayed()})
+		}
+		return true
+	})
+	return res
+}
+
+func (b *orderBook) countVolume(o *bookOrder, sign int64) {
+	if o.deal.Price > 0 {
+		b.bidVolume += sign * int64(o.displayed())
+	} else {
+		b.askVolume += sign * int64(o.displayed())
+	}
+}
+
+func (b *orderBook) updateMetrics() {
+	metrics.BookDepth.WithLabelValues(b.ticker).Set(float64(len(b.orders)))
+	metrics.BookVolume.WithLabelValues(b.ticker, metrics.SideBid).Set(float64(b.bidVolume))
+	metrics.BookVolume.WithLabelValues(b.ticker, metrics.SideAsk).Set(float64(b.askVolume))
+}
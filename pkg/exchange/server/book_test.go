@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestExchange has everything for orders and matching but no grpc server and feed
+func newTestExchange(tb testing.TB) *ExchangeSrv {
+	e := &ExchangeSrv{
+		BufferSize:   100,
+		Logger:       zap.NewNop().Sugar(),
+		Tape:         NewTradeTape(100),
+		shardsLock:   &sync.RWMutex{},
+		shards:       make(map[string]*shard),
+		orderShards:  &sync.Map{},
+		ChannelsLock: &sync.RWMutex{},
+		Channels:     make(map[int64]chan *exchange.Deal),
+		Journal:      NewJournal(),
+		stopping:     make(chan struct{}),
+	}
+	tb.Cleanup(func() { close(e.stopping) })
+	return e
+}
+
+func bookIDs(orders []*bookOrder) []int64 {
+	ids := make([]int64, 0, len(orders))
+	for _, o := range orders {
+		ids = append(ids, o.deal.ID)
+	}
+	return ids
+}
+
+func TestBookPriority(t *testing.T) {
+	b := newOrderBook("SPFB.RTS")
+	for _, d := range []*exchange.Deal{
+		{ID: 1, Volume: 1, Price: 100},
+		{ID: 2, Volume: 1, Price: 101},
+		{ID: 3, Volume: 1, Price: 100},
+		{ID: 4, Volume: 1, Price: -105},
+		{ID: 5, Volume: 1, Price: -103},
+		{ID: 6, Volume: 1, Price: -105},
+		{ID: 7, Volume: 1, Price: 99},
+	} {
+		b.add(d)
+	}
+
+	if have := bookIDs(b.eligible(100, true)); !reflect.DeepEqual(have, []int64{2, 1, 3}) {
+		t.Fatalf("buys dont match: have %v", have)
+	}
+	if have := bookIDs(b.eligible(105, false)); !reflect.DeepEqual(have, []int64{5, 4, 6}) {
+		t.Fatalf("sells dont match: have %v", have)
+	}
+	if bid, ask := b.bestBidAsk(); bid != 101 || ask != 103 {
+		t.Fatalf("best bid/ask dont match: have %v/%v", bid, ask)
+	}
+
+	if !b.remove(2) || b.remove(2) {
+		t.Fatalf("order must be removed once")
+	}
+	b.requeue(b.orders[1])
+	if have := bookIDs(b.eligible(99, true)); !reflect.DeepEqual(have, []int64{3, 1, 7}) {
+		t.Fatalf("buys after cancel and requeue dont match: have %v", have)
+	}
+
+	bids, asks := b.depth()
+	if !reflect.DeepEqual(bids, []DepthLevel{{100, 2}, {99, 1}}) || !reflect.DeepEqual(asks, []DepthLevel{{103, 1}, {105, 2}}) {
+		t.Fatalf("depth dont match: have %v %v", bids, asks)
+	}
+	if b.bidVolume != 3 || b.askVolume != 3 {
+		t.Fatalf("displayed volume dont match: have %v/%v", b.bidVolume, b.askVolume)
+	}
+}
+
+// insert and cancel of one order next to n resting ones
+func BenchmarkBookAddRemove(b *testing.B) {
+	for _, n := range []int{100, 10000, 1000000} {
+		b.Run(fmt.Sprintf("resting=%v", n), func(b *testing.B) {
+			book := newOrderBook("SPFB.RTS")
+			for i := 0; i < n; i++ {
+				book.add(&exchange.Deal{ID: int64(i), Volume: 1, Price: float32(100 + i%1000)})
+			}
+			deal := &exchange.Deal{ID: -1, Volume: 1, Price: 500}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				book.add(deal)
+				book.remove(deal.ID)
+			}
+		})
+	}
+}
+
+// ticks of all instruments are fed in turn like the feed does, each tick fills one order.
+// ns/op is per tick and includes journal and Results of the fill, with record=true journal file
+// gets the tick and the fill too. Shards match in parallel, so compare instruments at several GOMAXPROCS,
+// like -cpu 1,4,8: with one core ns/op stays flat and only shows dispatch overhead,
+// with more cores it goes down until the feed goroutine is the limit.
+func BenchmarkMatchingShards(b *testing.B) {
+	for _, record := range []bool{false, true} {
+		for _, instruments := range []int{1, 2, 4, 8, 16} {
+			b.Run(fmt.Sprintf("record=%v/instruments=%v", record, instruments), func(b *testing.B) {
+				benchmarkMatchingShards(b, instruments, record)
+			})
+		}
+	}
+}
+
+func benchmarkMatchingShards(b *testing.B, instruments int, record bool) {
+	e := newTestExchange(b)
+	if record {
+		err := e.Journal.Record(filepath.Join(b.TempDir(), "journal.bin"), zap.NewNop().Sugar())
+		if err != nil {
+			b.Fatalf("cant record journal: %v", err)
+		}
+		defer e.Journal.Close()
+	}
+
+	results, _ := e.GetBrokerChannel(&exchange.BrokerID{ID: 1})
+	go func() {
+		for range results {
+		}
+	}()
+	defer close(results)
+
+	shards := make([]*shard, instruments)
+	for i := range shards {
+		ticker := fmt.Sprintf("TICKER%v", i)
+		for j := 0; j < 1000; j++ {
+			_, err := e.Create(context.Background(), &exchange.Deal{BrokerID: 1, Ticker: ticker, Volume: 1 << 30, Price: float32(90 + j%20)})
+			if err != nil {
+				b.Fatalf("cant create order: %v", err)
+			}
+		}
+		shards[i] = e.shardFor(ticker)
+	}
+	ticks := make([]tickers.Tick, instruments)
+	for i := range ticks {
+		ticks[i] = tickers.Tick{Ticker: shards[i].book.ticker, Timestamp: time.Now(), Last: 109, Vol: 1}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s, t := shards[i%instruments], ticks[i%instruments]
+		s.do(func() { s.match(t) })
+	}
+	for _, s := range shards {
+		s.call(func() {})
+	}
+}
+
+// resultsStream hands every report to test and waits until it is released
+type resultsStream struct {
+	grpc.ServerStream
+	ctx     context.Context
+	entered chan *exchange.Deal
+	release chan struct{}
+}
+
+func (s *resultsStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *resultsStream) Send(deal *exchange.Deal) error {
+	s.entered <- deal
+	<-s.release
+	return nil
+}
+
+func TestResultsOverflow(t *testing.T) {
+	e := newTestExchange(t)
+	e.BufferSize = 1
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// broker does not read reports while shard fills its orders
+	stuck := &resultsStream{ctx: ctx, entered: make(chan *exchange.Deal), release: make(chan struct{})}
+	done := make(chan error, 1)
+	go func() { done <- e.Results(&exchange.BrokerID{ID: 123}, stuck) }()
+
+	for i := 0; i < 4; i++ {
+		_, err := e.Create(ctx, &exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 1, Price: 100})
+		if err != nil {
+			t.Fatalf("cant create order: %v", err)
+		}
+	}
+	s := e.shardFor("SPFB.RTS")
+	filled := make(chan struct{})
+	go func() {
+		s.call(func() { s.match(tickers.Tick{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 100, Vol: 4}) })
+		close(filled)
+	}()
+	select {
+	case <-filled:
+	case <-time.After(time.Second):
+		t.Fatalf("shard is blocked by broker which does not read results")
+	}
+
+	// stream is broken on dropped report
+	got := make(map[int64]bool, 4)
+	lastSeq := e.Journal.LastSeq() - 4
+	for broken := false; !broken; {
+		select {
+		case d := <-stuck.entered:
+			got[d.ExecSeq] = true
+			lastSeq = d.ExecSeq
+			stuck.release <- struct{}{}
+		case err := <-done:
+			if status.Code(err) != codes.ResourceExhausted {
+				t.Fatalf("expected results to be broken on overflow, got %v", err)
+			}
+			broken = true
+		}
+	}
+
+	// broker resumes after the last report it got and gets the rest once
+	resumed := &resultsStream{ctx: ctx, entered: make(chan *exchange.Deal), release: make(chan struct{})}
+	go e.Results(&exchange.BrokerID{ID: 123, LastExecSeq: lastSeq}, resumed)
+	for len(got) < 4 {
+		select {
+		case d := <-resumed.entered:
+			if got[d.ExecSeq] {
+				t.Fatalf("report %v is sent twice", d)
+			}
+			got[d.ExecSeq] = true
+			resumed.release <- struct{}{}
+		case <-time.After(time.Second):
+			t.Fatalf("reports are lost, got %v", got)
+		}
+	}
+}
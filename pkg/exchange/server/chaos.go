@@ -0,0 +1,202 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/metrics"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Chaos injects faults into Results and Statistic streams and Create calls,
+// so broker can be checked against exchange which loses, delays and repeats messages.
+// Zero settings inject nothing, they are changed at runtime by SetChaos rpc.
+type Chaos struct {
+	lock     *sync.Mutex
+	settings *exchange.ChaosSettings
+	rand     *rand.Rand
+}
+
+// fault decided for one message
+type chaosFault struct {
+	abort     bool
+	drop      bool
+	delay     time.Duration
+	duplicate bool
+}
+
+func NewChaos(settings *exchange.ChaosSettings) (*Chaos, error) {
+	c := &Chaos{lock: &sync.Mutex{}}
+	if settings == nil {
+		settings = &exchange.ChaosSettings{}
+	}
+	err := c.Set(settings)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ValidateChaos checks probabilities are in [0, 1] and delay is not negative
+func ValidateChaos(settings *exchange.ChaosSettings) error {
+	for name, p := range map[string]float64{
+		"delay probability":         settings.DelayProbability,
+		"drop probability":          settings.DropProbability,
+		"duplicate probability":     settings.DuplicateProbability,
+		"create reject probability": settings.CreateRejectProbability,
+		"abort probability":         settings.AbortProbability,
+	} {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("%v must be in [0, 1], got %v", name, p)
+		}
+	}
+	if settings.MaxDelayMs < 0 {
+		return fmt.Errorf("max delay must not be negative, got %v", settings.MaxDelayMs)
+	}
+	return nil
+}
+
+// Set replaces settings and restarts random sequence from their seed
+func (c *Chaos) Set(settings *exchange.ChaosSettings) error {
+	err := ValidateChaos(settings)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.settings = proto.Clone(settings).(*exchange.ChaosSettings)
+	c.rand = rand.New(rand.NewSource(settings.Seed))
+	return nil
+}
+
+func (c *Chaos) Settings() *exchange.ChaosSettings {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return proto.Clone(c.settings).(*exchange.ChaosSettings)
+}
+
+// lock must be held
+func (c *Chaos) roll(p float64) bool {
+	return p > 0 && c.rand.Float64() < p
+}
+
+func (c *Chaos) rejectCreate() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.roll(c.settings.CreateRejectProbability)
+}
+
+// next decides what happens to the next stream message, fills may be duplicated
+func (c *Chaos) next(fill bool) chaosFault {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	s := c.settings
+	if c.roll(s.AbortProbability) {
+		return chaosFault{abort: true}
+	}
+	if c.roll(s.DropProbability) {
+		return chaosFault{drop: true}
+	}
+	var f chaosFault
+	if s.MaxDelayMs > 0 && c.roll(s.DelayProbability) {
+		f.delay = time.Duration(c.rand.Int63n(int64(s.MaxDelayMs))+1) * time.Millisecond
+	}
+	f.duplicate = fill && c.roll(s.DuplicateProbability)
+	return f
+}
+
+func (c *Chaos) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if info.FullMethod == fullMethod("Create") && c.rejectCreate() {
+		metrics.ChaosFaults.WithLabelValues(metrics.FaultReject).Inc()
+		return nil, status.Error(codes.Unavailable, "chaos: order is rejected")
+	}
+	return handler(ctx, req)
+}
+
+func (c *Chaos) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if info.FullMethod != fullMethod("Results") && info.FullMethod != fullMethod("Statistic") {
+		return handler(srv, ss)
+	}
+
+	// handlers stop on context, so abort cancels it and the call ends with error
+	ctx, abort := context.WithCancel(ss.Context())
+	defer abort()
+	cs := &chaosStream{
+		ServerStream: ss,
+		chaos:        c,
+		ctx:          ctx,
+		abort:        abort,
+	}
+	err := handler(srv, cs)
+	if atomic.LoadInt32(&cs.aborted) != 0 {
+		return status.Error(codes.Unavailable, "chaos: stream is aborted")
+	}
+	return err
+}
+
+type chaosStream struct {
+	grpc.ServerStream
+	chaos   *Chaos
+	ctx     context.Context
+	abort   context.CancelFunc
+	aborted int32 // atomic, Statistic bars are sent by other goroutine than handler
+}
+
+func (s *chaosStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *chaosStream) SendMsg(m interface{}) error {
+	_, fill := m.(*exchange.Deal)
+	f := s.chaos.next(fill)
+
+	switch {
+	case f.abort:
+		metrics.ChaosFaults.WithLabelValues(metrics.FaultAbort).Inc()
+		atomic.StoreInt32(&s.aborted, 1)
+		s.abort()
+		return status.Error(codes.Unavailable, "chaos: stream is aborted")
+	case f.drop:
+		metrics.ChaosFaults.WithLabelValues(metrics.FaultDrop).Inc()
+		return nil
+	}
+
+	if f.delay > 0 {
+		metrics.ChaosFaults.WithLabelValues(metrics.FaultDelay).Inc()
+		timer := time.NewTimer(f.delay)
+		select {
+		case <-timer.C:
+		case <-s.ctx.Done():
+			timer.Stop()
+			return s.ctx.Err()
+		}
+	}
+
+	err := s.ServerStream.SendMsg(m)
+	if err != nil || !f.duplicate {
+		return err
+	}
+	metrics.ChaosFaults.WithLabelValues(metrics.FaultDuplicate).Inc()
+	return s.ServerStream.SendMsg(m)
+}
+
+// включает, меняет или выключает режим сбоев
+func (e *ExchangeSrv) SetChaos(ctx context.Context, settings *exchange.ChaosSettings) (*exchange.ChaosSettings, error) {
+	err := e.Chaos.Set(settings)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	e.Logger.Warnw("Chaos settings changed", "settings", settings)
+	return e.Chaos.Settings(), nil
+}
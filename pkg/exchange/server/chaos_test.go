@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestChaos(t *testing.T) {
+	ts := &TickersSourceTest{chLock: &sync.RWMutex{}}
+	clk := clock.NewManual(simStart)
+	ctx, stop := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- StartWithConfig(ctx, Config{
+			ListenAddr: listenAddr,
+			BufferSize: 100,
+			Clock:      clk,
+			Chaos:      &exchange.ChaosSettings{CreateRejectProbability: 1},
+		}, ts)
+	}()
+	defer func() {
+		stop()
+		<-stopped
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	c := exchange.NewExchangeClient(conn)
+
+	order := &exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 5, Price: 100}
+	_, err := c.Create(context.Background(), order, grpc.WaitForReady(true))
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected rejected order, got %v", err)
+	}
+
+	_, err = c.SetChaos(context.Background(), &exchange.ChaosSettings{DropProbability: 2})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected invalid settings error, got %v", err)
+	}
+	set, err := c.SetChaos(context.Background(), &exchange.ChaosSettings{DuplicateProbability: 1, Seed: 7})
+	if err != nil || set.DuplicateProbability != 1 || set.CreateRejectProbability != 0 {
+		t.Fatalf("unexpected settings %v: %v", set, err)
+	}
+
+	results, err := c.Results(context.Background(), &exchange.BrokerID{ID: 123})
+	if err != nil {
+		t.Fatalf("cant get results stream: %v", err)
+	}
+	// candles and trader
+	waitFeeds(t, ts, 2)
+	id, err := c.Create(context.Background(), order)
+	if err != nil {
+		t.Fatalf("cant create order: %v", err)
+	}
+	ts.Run([]tickers.Tick{{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 100, Vol: 1}})
+
+	// every fill comes twice
+	for i := 0; i < 2; i++ {
+		fill, err := results.Recv()
+		if err != nil {
+			t.Fatalf("cant receive fill: %v", err)
+		}
+		if fill.ID != id.ID || fill.LeavesVolume != 4 {
+			t.Fatalf("unexpected fill %v", fill)
+		}
+	}
+
+	// stream breaks on the next message
+	_, err = c.SetChaos(context.Background(), &exchange.ChaosSettings{AbortProbability: 1})
+	if err != nil {
+		t.Fatalf("cant set chaos: %v", err)
+	}
+	ts.Run([]tickers.Tick{{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 100, Vol: 1}})
+	_, err = results.Recv()
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected aborted stream, got %v", err)
+	}
+
+	// broker resumes after the last fill it got and receives the lost one from journal
+	_, err = c.SetChaos(context.Background(), &exchange.ChaosSettings{})
+	if err != nil {
+		t.Fatalf("cant turn chaos off: %v", err)
+	}
+	resumed, err := c.Results(context.Background(), &exchange.BrokerID{ID: 123, LastExecSeq: 2})
+	if err != nil {
+		t.Fatalf("cant resume results: %v", err)
+	}
+	lost, err := resumed.Recv()
+	if err != nil {
+		t.Fatalf("cant receive lost fill: %v", err)
+	}
+	if lost.ID != id.ID || lost.LeavesVolume != 3 {
+		t.Fatalf("unexpected lost fill %v", lost)
+	}
+
+	// statistic bars go through conflation sender, abort there ends the stream too
+	stat, err := c.Statistic(context.Background(), &exchange.BrokerID{ID: 123})
+	if err != nil {
+		t.Fatalf("cant get statistic stream: %v", err)
+	}
+	// candles, trader and statistic
+	waitFeeds(t, ts, 3)
+	clk.WaitTickers(2)
+	_, err = c.SetChaos(context.Background(), &exchange.ChaosSettings{AbortProbability: 1})
+	if err != nil {
+		t.Fatalf("cant set chaos: %v", err)
+	}
+	ts.Run([]tickers.Tick{{Ticker: "SPFB.Si", Timestamp: simStart, Last: 65, Vol: 1}})
+	clk.Advance(time.Second)
+	_, err = stat.Recv()
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected aborted statistic stream, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/KSerditov/Trading/api/exchange"
+)
+
+// conflater keeps bars not yet sent to one Statistic subscriber, only the latest one per ticker.
+// Slow subscriber gets fresh state of every ticker instead of falling further behind.
+type conflater struct {
+	lock    *sync.Mutex
+	pending map[string]*exchange.OHLCV
+	order   []string      // tickers with pending bar, oldest first
+	ready   chan struct{} // signalled when a bar is put
+}
+
+func newConflater() *conflater {
+	return &conflater{
+		lock:    &sync.Mutex{},
+		pending: make(map[string]*exchange.OHLCV, 2),
+		order:   make([]string, 0, 2),
+		ready:   make(chan struct{}, 1),
+	}
+}
+
+// put replaces pending bar of the ticker, it keeps its place in the queue
+func (c *conflater) put(bar *exchange.OHLCV) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, conflated := c.pending[bar.Ticker]
+	if conflated {
+		bar.Conflated = true
+	} else {
+		c.order = append(c.order, bar.Ticker)
+	}
+	c.pending[bar.Ticker] = bar
+
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+	return conflated
+}
+
+// pop returns the oldest pending bar, nil if there is none
+func (c *conflater) pop() *exchange.OHLCV {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if len(c.order) == 0 {
+		return nil
+	}
+	ticker := c.order[0]
+	c.order = c.order[1:]
+	bar := c.pending[ticker]
+	delete(c.pending, ticker)
+	return bar
+}
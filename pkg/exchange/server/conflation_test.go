@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"google.golang.org/grpc"
+)
+
+// statisticStream blocks every Send until it is released
+type statisticStream struct {
+	grpc.ServerStream
+	entered chan *exchange.OHLCV
+	release chan struct{}
+}
+
+func (s *statisticStream) Send(bar *exchange.OHLCV) error {
+	s.entered <- bar
+	<-s.release
+	return nil
+}
+
+func TestConflation(t *testing.T) {
+	e := newTestExchange(t)
+	stream := &statisticStream{
+		entered: make(chan *exchange.OHLCV),
+		release: make(chan struct{}),
+	}
+	bars := newConflater()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- e.sendBars(ctx, stream, bars) }()
+
+	bars.put(&exchange.OHLCV{Ticker: "SPFB.RTS", Time: 1})
+	if bar := <-stream.entered; bar.Time != 1 || bar.Conflated {
+		t.Fatalf("unexpected first bar %v", bar)
+	}
+
+	// subscriber is stuck on the first bar, RTS bars replace each other, Si keeps its place after RTS
+	bars.put(&exchange.OHLCV{Ticker: "SPFB.RTS", Time: 2})
+	bars.put(&exchange.OHLCV{Ticker: "SPFB.Si", Time: 2})
+	if !bars.put(&exchange.OHLCV{Ticker: "SPFB.RTS", Time: 3}) {
+		t.Fatalf("pending bar must be conflated")
+	}
+	stream.release <- struct{}{}
+
+	want := []*exchange.OHLCV{
+		{Ticker: "SPFB.RTS", Time: 3, Conflated: true},
+		{Ticker: "SPFB.Si", Time: 2},
+	}
+	for _, w := range want {
+		bar := <-stream.entered
+		if bar.Ticker != w.Ticker || bar.Time != w.Time || bar.Conflated != w.Conflated {
+			t.Fatalf("unexpected bar\nhave %v\nwant %v", bar, w)
+		}
+		stream.release <- struct{}{}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
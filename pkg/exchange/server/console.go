@@ -0,0 +1,79 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/console"
+)
+
+// Brokers lists brokers with Results channel or resting orders
+func (e *ExchangeSrv) Brokers() []console.Broker {
+	brokers := make(map[int64]*console.Broker, 4)
+	broker := func(id int64) *console.Broker {
+		b, ok := brokers[id]
+		if !ok {
+			b = &console.Broker{ID: id}
+			brokers[id] = b
+		}
+		return b
+	}
+
+	e.ChannelsLock.RLock()
+	for id, c := range e.Channels {
+		broker(id).PendingResults = len(c)
+	}
+	e.ChannelsLock.RUnlock()
+
+	for _, s := range e.allShards() {
+		s.call(func() {
+			s.book.each(func(o *bookOrder) {
+				broker(int64(o.deal.BrokerID)).Orders++
+			})
+		})
+	}
+
+	res := make([]console.Broker, 0, len(brokers))
+	for _, b := range brokers {
+		res = append(res, *b)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
+
+// Books returns top depth displayed levels of every ticker book
+func (e *ExchangeSrv) Books(depth int) []console.Book {
+	res := make([]console.Book, 0, 4)
+	for _, s := range e.allShards() {
+		var bids, asks []DepthLevel
+		book := console.Book{Ticker: s.book.ticker}
+		s.call(func() {
+			bids, asks = s.book.depth()
+			book.Orders = s.book.bids.Len() + s.book.asks.Len()
+		})
+		book.Bids = consoleLevels(bids, depth)
+		book.Asks = consoleLevels(asks, depth)
+		res = append(res, book)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Ticker < res[j].Ticker
+	})
+	return res
+}
+
+func consoleLevels(levels []DepthLevel, depth int) []console.Level {
+	if len(levels) > depth {
+		levels = levels[:depth]
+	}
+	res := make([]console.Level, 0, len(levels))
+	for _, l := range levels {
+		res = append(res, console.Level{Price: l.Price, Volume: l.Volume})
+	}
+	return res
+}
+
+func (e *ExchangeSrv) RecentTrades() []*exchange.Trade {
+	return e.Tape.Recent()
+}
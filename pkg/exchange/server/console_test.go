@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/console"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+)
+
+func TestConsoleSnapshot(t *testing.T) {
+	e := newTestExchange(t)
+	e.Channels[7] = make(chan *exchange.Deal, 10)
+
+	for _, d := range []*exchange.Deal{
+		{BrokerID: 7, ClientID: 1, Ticker: "SPFB.RTS", Volume: 5, Price: 100},
+		{BrokerID: 7, ClientID: 1, Ticker: "SPFB.RTS", Volume: 2, Price: 99},
+		{BrokerID: 7, ClientID: 2, Ticker: "SPFB.RTS", Volume: 3, Price: -101},
+		{BrokerID: 8, ClientID: 1, Ticker: "SPFB.SI", Volume: 1, Price: 70},
+	} {
+		_, err := e.Create(context.Background(), d)
+		if err != nil {
+			t.Fatalf("cant create order: %v", err)
+		}
+	}
+	s := e.shardFor("SPFB.RTS")
+	s.call(func() { s.match(tickers.Tick{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 100, Vol: 1}) })
+
+	ops := &console.Console{
+		Exchange: e,
+		Depth:    1,
+		Clock:    clock.NewManual(simStart),
+	}
+	err := ops.Init()
+	if err != nil {
+		t.Fatalf("cant init console: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	ops.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/snapshot", nil))
+	have := &console.Snapshot{}
+	err = json.Unmarshal(rec.Body.Bytes(), have)
+	if err != nil {
+		t.Fatalf("cant decode snapshot %q: %v", rec.Body.String(), err)
+	}
+
+	brokers := []console.Broker{{ID: 7, PendingResults: 1, Orders: 3}, {ID: 8, Orders: 1}}
+	if !reflect.DeepEqual(have.Brokers, brokers) {
+		t.Fatalf("brokers dont match\nhave %+v\nwant %+v", have.Brokers, brokers)
+	}
+	books := []console.Book{
+		{Ticker: "SPFB.RTS", Orders: 3, Bids: []console.Level{{Price: 100, Volume: 4}}, Asks: []console.Level{{Price: 101, Volume: 3}}},
+		{Ticker: "SPFB.SI", Orders: 1, Bids: []console.Level{{Price: 70, Volume: 1}}, Asks: []console.Level{}},
+	}
+	if !reflect.DeepEqual(have.Books, books) {
+		t.Fatalf("books dont match\nhave %+v\nwant %+v", have.Books, books)
+	}
+	if len(have.Trades) != 1 || have.Trades[0].Volume != 1 || have.Trades[0].Aggressor != exchange.Side_BUY {
+		t.Fatalf("unexpected trades %v", have.Trades)
+	}
+	if have.Replay != nil {
+		t.Fatalf("replay is shown without source: %+v", have.Replay)
+	}
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// resolve gives ticker the order goes to, front series for continuous ticker
+func (e *ExchangeSrv) resolve(ticker string) (string, error) {
+	if e.Schedule == nil {
+		return ticker, nil
+	}
+	return e.Schedule.Resolve(ticker, e.Clock.Now())
+}
+
+// serveExpiry settles series whose last trading time passed by exchange clock, checked every second
+func (e *ExchangeSrv) serveExpiry() {
+	ticker := e.Clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			// tick may be late, expiry goes by time now
+			e.expire(e.Clock.Now())
+		case <-e.stopping:
+			return
+		}
+	}
+}
+
+func (e *ExchangeSrv) expire(now time.Time) {
+	for _, series := range e.Schedule.All() {
+		if !series.Expired(now) {
+			continue
+		}
+		s := e.shardFor(series.Ticker)
+		s.call(func() {
+			if s.expired {
+				return
+			}
+			s.expire(now)
+			e.Logger.Infow("Series expired", "ticker", series.Ticker, "settlement", s.settlement)
+		})
+	}
+}
+
+// expire cancels resting orders and settles client positions at the last price of series,
+// called on shard goroutine once
+func (s *shard) expire(now time.Time) {
+	e := s.srv
+	s.expired = true
+	s.settlement = s.lastPrice
+	if s.settlement == 0 {
+		e.Logger.Warnw("Series expired without trades, positions are settled at zero", "ticker", s.book.ticker)
+	}
+
+	orders := make([]*bookOrder, 0, len(s.book.orders))
+	s.book.each(func(o *bookOrder) {
+		orders = append(orders, o)
+	})
+	for _, o := range orders {
+		s.expireOrder(o, now)
+	}
+
+	keys := make([]positionKey, 0, len(s.positions))
+	for key := range s.positions {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].brokerID != keys[j].brokerID {
+			return keys[i].brokerID < keys[j].brokerID
+		}
+		return keys[i].clientID < keys[j].clientID
+	})
+	for _, key := range keys {
+		pos := s.positions[key]
+		// client closes position with exchange at settlement price
+		side := exchange.Side_SELL
+		if pos < 0 {
+			side = exchange.Side_BUY
+		}
+		report := &exchange.Deal{
+			BrokerID: key.brokerID,
+			ClientID: key.clientID,
+			Ticker:   s.book.ticker,
+			Time:     int32(now.Unix()),
+			Price:    s.settlement,
+			Volume:   int32(pos),
+			Status:   exchange.OrderStatus_SETTLED,
+		}
+		e.Journal.Append(exchange.JournalEntryType_POSITION_SETTLED, report, side)
+		s.report(report)
+	}
+	s.positions = make(map[positionKey]int64)
+	s.book.updateMetrics()
+
+	if e.ClearingHouse != nil {
+		e.ClearingHouse.Expire(s.book.ticker, s.settlement)
+	}
+}
+
+// expireOrder removes order from book and reports its cancelled rest
+func (s *shard) expireOrder(o *bookOrder, now time.Time) {
+	e := s.srv
+	order := o.deal
+	s.book.remove(order.ID)
+	e.orderShards.Delete(order.ID)
+
+	report := &exchange.Deal{
+		ID:           order.ID,
+		BrokerID:     order.BrokerID,
+		ClientID:     order.ClientID,
+		Ticker:       order.Ticker,
+		Time:         int32(now.Unix()),
+		Price:        order.Price,
+		CumVolume:    order.CumVolume,
+		LeavesVolume: order.Volume,
+		AvgPrice:     order.AvgPrice,
+		Status:       exchange.OrderStatus_EXPIRED,
+	}
+	e.Journal.Append(exchange.JournalEntryType_ORDER_EXPIRED, report, exchange.Side_SIDE_UNKNOWN)
+	s.report(report)
+}
+
+// серии фьючерсов, по тикеру серии - только она, по непрерывному тикеру - все его серии
+func (e *ExchangeSrv) Contracts(ctx context.Context, req *exchange.ContractsRequest) (*exchange.ContractsResponse, error) {
+	if e.Schedule == nil {
+		return nil, status.Error(codes.Unimplemented, "no futures series")
+	}
+
+	now := e.Clock.Now()
+	res := &exchange.ContractsResponse{
+		Contracts: make([]*exchange.Contract, 0, 4),
+	}
+	for _, series := range e.Schedule.All() {
+		if req.Ticker != "" && req.Ticker != series.Ticker && req.Ticker != series.Underlying {
+			continue
+		}
+		front, _ := e.Schedule.Front(series.Underlying, now)
+		c := &exchange.Contract{
+			Ticker:          series.Ticker,
+			Underlying:      series.Underlying,
+			LastTradingTime: int32(series.LastTrading.Unix()),
+			Front:           front.Ticker == series.Ticker,
+			Expired:         series.Expired(now),
+		}
+		if s := e.existingShard(series.Ticker); s != nil {
+			s.call(func() {
+				if s.expired {
+					c.SettlementPrice = s.settlement
+				}
+			})
+		}
+		res.Contracts = append(res.Contracts, c)
+	}
+	return res, nil
+}
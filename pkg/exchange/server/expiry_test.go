@@ -0,0 +1,117 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/contracts"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSeriesExpiry(t *testing.T) {
+	schedule, err := contracts.NewSchedule([]contracts.Series{
+		{Ticker: "RTS-6.19", Underlying: "SPFB.RTS", LastTrading: simStart.Add(10 * time.Second)},
+		{Ticker: "RTS-9.19", Underlying: "SPFB.RTS", LastTrading: simStart.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("cant build schedule: %v", err)
+	}
+	clk := clock.NewManual(simStart)
+	e := newTestExchange(t)
+	e.Clock = clk
+	e.Schedule = schedule
+	results, _ := e.GetBrokerChannel(&exchange.BrokerID{ID: 123})
+
+	create := func(ticker string, price float32, volume int32) (*exchange.DealID, error) {
+		return e.Create(context.Background(), &exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: ticker, Price: price, Volume: volume})
+	}
+	next := func() *exchange.Deal {
+		t.Helper()
+		select {
+		case d := <-results:
+			return d
+		case <-time.After(time.Second):
+			t.Fatalf("no execution report")
+			return nil
+		}
+	}
+
+	buy, err := create("SPFB.RTS", 100, 5)
+	if err != nil {
+		t.Fatalf("cant create order: %v", err)
+	}
+	sell, err := create("SPFB.RTS", -120, 2)
+	if err != nil {
+		t.Fatalf("cant create order: %v", err)
+	}
+	s := e.shardFor("RTS-6.19")
+	s.call(func() {
+		s.match(tickers.Tick{Ticker: "RTS-6.19", Timestamp: simStart.Add(time.Second), Last: 100, Vol: 3})
+	})
+	if fill := next(); fill.ID != buy.ID || fill.Ticker != "RTS-6.19" || fill.Volume != 3 {
+		t.Fatalf("continuous ticker order must trade as front series: %v", fill)
+	}
+	if bid, _ := e.BestBidAsk("SPFB.RTS"); bid != 100 {
+		t.Fatalf("continuous ticker must show front series book, bid %v", bid)
+	}
+
+	go e.serveExpiry()
+	clk.WaitTickers(1)
+	clk.Advance(10 * time.Second)
+
+	reports := map[int64]*exchange.Deal{}
+	for i := 0; i < 2; i++ {
+		d := next()
+		if d.Status != exchange.OrderStatus_EXPIRED {
+			t.Fatalf("expected expired order, got %v", d)
+		}
+		reports[d.ID] = d
+	}
+	if d := reports[buy.ID]; d == nil || d.LeavesVolume != 2 || d.CumVolume != 3 {
+		t.Fatalf("buy expiry report dont match: %v", d)
+	}
+	if d := reports[sell.ID]; d == nil || d.LeavesVolume != 2 || d.CumVolume != 0 {
+		t.Fatalf("sell expiry report dont match: %v", d)
+	}
+	settled := next()
+	if settled.Status != exchange.OrderStatus_SETTLED || settled.ClientID != 1 || settled.Volume != 3 || settled.Price != 100 || settled.ExecSeq == 0 {
+		t.Fatalf("settlement report dont match: %v", settled)
+	}
+	if oi := e.OpenInterest("RTS-6.19"); oi != 0 {
+		t.Fatalf("positions must be closed, open interest %v", oi)
+	}
+
+	missed, _ := e.Journal.Fills(123, 0)
+	if len(missed) != 4 || missed[3].Status != exchange.OrderStatus_SETTLED {
+		t.Fatalf("expiry reports must be resumable from journal: %v", missed)
+	}
+
+	_, err = create("RTS-6.19", 100, 1)
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition for expired series, got %v", err)
+	}
+	id, err := create("SPFB.RTS", 100, 1)
+	if err != nil {
+		t.Fatalf("cant create order: %v", err)
+	}
+	orders, err := e.Orders(context.Background(), &exchange.OrdersRequest{BrokerID: 123, ID: id.ID})
+	if err != nil || orders.Orders[0].Ticker != "RTS-9.19" {
+		t.Fatalf("order must roll over to next series: %v, %v", orders, err)
+	}
+
+	contracts, err := e.Contracts(context.Background(), &exchange.ContractsRequest{Ticker: "SPFB.RTS"})
+	if err != nil || len(contracts.Contracts) != 2 {
+		t.Fatalf("contracts dont match: %v, %v", contracts, err)
+	}
+	if c := contracts.Contracts[0]; !c.Expired || c.Front || c.SettlementPrice != 100 {
+		t.Fatalf("expired series dont match: %v", c)
+	}
+	if c := contracts.Contracts[1]; c.Expired || !c.Front {
+		t.Fatalf("front series dont match: %v", c)
+	}
+}
@@ -2,118 +2,528 @@ package server
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
-	"fmt"
-	"log"
 	"net"
+	"net/http"
+	"os"
+	"path/filepath"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/candles"
+	"github.com/KSerditov/Trading/pkg/exchange/clearing"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/console"
+	"github.com/KSerditov/Trading/pkg/exchange/contracts"
+	"github.com/KSerditov/Trading/pkg/exchange/matching"
+	"github.com/KSerditov/Trading/pkg/exchange/metrics"
+	"github.com/KSerditov/Trading/pkg/exchange/simulation"
 	"github.com/KSerditov/Trading/pkg/exchange/tickers"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	candlesPageSize    = 1000
+	candlesMaxPageSize = 10000
+
+	defaultDrainTimeout = 10 * time.Second
 )
 
 type ExchangeSrv struct {
 	BufferSize int
 
+	// bars per second sent to one Statistic subscriber, unlimited if 0
+	StatisticMaxRate int
+
 	Tickers tickers.TickersSource
+	Logger  *zap.SugaredLogger
+	Clock   clock.Clock
+	Candles candles.CandlesStorage
+	Tape    *TradeTape
+
+	// ticker -> matching algorithm, FIFO if not set
+	Matchers map[string]matching.Matcher
+
+	// futures series expiring by Clock, orders and ticks of continuous ticker go to its front series.
+	// All tickers are traded without expiry if nil.
+	Schedule *contracts.Schedule
+
+	ClearingHouse clearing.Clearing // end of day reports, disabled if nil
+
+	// latency, queue position, slippage and partial fills, instant fills at tick price if zero
+	Simulation simulation.Model
+
+	// orders and executions in sequence, standby replays it from primary,
+	// with ticks when it is recorded to file for exchange-replay
+	Journal *Journal
 
-	MaxDealID     int64
-	OrderBookLock *sync.RWMutex
-	OrderBook     []*exchange.Deal
+	// faults injected into broker streams and calls, changed by SetChaos
+	Chaos *Chaos
+
+	MaxDealID int64
+
+	// order book of each ticker is owned by its shard
+	shardsLock  *sync.RWMutex
+	shards      map[string]*shard
+	orderShards *sync.Map // DealID -> *shard of resting order
 
 	ohlcvId int64
 
-	ChannelsLock *sync.RWMutex
-	Channels     map[int64]chan *exchange.Deal
+	ChannelsLock   *sync.RWMutex
+	Channels       map[int64]chan *exchange.Deal
+	resultsDropped sync.Map // BrokerID -> true, report did not fit into channel
+
+	draining   int32         // new orders are rejected
+	traderDone chan struct{} // trader processed all fed ticks
+	stopping   chan struct{} // streams flush what they have and return
+
+	standby      int32          // journal is applied from primary, orders and Results are rejected
+	promoted     chan struct{}  // standby became primary
+	followerDone chan struct{}  // standby stopped applying journal
+	health       *health.Server // exchange service is serving on primary only
 
 	exchange.UnimplementedExchangeServer
 }
 
+type Config struct {
+	ListenAddr string
+	BufferSize int
+
+	// bars per second sent to one Statistic subscriber, unlimited if 0.
+	// Subscriber which does not keep up gets only the latest bar of each ticker.
+	StatisticMaxRate int
+
+	// consumer -> allowed methods, access is not checked if empty
+	ACL map[string][]string
+
+	// global zap logger is used if nil
+	Logger *zap.SugaredLogger
+
+	// wall clock is used if nil
+	Clock clock.Clock
+
+	// history for GetCandles, 1s and 1m bars in memory fed from datasource if nil
+	Candles candles.CandlesStorage
+
+	// how long to wait for trader and streams on shutdown, 10s if not set
+	DrainTimeout time.Duration
+
+	// plaintext if nil, set ClientCAs to require broker certificates
+	TLS *tls.Config
+
+	// client certificate CommonName -> BrokerID, brokers may act only on their own orders and streams
+	BrokerCerts map[string]int64
+
+	// client certificate CommonNames allowed to call Replicate, Promote and SetChaos,
+	// nobody may call them if BrokerCerts are set and these are not
+	AdminCerts []string
+
+	// ticker -> matching algorithm, FIFO if not set
+	Matchers map[string]matching.Matcher
+
+	Clearing clearing.Clearing // end of day reports, disabled if nil
+
+	// futures series with expiry and rollover, tickers never expire if nil
+	Contracts *contracts.Schedule
+
+	// latency, queue position, slippage and partial fills, instant fills at tick price if zero
+	Simulation simulation.Model
+
+	// JSON/SSE gateway address, same TLS and authentication as grpc, disabled if empty
+	HTTPListen string
+
+	// read-only web console address, disabled if empty
+	ConsoleListen string
+
+	// hot standby replicates journal from primary at this address until promoted, primary if empty
+	Primary         string
+	PrimaryTLS      *tls.Config // plaintext if nil
+	PrimaryConsumer string      // "consumer" metadata for ACL of primary
+
+	// standby promotes itself when primary is silent this long, only by Promote rpc if 0
+	Lease time.Duration
+
+	// faults injected into broker streams and calls from start, none if nil
+	Chaos *exchange.ChaosSettings
+
+	// journal with ticks is written to new file in this directory for exchange-replay, not written if empty
+	JournalDir string
+}
+
 func Start(ctx context.Context, listenAddr string, ACLData string, datasource tickers.TickersSource) error {
-	/*
-		auther := Authenticator{}
-		errjson := json.Unmarshal([]byte(ACLData), &auther.accessList)
+	cfg := Config{
+		ListenAddr: listenAddr,
+		BufferSize: 100,
+	}
+	if ACLData != "" {
+		errjson := json.Unmarshal([]byte(ACLData), &cfg.ACL)
 		if errjson != nil {
 			return errjson
 		}
-	*/
+	}
+
+	return StartWithConfig(ctx, cfg, datasource)
+}
+
+func StartWithConfig(ctx context.Context, cfg Config, datasource tickers.TickersSource) error {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.S()
+	}
+
+	clk := cfg.Clock
+	if clk == nil {
+		clk = clock.Real{}
+	}
+
+	history := cfg.Candles
+	if history == nil {
+		inmem := &candles.CandlesInMem{
+			BarIntervals: []time.Duration{time.Second, time.Minute},
+			Clock:        clk,
+		}
+		err := inmem.Init()
+		if err != nil {
+			return err
+		}
+		inmem.Start(datasource.GetFeedChannel())
+		history = inmem
+	}
+
+	chaos, err := NewChaos(cfg.Chaos)
+	if err != nil {
+		return err
+	}
+
+	auther := Authenticator{
+		accessList: cfg.ACL,
+	}
+
+	identity := NewBrokerIdentity(cfg.BrokerCerts, cfg.AdminCerts)
 
 	s := &ExchangeSrv{
-		BufferSize:                  100,
+		BufferSize:                  cfg.BufferSize,
+		StatisticMaxRate:            cfg.StatisticMaxRate,
 		Tickers:                     datasource,
+		Logger:                      logger,
+		Clock:                       clk,
+		Candles:                     history,
+		Tape:                        NewTradeTape(cfg.BufferSize),
+		Matchers:                    cfg.Matchers,
+		Schedule:                    cfg.Contracts,
+		ClearingHouse:               cfg.Clearing,
+		Simulation:                  cfg.Simulation,
+		Journal:                     NewJournal(),
+		Chaos:                       chaos,
 		MaxDealID:                   0,
-		OrderBookLock:               &sync.RWMutex{},
-		OrderBook:                   make([]*exchange.Deal, 0, 100),
+		shardsLock:                  &sync.RWMutex{},
+		shards:                      make(map[string]*shard, 2),
+		orderShards:                 &sync.Map{},
 		ChannelsLock:                &sync.RWMutex{},
 		Channels:                    make(map[int64]chan *exchange.Deal, 10),
+		traderDone:                  make(chan struct{}),
+		stopping:                    make(chan struct{}),
+		promoted:                    make(chan struct{}),
+		followerDone:                make(chan struct{}),
 		UnimplementedExchangeServer: exchange.UnimplementedExchangeServer{},
 	}
+	if cfg.Primary != "" {
+		s.standby = 1
+	}
+	s.Journal.clock = clk
+	if cfg.JournalDir != "" {
+		err = os.MkdirAll(cfg.JournalDir, 0755)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(cfg.JournalDir, "journal-"+clk.Now().Format("20060102-150405")+".bin")
+		err = s.Journal.Record(path, logger)
+		if err != nil {
+			return err
+		}
+		defer s.Journal.Close()
+		logger.Infow("Recording journal", "file", path)
+	}
+
+	// history bars get order book state on close too
+	if m, ok := history.(interface{ SetMarket(candles.Market) }); ok {
+		m.SetMarket(s)
+	}
 
-	lis, err := net.Listen("tcp", listenAddr)
+	metrics.RegisterBacklog("results", s.resultsBacklog)
+	metrics.RegisterBacklog("trades", s.Tape.Backlog)
+	if b, ok := datasource.(interface{ Backlog() map[string]int }); ok {
+		metrics.RegisterBacklog("ticks", b.Backlog)
+	}
+
+	lis, err := net.Listen("tcp", cfg.ListenAddr)
 	if err != nil {
-		log.Fatalln("cant listen port", err)
+		logger.Errorw("cant listen port", "addr", cfg.ListenAddr, "error", err)
 		return err
 	}
 
-	server := grpc.NewServer(
-		grpc.ChainStreamInterceptor(
+	streamInterceptors := []grpc.StreamServerInterceptor{
 		//logStreamInterceptor,
-		//auther.AuthStreamInterceptor,
-		),
-		grpc.ChainUnaryInterceptor(
+		auther.AuthStreamInterceptor,
+		identity.StreamInterceptor,
+		chaos.StreamInterceptor,
+	}
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
 		//logInterceptor,
-		//auther.AuthInterceptor,
-		),
-	)
+		auther.AuthInterceptor,
+		identity.UnaryInterceptor,
+		chaos.UnaryInterceptor,
+	}
+	opts := []grpc.ServerOption{
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+	}
+	if cfg.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(cfg.TLS)))
+	}
+
+	server := grpc.NewServer(opts...)
 
 	exchange.RegisterExchangeServer(server, s)
 
-	go func(s *grpc.Server) {
-		for {
-			<-ctx.Done()
-			datasource.CloseFeed()
-			s.GracefulStop()
-			return
+	// not serving until tickers are loaded
+	healthSrv := health.NewServer()
+	healthSrv.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthSrv.SetServingStatus(exchange.Exchange_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, healthSrv)
+	s.health = healthSrv
+
+	reflection.Register(server)
+
+	go func() {
+		if r, ok := datasource.(interface{ Ready() <-chan struct{} }); ok {
+			select {
+			case <-r.Ready():
+			case <-ctx.Done():
+				return
+			}
+		}
+		healthSrv.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+		// brokers balance to exchange service status, standby gets it on promotion
+		if !s.isStandby() {
+			healthSrv.SetServingStatus(exchange.Exchange_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+		}
+		logger.Info("Exchange is serving")
+	}()
+
+	var httpSrv *http.Server
+	if cfg.HTTPListen != "" {
+		gateway := &HTTPGateway{
+			srv:      s,
+			unary:    unaryInterceptors,
+			stream:   streamInterceptors,
+			identity: identity,
+		}
+		httpSrv = &http.Server{
+			Addr:      cfg.HTTPListen,
+			Handler:   gateway.Handler(),
+			TLSConfig: cfg.TLS,
+		}
+		httpLis, err := net.Listen("tcp", cfg.HTTPListen)
+		if err != nil {
+			lis.Close()
+			logger.Errorw("cant listen port", "addr", cfg.HTTPListen, "error", err)
+			return err
+		}
+
+		go func() {
+			logger.Infow("Starting exchange HTTP gateway...", "addr", cfg.HTTPListen)
+			var err error
+			if cfg.TLS != nil {
+				err = httpSrv.ServeTLS(httpLis, "", "")
+			} else {
+				err = httpSrv.Serve(httpLis)
+			}
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logger.Errorw("HTTP gateway stopped", "error", err)
+			}
+		}()
+	}
+
+	if cfg.ConsoleListen != "" {
+		ops := &console.Console{
+			Exchange:    s,
+			BarInterval: history.Intervals()[0],
+			Clock:       clk,
+		}
+		for _, iv := range history.Intervals() {
+			if iv < ops.BarInterval {
+				ops.BarInterval = iv
+			}
+		}
+		if b, ok := history.(console.Bars); ok {
+			ops.Bars = b
+		}
+		if r, ok := datasource.(console.Replay); ok {
+			ops.Replay = r
+		}
+		err = ops.Init()
+		if err != nil {
+			lis.Close()
+			return err
 		}
-	}(server)
 
-	fmt.Println("Starting exchange server...")
+		go func() {
+			logger.Infow("Starting exchange console...", "addr", cfg.ConsoleListen)
+			err := ops.Serve(ctx, cfg.ConsoleListen)
+			if err != nil {
+				logger.Errorw("console stopped", "error", err)
+			}
+		}()
+	}
 
-	s.StartTrader()
+	drainTimeout := cfg.DrainTimeout
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		s.drain(server, httpSrv, healthSrv, drainTimeout)
+	}()
+
+	logger.Infow("Starting exchange server...", "addr", cfg.ListenAddr)
+
+	if s.isStandby() {
+		err = s.startFollower(ctx, cfg)
+		if err != nil {
+			lis.Close()
+			return err
+		}
+	} else {
+		close(s.followerDone)
+		s.StartTrader()
+	}
 
 	errs := server.Serve(lis)
 	if errs != nil {
 		return errs
 	}
 
+	<-drained
 	return nil
 }
 
+// drain stops accepting orders, lets trader finish ticks already fed,
+// delivers pending Results and closes streams
+func (e *ExchangeSrv) drain(server *grpc.Server, httpSrv *http.Server, healthSrv *health.Server, timeout time.Duration) {
+	e.Logger.Info("Draining exchange server...")
+
+	healthSrv.Shutdown()
+	atomic.StoreInt32(&e.draining, 1)
+	e.Tickers.CloseFeed()
+
+	deadline := time.After(timeout)
+	if e.isStandby() {
+		// trader is not started, journal is not applied after stopping
+		<-e.followerDone
+	} else {
+		select {
+		case <-e.traderDone:
+		case <-deadline:
+			e.Logger.Warn("Trader did not stop in time")
+		}
+	}
+	close(e.stopping)
+
+	if httpSrv != nil {
+		// streams are finished by stopping, hanging connections are closed on drain timeout
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := httpSrv.Shutdown(ctx); err != nil {
+				httpSrv.Close()
+			}
+		}()
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		e.Logger.Info("Exchange server stopped")
+	case <-deadline:
+		e.Logger.Warn("Drain timeout, closing remaining connections")
+		server.Stop()
+	}
+}
+
 // поток ценовых данных от биржи к брокеру
 // мы каждую секнуду будем получать отсюда событие с ценами, которые брокер аггрегирует у себя и показывает клиентам
 // устанавливается 1 раз брокером
 func (e *ExchangeSrv) Statistic(brokerID *exchange.BrokerID, exchangeStatisticServer exchange.Exchange_StatisticServer) error {
-	fmt.Printf("Broker connected to Statistic, brokerId: %v\n", brokerID)
+	e.Logger.Infow("Broker connected to Statistic", "brokerId", brokerID.ID)
+	metrics.ConnectedBrokers.WithLabelValues(metrics.StreamStatistic).Inc()
+	defer metrics.ConnectedBrokers.WithLabelValues(metrics.StreamStatistic).Dec()
+
 	interval := time.Second * 1
-	ticker := time.NewTicker(interval)
+	ticker := e.Clock.NewTicker(interval)
 	defer ticker.Stop()
 
 	ctx := exchangeStatisticServer.Context()
+
+	// bars are sent by own goroutine, so slow subscriber does not hold the feed, its bars are conflated
+	bars := newConflater()
+	sendCtx, stopSend := context.WithCancel(ctx)
+	sendErr := make(chan error, 1)
+	senderDone := make(chan struct{})
+	go func() {
+		defer close(senderDone)
+		sendErr <- e.sendBars(sendCtx, exchangeStatisticServer, bars)
+	}()
+	defer func() {
+		stopSend()
+		<-senderDone
+	}()
+
 	//fmt.Printf("STATISTICS requesting feed channel\n")
 	feed := e.Tickers.GetFeedChannel()
+	defer func() {
+		// feed may be blocked on this channel until it is released
+		go func() {
+			for range feed {
+			}
+		}()
+		e.Tickers.ReleaseFeedChannel(feed)
+	}()
 
 	var opents, closets time.Time
 	ohlcvs := make(map[string]*exchange.OHLCV, 2)
+	turnover := make(map[string]float64, 2) // price * volume for VWAP
 
 	for {
 		select {
 		// new ticker from feed - collect data into ohlcv map per each ticker value
-		case v := <-feed:
+		case v, open := <-feed:
+			if !open {
+				return nil
+			}
 			//fmt.Printf("STATISTICS NEW TICKER FROM FEED %v\n", v)
 			_, ok := ohlcvs[v.Ticker]
 			if !ok { // add new ticker first time in interval
@@ -130,13 +540,17 @@ func (e *ExchangeSrv) Statistic(brokerID *exchange.BrokerID, exchangeStatisticSe
 					Close:    v.Last,
 					Volume:   v.Vol,
 					Ticker:   v.Ticker,
+					Trades:   1,
 				}
 				ohlcvs[v.Ticker] = newOHLCV
+				turnover[v.Ticker] = float64(v.Last) * float64(v.Vol)
 				continue
 			}
 
 			// aggregation
 			atomic.AddInt32(&ohlcvs[v.Ticker].Volume, v.Vol)
+			ohlcvs[v.Ticker].Trades++
+			turnover[v.Ticker] += float64(v.Last) * float64(v.Vol)
 
 			if v.Last > ohlcvs[v.Ticker].High {
 				ohlcvs[v.Ticker].High = v.Last
@@ -159,35 +573,173 @@ func (e *ExchangeSrv) Statistic(brokerID *exchange.BrokerID, exchangeStatisticSe
 			//fmt.Printf("STATISTICS AGGREGATE %v\n", ohlcvs[v.Ticker])
 
 		// broker notification interval elapsed - send collected data
-		case timetick := <-ticker.C:
+		case timetick := <-ticker.C():
 			//fmt.Printf("STATISTICS NEW TIME TICK\n")
 			for _, v := range ohlcvs {
 				v.Time = int32(timetick.Unix())
+				if v.Volume > 0 {
+					v.VWAP = float32(turnover[v.Ticker] / float64(v.Volume))
+				}
+				v.BestBid, v.BestAsk = e.BestBidAsk(v.Ticker)
+				v.OpenInterest = e.OpenInterest(v.Ticker)
 				//fmt.Printf("STATISTICS SENDING %v\n", v)
-				errsend := exchangeStatisticServer.Send(v)
-
-				if errsend != nil {
-					return errsend
+				if bars.put(v) {
+					metrics.Conflated.WithLabelValues(metrics.StreamStatistic).Inc()
 				}
 			}
 			ohlcvs = make(map[string]*exchange.OHLCV, 2)
+			turnover = make(map[string]float64, 2)
+
+		case <-senderDone:
+			return <-sendErr
+
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// sendBars sends bars to subscriber as they come, at most StatisticMaxRate per second if set
+func (e *ExchangeSrv) sendBars(ctx context.Context, statisticServer exchange.Exchange_StatisticServer, bars *conflater) error {
+	var limit <-chan time.Time
+	if e.StatisticMaxRate > 0 {
+		limiter := e.Clock.NewTicker(time.Second / time.Duration(e.StatisticMaxRate))
+		defer limiter.Stop()
+		limit = limiter.C()
+	}
 
+	for {
+		select {
+		case <-bars.ready:
 		case <-ctx.Done():
 			return nil
 		}
+
+		for {
+			if limit != nil {
+				select {
+				case <-limit:
+				case <-ctx.Done():
+					return nil
+				}
+			}
+			bar := bars.pop()
+			if bar == nil {
+				break
+			}
+			err := statisticServer.Send(bar)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// история свечей за период, постранично
+func (e *ExchangeSrv) GetCandles(ctx context.Context, req *exchange.CandlesRequest) (*exchange.CandlesResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = candlesPageSize
+	}
+	if pageSize > candlesMaxPageSize {
+		pageSize = candlesMaxPageSize
+	}
+
+	to := e.Clock.Now()
+	if req.To != 0 {
+		to = time.Unix(int64(req.To), 0)
+	}
+
+	bars, next, err := e.Candles.Get(req.Ticker, time.Duration(req.Interval)*time.Second, time.Unix(int64(req.From), 0), to, req.PageToken, pageSize)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &exchange.CandlesResponse{
+		Candles:       bars,
+		NextPageToken: next,
+	}, nil
+}
+
+// клиринговый отчет за день, брокер видит только свои позиции
+func (e *ExchangeSrv) Clearing(ctx context.Context, req *exchange.ClearingRequest) (*exchange.ClearingReport, error) {
+	if e.ClearingHouse == nil {
+		return nil, status.Error(codes.Unimplemented, "clearing is disabled")
+	}
+
+	report, err := e.ClearingHouse.Report(req.Date)
+	switch {
+	case errors.Is(err, clearing.ErrorBadDate):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, clearing.ErrorNoReport):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	res := &exchange.ClearingReport{
+		Date:        report.Date,
+		Time:        report.Time,
+		Positions:   make([]*exchange.ClearingPosition, 0, 4),
+		Instruments: report.Instruments,
+	}
+	for _, p := range report.Positions {
+		if int64(p.BrokerID) == req.BrokerID {
+			res.Positions = append(res.Positions, p)
+		}
 	}
+	return res, nil
 }
 
-// Adds new Order from broker to OrderBook and returns assigned unique DealID
+// Adds new Order from broker to order book of its ticker and returns assigned unique DealID
 func (e *ExchangeSrv) Create(ctx context.Context, deal *exchange.Deal) (*exchange.DealID, error) {
+	if atomic.LoadInt32(&e.draining) == 1 {
+		return nil, status.Error(codes.Unavailable, "exchange is shutting down")
+	}
+	if e.isStandby() {
+		return nil, errorStandby
+	}
+
+	if deal.DisplayVolume < 0 {
+		return nil, status.Error(codes.InvalidArgument, "display volume must not be negative")
+	}
+
+	// continuous ticker trades as its front series, expired series does not trade
+	ticker, err := e.resolve(deal.Ticker)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	deal.Ticker = ticker
+
 	//fmt.Printf("new order received: %v\n", deal)
 	//deal.ID = atomic.AddInt64(&e.MaxDealID, 1)
 	deal.ID = int64(uuid.New().ID()) // since there is no persistence for exchange yet
-
-	e.OrderBookLock.Lock()
-	e.OrderBook = append(e.OrderBook, deal)
-	//fmt.Printf("order book now is: %v\n", e.OrderBook)
-	e.OrderBookLock.Unlock()
+	// execution state is kept on the order in book
+	deal.CumVolume = 0
+	deal.LeavesVolume = 0
+	deal.AvgPrice = 0
+	deal.Status = exchange.OrderStatus_STATUS_UNKNOWN
+
+	metrics.Orders.WithLabelValues(metrics.OrderCreated).Inc()
+	if deal.Price == 0 || deal.Volume <= 0 {
+		// neither buy nor sell or nothing to fill, never trades
+		metrics.Orders.WithLabelValues(metrics.OrderDropped).Inc()
+	} else {
+		s := e.shardFor(deal.Ticker)
+		e.orderShards.Store(deal.ID, s)
+		if !s.do(func() {
+			o := s.rest(deal)
+			e.Journal.Append(exchange.JournalEntryType_ORDER_CREATED, deal, exchange.Side_SIDE_UNKNOWN)
+			// series expired after the order was resolved to it
+			if s.expired {
+				s.expireOrder(o, e.Clock.Now())
+			}
+			s.book.updateMetrics()
+		}) {
+			e.orderShards.Delete(deal.ID)
+			return nil, status.Error(codes.Unavailable, "exchange is shutting down")
+		}
+	}
 
 	dealid := &exchange.DealID{
 		ID:       deal.ID,
@@ -201,45 +753,187 @@ func (e *ExchangeSrv) Create(ctx context.Context, deal *exchange.Deal) (*exchang
 // Cancels existing deal or returns an error if deal does not exist
 func (e *ExchangeSrv) Cancel(ctx context.Context, deal *exchange.DealID) (*exchange.CancelResult, error) {
 	cancelResult := &exchange.CancelResult{Success: false}
+	if e.isStandby() {
+		return cancelResult, errorStandby
+	}
 
-	e.OrderBookLock.Lock()
-	defer e.OrderBookLock.Unlock()
+	if v, ok := e.orderShards.Load(deal.ID); ok {
+		s := v.(*shard)
+		s.call(func() {
+			cancelResult.Success = s.book.remove(deal.ID)
+			s.book.updateMetrics()
+			if cancelResult.Success {
+				e.Journal.Append(exchange.JournalEntryType_ORDER_CANCELLED, &exchange.Deal{
+					ID:       deal.ID,
+					BrokerID: int32(deal.BrokerID),
+					Ticker:   s.book.ticker,
+				}, exchange.Side_SIDE_UNKNOWN)
+			}
+		})
+	}
+
+	if !cancelResult.Success {
+		metrics.Orders.WithLabelValues(metrics.OrderCancelNotFound).Inc()
+		return cancelResult, status.Error(codes.NotFound, "no such deal id found")
+	}
+	e.orderShards.Delete(deal.ID)
+	metrics.Orders.WithLabelValues(metrics.OrderCancelled).Inc()
+	return cancelResult, nil
+}
+
+// активные заявки брокера в порядке приоритета, копии чтобы не гоняться с шардами при отправке
+func (e *ExchangeSrv) Orders(ctx context.Context, req *exchange.OrdersRequest) (*exchange.OrdersResponse, error) {
+	res := &exchange.OrdersResponse{
+		Orders: make([]*exchange.Deal, 0, 4),
+	}
 
-	for i := len(e.OrderBook) - 1; i >= 0; i-- {
-		if deal.ID == e.OrderBook[i].ID {
-			e.OrderBook = append(e.OrderBook[:i], e.OrderBook[i+1:]...)
-			cancelResult.Success = true
-			break
+	shards := e.allShards()
+	if req.ID != 0 {
+		shards = shards[:0]
+		if v, ok := e.orderShards.Load(req.ID); ok {
+			shards = append(shards, v.(*shard))
 		}
 	}
 
-	//fmt.Println(e.OrderBook)
+	for _, s := range shards {
+		if req.Ticker != "" && s.book.ticker != req.Ticker {
+			continue
+		}
+		s.call(func() {
+			s.book.each(func(o *bookOrder) {
+				if int64(o.deal.BrokerID) != req.BrokerID || req.ID != 0 && o.deal.ID != req.ID {
+					return
+				}
+				res.Orders = append(res.Orders, proto.Clone(o.deal).(*exchange.Deal))
+			})
+		})
+	}
 
-	if !cancelResult.Success {
-		return cancelResult, errors.New("no such deal id found")
+	if req.ID != 0 && len(res.Orders) == 0 {
+		return nil, status.Errorf(codes.NotFound, "no order %v", req.ID)
 	}
-	return cancelResult, nil
+	return res, nil
 }
 
 // исполнение заявок от биржи к брокеру
 // устанавливается 1 раз брокером и при исполнении какой-то заявки
+// после переподключения (в том числе к новой основной бирже) брокер передает LastExecSeq
+// и получает пропущенные отчеты из журнала. Брокер, не успевающий читать отчеты, получает ResourceExhausted
+// и так же переподключается, шард его не ждет
+// репликация асинхронная: если брокер получил отчеты, которых нет в журнале резервной биржи,
+// ставшей основной, возвращается FailedPrecondition, эти отчеты потеряны и брокер начинает с новых
 func (e *ExchangeSrv) Results(brokerID *exchange.BrokerID, exchangeResultsServer exchange.Exchange_ResultsServer) error {
+	if e.isStandby() {
+		return errorStandby
+	}
+	if last := e.Journal.LastSeq(); brokerID.LastExecSeq > last {
+		e.Logger.Warnw("Broker is ahead of journal", "brokerId", brokerID.ID, "lastExecSeq", brokerID.LastExecSeq, "seq", last)
+		return status.Errorf(codes.FailedPrecondition, "broker is ahead of exchange journal: %v > %v", brokerID.LastExecSeq, last)
+	}
 	defer e.DeleteBrokerChannel(brokerID)
+	metrics.ConnectedBrokers.WithLabelValues(metrics.StreamResults).Inc()
+	defer metrics.ConnectedBrokers.WithLabelValues(metrics.StreamResults).Dec()
 
 	//fmt.Printf("Broker connected to Results, brokerId: %v\n", brokerID)
 	c, err := e.GetBrokerChannel(brokerID)
 	if err != nil {
 		return err
 	}
+	// reports dropped before are in journal after LastExecSeq
+	e.resultsDropped.Delete(brokerID.ID)
+
+	// channel is registered, so every report after replayed ones comes from it
+	var replayed int64
+	if brokerID.LastExecSeq > 0 {
+		var missed []*exchange.Deal
+		missed, replayed = e.Journal.Fills(brokerID.ID, brokerID.LastExecSeq)
+		for _, d := range missed {
+			errsend := exchangeResultsServer.Send(d)
+			if errsend != nil {
+				return errsend
+			}
+		}
+	}
+	send := func(d *exchange.Deal) error {
+		if d.ExecSeq <= replayed {
+			return nil
+		}
+		return exchangeResultsServer.Send(d)
+	}
 
 	ctx := exchangeResultsServer.Context()
 
 	for {
 		select {
 		case d := <-c:
-			errsend := exchangeResultsServer.Send(d)
+			if _, dropped := e.resultsDropped.LoadAndDelete(brokerID.ID); dropped {
+				// broker resumes after the last report it got, the rest is taken from journal
+				return status.Error(codes.ResourceExhausted, "results buffer overflowed, resume with LastExecSeq")
+			}
+			errsend := send(d)
 			if errsend != nil {
-				fmt.Printf("Error sending Results: %v", errsend)
+				e.Logger.Errorw("Error sending Results", "brokerId", brokerID.ID, "error", errsend)
+			}
+		case <-e.stopping:
+			// trader is stopped, nothing new comes to c
+			for {
+				select {
+				case d := <-c:
+					errsend := send(d)
+					if errsend != nil {
+						return errsend
+					}
+				default:
+					return nil
+				}
+			}
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// публичная лента сделок, без брокера и клиента
+func (e *ExchangeSrv) Trades(brokerID *exchange.BrokerID, tradesServer exchange.Exchange_TradesServer) error {
+	e.Logger.Infow("Broker connected to Trades", "brokerId", brokerID.ID)
+	metrics.ConnectedBrokers.WithLabelValues(metrics.StreamTrades).Inc()
+	defer metrics.ConnectedBrokers.WithLabelValues(metrics.StreamTrades).Dec()
+
+	id, trades := e.Tape.Subscribe()
+	defer e.Tape.Unsubscribe(id)
+
+	// subscriber is registered, client may rely on it after receiving headers
+	err := tradesServer.SendHeader(metadata.MD{})
+	if err != nil {
+		return err
+	}
+
+	ctx := tradesServer.Context()
+	for {
+		select {
+		case t, ok := <-trades:
+			if !ok {
+				e.Logger.Warnw("Trades subscriber is too slow, disconnecting", "brokerId", brokerID.ID)
+				return status.Error(codes.ResourceExhausted, "trades subscriber is too slow")
+			}
+			errsend := tradesServer.Send(t)
+			if errsend != nil {
+				return errsend
+			}
+		case <-e.stopping:
+			for {
+				select {
+				case t, ok := <-trades:
+					if !ok {
+						return nil
+					}
+					errsend := tradesServer.Send(t)
+					if errsend != nil {
+						return errsend
+					}
+				default:
+					return nil
+				}
 			}
 		case <-ctx.Done():
 			return nil
@@ -254,106 +948,110 @@ func (e *ExchangeSrv) DeleteBrokerChannel(brokerId *exchange.BrokerID) {
 	delete(e.Channels, brokerId.ID)
 }
 
+// GetBrokerChannel is called by shards on every fill, so channel is looked up under read lock
+// and write lock is taken only to create it
 func (e *ExchangeSrv) GetBrokerChannel(brokerId *exchange.BrokerID) (chan *exchange.Deal, error) {
-	e.ChannelsLock.Lock()
-	defer e.ChannelsLock.Unlock()
-
+	e.ChannelsLock.RLock()
 	val, ok := e.Channels[brokerId.ID]
+	e.ChannelsLock.RUnlock()
 	if ok {
 		return val, nil
-	} else {
-		e.Channels[brokerId.ID] = make(chan *exchange.Deal, e.BufferSize)
-		return e.Channels[brokerId.ID], nil
 	}
+
+	e.ChannelsLock.Lock()
+	defer e.ChannelsLock.Unlock()
+
+	val, ok = e.Channels[brokerId.ID]
+	if !ok {
+		val = make(chan *exchange.Deal, e.BufferSize)
+		e.Channels[brokerId.ID] = val
+	}
+	return val, nil
 }
 
 func (e *ExchangeSrv) StartTrader() error {
-	fmt.Println("Starting trader...")
+	e.Logger.Info("Starting trader...")
+
+	if e.Schedule != nil {
+		go e.serveExpiry()
+	}
 
 	go func() {
+		defer close(e.traderDone)
+		// ticks already queued to shards are matched before trader is done
+		defer func() {
+			for _, s := range e.allShards() {
+				s.call(func() {})
+			}
+		}()
+
 		feed := e.Tickers.GetFeedChannel()
 		for t := range feed {
 			// new ticker received from ticker feed
 			//fmt.Printf("TRADER TICKER: %v\n", t)
+			// feed has continuous tickers, front series of tick time trades with them
+			t.Ticker = e.Schedule.Route(t.Ticker, t.Timestamp)
+			if e.ClearingHouse != nil {
+				e.ClearingHouse.Mark(t)
+			}
 			if t.Vol == 0 {
 				continue
 			}
 
-			e.OrderBookLock.Lock()
-
-			// go through incomplete orders
-			for i, order := range e.OrderBook {
-				// drop completed orders or orders with 0 price
-				if order.Volume == 0 || order.Price == 0 {
-					e.OrderBook = append(e.OrderBook[:i], e.OrderBook[i+1:]...)
-					continue
-				}
-
-				fmt.Printf("TRADER ORDER: %v\n", order)
-				if order.Ticker != t.Ticker {
-					continue
-				}
-
-				c, err := e.GetBrokerChannel(&exchange.BrokerID{
-					ID: int64(order.BrokerID),
-				})
-				if err != nil {
-					fmt.Printf("Error getting broker channel: %v\n", err)
-				}
-
-				// prepare deal
-				deal := &exchange.Deal{
-					ID:       order.ID,
-					BrokerID: order.BrokerID,
-					ClientID: order.ClientID,
-					Ticker:   order.Ticker,
-					Time:     int32(t.Timestamp.Unix()),
-					Price:    t.Last,
-					Partial:  false,
-				}
-
-				if order.Volume > t.Vol {
-					deal.Volume = t.Vol
-					deal.Partial = true
-				} else {
-					deal.Volume = order.Volume
-				}
-
-				// make deal if price conditions are met
-				// pending deal price exceeds ticker from feed, then exchange sells, broker buys
-				// positive price expected if pending deal has BUY type
-				if order.Price > 0 && order.Price >= t.Last {
-					fmt.Printf("TRADER SELLS ORDER VOL %v\n", order.Volume)
-
-					e.OrderBook[i].Volume -= deal.Volume
-					t.Vol -= deal.Volume
-
-					fmt.Printf("TRADER SOLD %v\n", deal)
-
-					c <- deal
-					continue
-				}
+			t := t
+			s := e.shardFor(t.Ticker)
+			s.do(func() { s.match(t) })
+		}
+	}()
 
-				// exchange buys, broker sells
-				// negative price expected if pending deal has SELL type
-				//
-				if order.Price < 0 && -order.Price <= t.Last {
-					fmt.Printf("TRADER BUYS ORDER VOL %v\n", order.Volume)
+	e.Logger.Info("Trader started...")
+	return nil
+}
 
-					e.OrderBook[i].Volume -= deal.Volume
-					t.Vol += deal.Volume
+// fillOrder applies executed deal.Volume to the order in book and completes execution report
+func fillOrder(order *exchange.Deal, deal *exchange.Deal) {
+	cum := order.CumVolume + deal.Volume
+	order.AvgPrice = float32((float64(order.AvgPrice)*float64(order.CumVolume) + float64(deal.Price)*float64(deal.Volume)) / float64(cum))
+	order.CumVolume = cum
+	order.Volume -= deal.Volume
+
+	deal.CumVolume = order.CumVolume
+	deal.LeavesVolume = order.Volume
+	deal.AvgPrice = order.AvgPrice
+	if order.Volume > 0 {
+		deal.Status = exchange.OrderStatus_PARTIALLY_FILLED
+		deal.Partial = true
+	} else {
+		deal.Status = exchange.OrderStatus_FILLED
+		deal.Partial = false
+	}
+}
 
-					fmt.Printf("TRADER BOUGHT %v\n", deal)
+func (e *ExchangeSrv) publishTrade(deal *exchange.Deal, aggressor exchange.Side) {
+	e.Tape.Publish(&exchange.Trade{
+		Ticker:    deal.Ticker,
+		Price:     deal.Price,
+		Volume:    deal.Volume,
+		Aggressor: aggressor,
+		Time:      deal.Time,
+	})
+}
 
-					c <- deal
-					continue
-				}
-			}
+func countFill(deal *exchange.Deal) {
+	if deal.Partial {
+		metrics.Orders.WithLabelValues(metrics.OrderPartiallyFilled).Inc()
+	} else {
+		metrics.Orders.WithLabelValues(metrics.OrderFilled).Inc()
+	}
+}
 
-			e.OrderBookLock.Unlock()
-		}
-	}()
+func (e *ExchangeSrv) resultsBacklog() map[string]int {
+	e.ChannelsLock.RLock()
+	defer e.ChannelsLock.RUnlock()
 
-	fmt.Println("Trader started...")
-	return nil
+	backlog := make(map[string]int, len(e.Channels))
+	for id, c := range e.Channels {
+		backlog[metrics.SubscriberID(id)] = len(c)
+	}
+	return backlog
 }
@@ -9,9 +9,13 @@ import (
 	"time"
 
 	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
 	"github.com/KSerditov/Trading/pkg/exchange/tickers"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -34,8 +38,9 @@ type TickersSourceTest struct {
 	ch     []chan tickers.Tick
 }
 
+// unbuffered, so Run returns only after every consumer took all ticks
 func (t *TickersSourceTest) GetFeedChannel() <-chan tickers.Tick {
-	c := make(chan tickers.Tick, 100)
+	c := make(chan tickers.Tick)
 
 	t.chLock.Lock()
 	t.ch = append(t.ch, c)
@@ -44,6 +49,19 @@ func (t *TickersSourceTest) GetFeedChannel() <-chan tickers.Tick {
 	return c
 }
 
+func (t *TickersSourceTest) ReleaseFeedChannel(c <-chan tickers.Tick) {
+	t.chLock.Lock()
+	defer t.chLock.Unlock()
+
+	for i, v := range t.ch {
+		if v == c {
+			t.ch = append(t.ch[:i], t.ch[i+1:]...)
+			close(v)
+			return
+		}
+	}
+}
+
 func (t *TickersSourceTest) CloseFeed() {
 	t.chLock.Lock()
 	defer t.chLock.Unlock()
@@ -51,9 +69,13 @@ func (t *TickersSourceTest) CloseFeed() {
 	for _, c := range t.ch {
 		close(c)
 	}
+	t.ch = nil
 }
 
 func (t *TickersSourceTest) Run(tickers []tickers.Tick) {
+	t.chLock.RLock()
+	defer t.chLock.RUnlock()
+
 	for _, v := range tickers {
 		for _, c := range t.ch {
 			c <- v
@@ -62,12 +84,15 @@ func (t *TickersSourceTest) Run(tickers []tickers.Tick) {
 }
 
 type PlainOHLCV struct {
+	Time   int32
 	Open   float32
 	High   float32
 	Low    float32
 	Close  float32
 	Volume int32
 	Ticker string
+	VWAP   float32
+	Trades int32
 }
 
 type StatTests struct {
@@ -76,47 +101,55 @@ type StatTests struct {
 }
 
 var (
+	simStart = time.Date(2019, 5, 17, 10, 0, 0, 0, time.UTC)
+
 	stattests = []StatTests{
 		{
 			tickers: []tickers.Tick{
 				{
 					Ticker:    "SPFB.RTS",
-					Timestamp: time.Now().Add(time.Second * 3),
+					Timestamp: simStart.Add(time.Millisecond * 300),
 					Last:      100,
 					Vol:       1,
 				},
 			},
 			expected: PlainOHLCV{
+				Time:   int32(simStart.Add(time.Second).Unix()),
 				Open:   100,
 				High:   100,
 				Low:    100,
 				Close:  100,
 				Volume: 1,
 				Ticker: "SPFB.RTS",
+				VWAP:   100,
+				Trades: 1,
 			},
 		},
 		{
 			tickers: []tickers.Tick{
 				{
 					Ticker:    "SPFB.RTS",
-					Timestamp: time.Now().Add(time.Second * 2),
+					Timestamp: simStart.Add(time.Millisecond * 1200),
 					Last:      100,
 					Vol:       1,
 				},
 				{
 					Ticker:    "SPFB.RTS",
-					Timestamp: time.Now().Add(time.Second * 3),
+					Timestamp: simStart.Add(time.Millisecond * 1300),
 					Last:      50,
 					Vol:       3,
 				},
 			},
 			expected: PlainOHLCV{
+				Time:   int32(simStart.Add(2 * time.Second).Unix()),
 				Open:   100,
 				High:   100,
 				Low:    50,
 				Close:  50,
 				Volume: 4,
 				Ticker: "SPFB.RTS",
+				VWAP:   62.5,
+				Trades: 2,
 			},
 		},
 	}
@@ -132,31 +165,40 @@ func TestStat(t *testing.T) {
 		ch:     make([]chan tickers.Tick, 0, 2),
 	}
 
+	clk := clock.NewManual(simStart)
+
 	ctx, finish := context.WithCancel(context.Background())
-	go Start(ctx, listenAddr, ``, ts)
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- StartWithConfig(ctx, Config{
+			ListenAddr: listenAddr,
+			BufferSize: 100,
+			Clock:      clk,
+		}, ts)
+	}()
 
 	conn := getGrpcConn(t)
-	defer conn.Close()
 
 	brokerid := exchange.BrokerID{
 		ID: 123,
 	}
 	exch := exchange.NewExchangeClient(conn)
-	statStream1, err := exch.Statistic(context.Background(), &brokerid)
+	statStream1, err := exch.Statistic(context.Background(), &brokerid, grpc.WaitForReady(true))
 	if err != nil {
 		t.Fatalf("cant get stat stream: %v", err)
 	}
 
-	wait(3)
+	// candles and statistic interval tickers are running, time can be moved
+	clk.WaitTickers(2)
 
 	for j, v := range stattests {
 		t.Logf("executing stat test %v\n", j)
 		ohclv1 := PlainOHLCV{}
+		//feed with data, then close the interval
+		ts.Run(v.tickers)
+		clk.Advance(time.Second)
+
 		//reading results
-		//feed with data
-		go func() {
-			ts.Run(v.tickers)
-		}()
 
 		for i := 0; i < 1; i++ {
 			stat, err := statStream1.Recv()
@@ -165,12 +207,15 @@ func TestStat(t *testing.T) {
 			}
 
 			ohclv1 = PlainOHLCV{
+				Time:   stat.Time,
 				Open:   stat.Open,
 				High:   stat.High,
 				Low:    stat.Low,
 				Close:  stat.Close,
 				Volume: stat.Volume,
 				Ticker: stat.Ticker,
+				VWAP:   stat.VWAP,
+				Trades: stat.Trades,
 			}
 		}
 
@@ -182,5 +227,305 @@ func TestStat(t *testing.T) {
 
 	}
 
+	// server drains streams on stop, so client goes first
+	conn.Close()
+	finish()
+	<-stopped
+}
+
+func TestCandles(t *testing.T) {
+	ts := &TickersSourceTest{
+		chLock: &sync.RWMutex{},
+		ch:     make([]chan tickers.Tick, 0, 2),
+	}
+
+	clk := clock.NewManual(simStart)
+
+	ctx, finish := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- StartWithConfig(ctx, Config{
+			ListenAddr: listenAddr,
+			BufferSize: 100,
+			Clock:      clk,
+		}, ts)
+	}()
+
+	conn := getGrpcConn(t)
+	exch := exchange.NewExchangeClient(conn)
+
+	_, err := exch.GetCandles(context.Background(), &exchange.CandlesRequest{Interval: 5}, grpc.WaitForReady(true))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("unexpected error for unknown interval: %v", err)
+	}
+
+	clk.WaitTickers(1)
+	ts.Run([]tickers.Tick{
+		{Ticker: "SPFB.RTS", Timestamp: simStart.Add(300 * time.Millisecond), Last: 100, Vol: 1},
+		{Ticker: "SPFB.Si", Timestamp: simStart.Add(500 * time.Millisecond), Last: 60, Vol: 2},
+		{Ticker: "SPFB.RTS", Timestamp: simStart.Add(1200 * time.Millisecond), Last: 110, Vol: 1},
+		{Ticker: "SPFB.RTS", Timestamp: simStart.Add(1700 * time.Millisecond), Last: 104, Vol: 3},
+		{Ticker: "SPFB.RTS", Timestamp: simStart.Add(2500 * time.Millisecond), Last: 90, Vol: 1},
+	})
+
+	expected := []PlainOHLCV{
+		{Time: int32(simStart.Add(time.Second).Unix()), Open: 100, High: 100, Low: 100, Close: 100, Volume: 1, Ticker: "SPFB.RTS", VWAP: 100, Trades: 1},
+		{Time: int32(simStart.Add(time.Second).Unix()), Open: 60, High: 60, Low: 60, Close: 60, Volume: 2, Ticker: "SPFB.Si", VWAP: 60, Trades: 1},
+		{Time: int32(simStart.Add(2 * time.Second).Unix()), Open: 110, High: 110, Low: 104, Close: 104, Volume: 4, Ticker: "SPFB.RTS", VWAP: 105.5, Trades: 2},
+		{Time: int32(simStart.Add(3 * time.Second).Unix()), Open: 90, High: 90, Low: 90, Close: 90, Volume: 1, Ticker: "SPFB.RTS", VWAP: 90, Trades: 1},
+	}
+
+	// bars are closed by candles storage goroutine, so poll until the last one is there
+	var have []PlainOHLCV
+	for i := 0; i < 100 && len(have) < len(expected); i++ {
+		clk.Advance(time.Second)
+		wait(1)
+
+		have = have[:0]
+		req := &exchange.CandlesRequest{
+			Interval: 1,
+			From:     int32(simStart.Unix()),
+			To:       int32(simStart.Add(time.Minute).Unix()),
+			PageSize: 2,
+		}
+		for {
+			resp, err := exch.GetCandles(context.Background(), req)
+			if err != nil {
+				t.Fatalf("cant get candles: %v", err)
+			}
+			for _, c := range resp.Candles {
+				have = append(have, PlainOHLCV{
+					Time:   c.Time,
+					Open:   c.Open,
+					High:   c.High,
+					Low:    c.Low,
+					Close:  c.Close,
+					Volume: c.Volume,
+					Ticker: c.Ticker,
+					VWAP:   c.VWAP,
+					Trades: c.Trades,
+				})
+			}
+			if resp.NextPageToken == "" {
+				break
+			}
+			req.PageToken = resp.NextPageToken
+		}
+	}
+
+	if !reflect.DeepEqual(have, expected) {
+		t.Fatalf("candles dont match\nhave %+v\nwant %+v", have, expected)
+	}
+
+	conn.Close()
+	finish()
+	<-stopped
+}
+
+func TestTrades(t *testing.T) {
+	ts := &TickersSourceTest{
+		chLock: &sync.RWMutex{},
+		ch:     make([]chan tickers.Tick, 0, 2),
+	}
+
+	ctx, finish := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- StartWithConfig(ctx, Config{
+			ListenAddr: listenAddr,
+			BufferSize: 100,
+			Clock:      clock.NewManual(simStart),
+		}, ts)
+	}()
+
+	conn := getGrpcConn(t)
+	exch := exchange.NewExchangeClient(conn)
+
+	tape, err := exch.Trades(context.Background(), &exchange.BrokerID{ID: 123}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("cant get trades stream: %v", err)
+	}
+	// headers are sent after subscription
+	_, err = tape.Header()
+	if err != nil {
+		t.Fatalf("cant get trades stream headers: %v", err)
+	}
+
+	orders := []*exchange.Deal{
+		{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 2, Price: 100},
+		{BrokerID: 124, ClientID: 7, Ticker: "SPFB.RTS", Volume: 1, Price: -80},
+	}
+	for _, o := range orders {
+		_, err := exch.Create(context.Background(), o)
+		if err != nil {
+			t.Fatalf("cant create order: %v", err)
+		}
+	}
+
+	ts.Run([]tickers.Tick{
+		{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 90, Vol: 5},
+	})
+
+	expected := []*exchange.Trade{
+		{ID: 1, Ticker: "SPFB.RTS", Price: 90, Volume: 2, Aggressor: exchange.Side_BUY, Time: int32(simStart.Unix())},
+		{ID: 2, Ticker: "SPFB.RTS", Price: 90, Volume: 1, Aggressor: exchange.Side_SELL, Time: int32(simStart.Unix())},
+	}
+	for i, want := range expected {
+		have, err := tape.Recv()
+		if err != nil {
+			t.Fatalf("cant receive trade %v: %v", i, err)
+		}
+		if have.ID != want.ID || have.Ticker != want.Ticker || have.Price != want.Price ||
+			have.Volume != want.Volume || have.Aggressor != want.Aggressor || have.Time != want.Time {
+			t.Fatalf("trade %v dont match\nhave %v\nwant %v", i, have, want)
+		}
+	}
+
+	conn.Close()
+	finish()
+	<-stopped
+}
+
+type readyTickersSource struct {
+	*TickersSourceTest
+	ready chan struct{}
+}
+
+func (r *readyTickersSource) Ready() <-chan struct{} {
+	return r.ready
+}
+
+func TestHealthAndDrain(t *testing.T) {
+	ts := &readyTickersSource{
+		TickersSourceTest: &TickersSourceTest{
+			chLock: &sync.RWMutex{},
+			ch:     make([]chan tickers.Tick, 0, 2),
+		},
+		ready: make(chan struct{}),
+	}
+
+	ctx, finish := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- StartWithConfig(ctx, Config{
+			ListenAddr: listenAddr,
+			BufferSize: 100,
+			Clock:      clock.NewManual(simStart),
+		}, ts)
+	}()
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	hc := healthpb.NewHealthClient(conn)
+	exch := exchange.NewExchangeClient(conn)
+
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	watch, err := hc.Watch(watchCtx, &healthpb.HealthCheckRequest{Service: "main.Exchange"}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("cant watch health: %v", err)
+	}
+	expectStatus := func(want healthpb.HealthCheckResponse_ServingStatus) {
+		resp, err := watch.Recv()
+		if err != nil {
+			t.Fatalf("cant receive health status: %v", err)
+		}
+		if resp.Status != want {
+			t.Fatalf("health status dont match\nhave %v\nwant %v", resp.Status, want)
+		}
+	}
+
+	// tickers are not loaded yet
+	expectStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	close(ts.ready)
+	expectStatus(healthpb.HealthCheckResponse_SERVING)
+
+	results, err := exch.Results(context.Background(), &exchange.BrokerID{ID: 123})
+	if err != nil {
+		t.Fatalf("cant get results stream: %v", err)
+	}
+	_, err = exch.Create(context.Background(), &exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 1, Price: 100})
+	if err != nil {
+		t.Fatalf("cant create order: %v", err)
+	}
+	ts.Run([]tickers.Tick{
+		{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 90, Vol: 5},
+	})
+
+	finish()
+
+	// fill made before shutdown is delivered, then stream is closed by server
+	deal, err := results.Recv()
+	if err != nil {
+		t.Fatalf("cant receive fill: %v", err)
+	}
+	if deal.Volume != 1 || deal.Price != 90 {
+		t.Fatalf("unexpected fill %v", deal)
+	}
+	_, err = results.Recv()
+	if err != io.EOF {
+		t.Fatalf("results stream is not closed: %v", err)
+	}
+
+	expectStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	// graceful stop waits for open watches
+	stopWatch()
+
+	err = <-stopped
+	if err != nil {
+		t.Fatalf("server stopped with error: %v", err)
+	}
+}
+
+func TestFillReports(t *testing.T) {
+	ts := &TickersSourceTest{
+		chLock: &sync.RWMutex{},
+		ch:     make([]chan tickers.Tick, 0, 2),
+	}
+
+	ctx, finish := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- StartWithConfig(ctx, Config{
+			ListenAddr: listenAddr,
+			BufferSize: 100,
+			Clock:      clock.NewManual(simStart),
+		}, ts)
+	}()
+
+	conn := getGrpcConn(t)
+	exch := exchange.NewExchangeClient(conn)
+
+	dealID, err := exch.Create(context.Background(), &exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 5, Price: 100}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("cant create order: %v", err)
+	}
+	results, err := exch.Results(context.Background(), &exchange.BrokerID{ID: 123})
+	if err != nil {
+		t.Fatalf("cant get results stream: %v", err)
+	}
+
+	ts.Run([]tickers.Tick{
+		{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 90, Vol: 2},
+		{Ticker: "SPFB.RTS", Timestamp: simStart.Add(time.Second), Last: 95, Vol: 5},
+	})
+
+	expected := []*exchange.Deal{
+		{Volume: 2, Price: 90, CumVolume: 2, LeavesVolume: 3, AvgPrice: 90, Status: exchange.OrderStatus_PARTIALLY_FILLED, Partial: true},
+		{Volume: 3, Price: 95, CumVolume: 5, LeavesVolume: 0, AvgPrice: 93, Status: exchange.OrderStatus_FILLED, Partial: false},
+	}
+	for i, want := range expected {
+		have, err := results.Recv()
+		if err != nil {
+			t.Fatalf("cant receive report %v: %v", i, err)
+		}
+		if have.ID != dealID.ID || have.Volume != want.Volume || have.Price != want.Price ||
+			have.CumVolume != want.CumVolume || have.LeavesVolume != want.LeavesVolume ||
+			have.AvgPrice != want.AvgPrice || have.Status != want.Status || have.Partial != want.Partial {
+			t.Fatalf("report %v dont match\nhave %v\nwant %v", i, have, want)
+		}
+	}
+
+	conn.Close()
 	finish()
+	<-stopped
 }
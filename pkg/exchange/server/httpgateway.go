@@ -0,0 +1,286 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/gorilla/mux"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ConsumerHeader is passed to grpc "consumer" metadata checked by ACL
+const ConsumerHeader = "X-Consumer"
+
+// HTTPGateway serves Exchange rpcs as JSON over HTTP and server streams as Server-Sent Events.
+// Calls go through the same interceptors as grpc ones: consumer is taken from X-Consumer header
+// and broker identity from client certificate of the HTTPS connection.
+type HTTPGateway struct {
+	srv    exchange.ExchangeServer
+	unary  []grpc.UnaryServerInterceptor
+	stream []grpc.StreamServerInterceptor
+
+	// admin routes are served only to admin client certificates, forbidden if nil
+	identity *BrokerIdentity
+}
+
+var jsonOut = protojson.MarshalOptions{EmitUnpopulated: true}
+
+func (g *HTTPGateway) Handler() http.Handler {
+	r := mux.NewRouter()
+	api := r.PathPrefix("/api/v1").Subrouter()
+
+	api.HandleFunc("/orders", g.unaryHandler("Create")).Methods(http.MethodPost)
+	api.HandleFunc("/orders", g.unaryHandler("Orders")).Methods(http.MethodGet)
+	api.HandleFunc("/orders/{ID}", g.unaryHandler("Orders")).Methods(http.MethodGet)
+	api.HandleFunc("/orders/{ID}", g.unaryHandler("Cancel")).Methods(http.MethodDelete)
+	api.HandleFunc("/candles", g.unaryHandler("GetCandles")).Methods(http.MethodGet)
+	api.HandleFunc("/clearing", g.unaryHandler("Clearing")).Methods(http.MethodGet)
+	api.HandleFunc("/contracts", g.unaryHandler("Contracts")).Methods(http.MethodGet)
+	api.HandleFunc("/chaos", g.adminHandler("SetChaos")).Methods(http.MethodPost)
+
+	api.HandleFunc("/statistic", g.streamHandler("Statistic")).Methods(http.MethodGet)
+	api.HandleFunc("/results", g.streamHandler("Results")).Methods(http.MethodGet)
+	api.HandleFunc("/trades", g.streamHandler("Trades")).Methods(http.MethodGet)
+
+	return r
+}
+
+func fullMethod(name string) string {
+	return fmt.Sprintf("/%v/%v", exchange.Exchange_ServiceDesc.ServiceName, name)
+}
+
+// context as grpc server would build it for the call
+func incomingContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if consumer := r.Header.Get(ConsumerHeader); consumer != "" {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("consumer", consumer))
+	}
+	if r.TLS != nil {
+		ctx = peer.NewContext(ctx, &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: *r.TLS},
+		})
+	}
+	return ctx
+}
+
+// decoder fills request message from JSON body or from query and path parameters named as proto fields,
+// like /api/v1/orders/123?BrokerID=1
+func decoder(r *http.Request) func(interface{}) error {
+	return func(m interface{}) error {
+		msg, ok := m.(proto.Message)
+		if !ok {
+			return status.Errorf(codes.Internal, "unexpected request type %T", m)
+		}
+
+		var data []byte
+		var err error
+		if r.Method == http.MethodPost {
+			data, err = io.ReadAll(r.Body)
+		} else {
+			fields := make(map[string]string, len(r.URL.Query()))
+			for k, v := range r.URL.Query() {
+				fields[k] = v[0]
+			}
+			for k, v := range mux.Vars(r) {
+				fields[k] = v
+			}
+			data, err = json.Marshal(fields)
+		}
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		err = protojson.Unmarshal(data, msg)
+		if err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil
+	}
+}
+
+// adminHandler serves rpc which affects all brokers. Unlike grpc it is never open,
+// HTTPS connection must present admin client certificate even if identity checks are off.
+func (g *HTTPGateway) adminHandler(name string) http.HandlerFunc {
+	next := g.unaryHandler(name)
+	return func(w http.ResponseWriter, r *http.Request) {
+		if g.identity == nil {
+			writeError(w, status.Errorf(codes.PermissionDenied, "%v requires admin certificate", name))
+			return
+		}
+		err := g.identity.admin(incomingContext(r), fullMethod(name))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (g *HTTPGateway) unaryHandler(name string) http.HandlerFunc {
+	var desc grpc.MethodDesc
+	for _, md := range exchange.Exchange_ServiceDesc.Methods {
+		if md.MethodName == name {
+			desc = md
+		}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp, err := desc.Handler(g.srv, incomingContext(r), decoder(r), func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			for i := len(g.unary) - 1; i >= 0; i-- {
+				next, interceptor := handler, g.unary[i]
+				handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+					return interceptor(ctx, req, info, next)
+				}
+			}
+			return handler(ctx, req)
+		})
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		data, err := jsonOut.Marshal(resp.(proto.Message))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
+
+func (g *HTTPGateway) streamHandler(name string) http.HandlerFunc {
+	var desc grpc.StreamDesc
+	for _, sd := range exchange.Exchange_ServiceDesc.Streams {
+		if sd.StreamName == name {
+			desc = sd
+		}
+	}
+	info := &grpc.StreamServerInfo{FullMethod: fullMethod(name), IsServerStream: true}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, status.Error(codes.Unimplemented, "streaming is not supported by connection"))
+			return
+		}
+		ss := &sseStream{
+			ctx:     incomingContext(r),
+			w:       w,
+			flusher: flusher,
+			decode:  decoder(r),
+		}
+
+		handler := desc.Handler
+		for i := len(g.stream) - 1; i >= 0; i-- {
+			next, interceptor := handler, g.stream[i]
+			handler = func(srv interface{}, ss grpc.ServerStream) error {
+				return interceptor(srv, ss, info, next)
+			}
+		}
+
+		err := handler(g.srv, ss)
+		if err == nil {
+			return
+		}
+		if !ss.headerSent {
+			writeError(w, err)
+			return
+		}
+		// status code is already sent, error goes as the last event
+		st := status.Convert(err)
+		data, _ := jsonOut.Marshal(st.Proto())
+		fmt.Fprintf(w, "event: error\ndata: %s\n\n", data)
+		flusher.Flush()
+	}
+}
+
+// sseStream is grpc server stream writing each message as Server-Sent Event
+type sseStream struct {
+	ctx        context.Context
+	w          http.ResponseWriter
+	flusher    http.Flusher
+	decode     func(interface{}) error
+	headerSent bool
+}
+
+func (s *sseStream) SetHeader(metadata.MD) error { return nil }
+func (s *sseStream) SetTrailer(metadata.MD)      {}
+func (s *sseStream) Context() context.Context    { return s.ctx }
+func (s *sseStream) RecvMsg(m interface{}) error { return s.decode(m) }
+
+func (s *sseStream) SendHeader(metadata.MD) error {
+	if s.headerSent {
+		return nil
+	}
+	s.headerSent = true
+	s.w.Header().Set("Content-Type", "text/event-stream")
+	s.w.Header().Set("Cache-Control", "no-cache")
+	s.w.WriteHeader(http.StatusOK)
+	s.flusher.Flush()
+	return nil
+}
+
+func (s *sseStream) SendMsg(m interface{}) error {
+	s.SendHeader(nil)
+
+	data, err := jsonOut.Marshal(m.(proto.Message))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "data: %s\n\n", data)
+	if err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	st := status.Convert(err)
+	data, _ := jsonOut.Marshal(st.Proto())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus(st.Code()))
+	w.Write(data)
+}
+
+// same mapping as grpc-gateway uses
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
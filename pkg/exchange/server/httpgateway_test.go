@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"github.com/KSerditov/Trading/pkg/tlsutil"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestHTTPGateway(t *testing.T) {
+	e := newTestExchange(t)
+	auther := &Authenticator{accessList: map[string][]string{
+		"broker": {"/" + exchange.Exchange_ServiceDesc.ServiceName + "/*"},
+	}}
+	gw := &HTTPGateway{
+		srv:    e,
+		unary:  []grpc.UnaryServerInterceptor{auther.AuthInterceptor},
+		stream: []grpc.StreamServerInterceptor{auther.AuthStreamInterceptor},
+	}
+	ts := httptest.NewServer(gw.Handler())
+	defer ts.Close()
+
+	call := func(method, path, consumer, body string, wantCode int, resp proto.Message) {
+		t.Helper()
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("cant build request: %v", err)
+		}
+		if consumer != "" {
+			req.Header.Set(ConsumerHeader, consumer)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%v %v failed: %v", method, path, err)
+		}
+		defer res.Body.Close()
+
+		if res.StatusCode != wantCode {
+			t.Fatalf("%v %v status dont match\nhave %v\nwant %v", method, path, res.StatusCode, wantCode)
+		}
+		if resp == nil {
+			return
+		}
+		data, _ := io.ReadAll(res.Body)
+		err = protojson.Unmarshal(data, resp)
+		if err != nil {
+			t.Fatalf("cant decode %q: %v", data, err)
+		}
+	}
+
+	call(http.MethodPost, "/api/v1/orders", "", `{"BrokerID": 123, "Ticker": "SPFB.RTS", "Volume": 2, "Price": 100}`, http.StatusUnauthorized, nil)
+	call(http.MethodPost, "/api/v1/orders", "broker", `{"BrokerID": "abc"}`, http.StatusBadRequest, nil)
+
+	id := &exchange.DealID{}
+	call(http.MethodPost, "/api/v1/orders", "broker", `{"BrokerID": 123, "ClientID": 1, "Ticker": "SPFB.RTS", "Volume": 2, "Price": 100}`, http.StatusOK, id)
+	if id.ID == 0 || id.BrokerID != 123 {
+		t.Fatalf("unexpected deal id %v", id)
+	}
+	path := fmt.Sprintf("/api/v1/orders/%v?BrokerID=123", id.ID)
+
+	orders := &exchange.OrdersResponse{}
+	call(http.MethodGet, "/api/v1/orders?BrokerID=123&Ticker=SPFB.RTS", "broker", "", http.StatusOK, orders)
+	if len(orders.Orders) != 1 || orders.Orders[0].ID != id.ID {
+		t.Fatalf("unexpected orders %v", orders)
+	}
+	call(http.MethodGet, "/api/v1/orders?BrokerID=1", "broker", "", http.StatusOK, orders)
+	if len(orders.Orders) != 0 {
+		t.Fatalf("orders of other broker returned: %v", orders)
+	}
+
+	// resting order trades and goes to the tape
+	e.Channels[123] = make(chan *exchange.Deal, 1)
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/trades?ID=123", nil)
+	req.Header.Set(ConsumerHeader, "broker")
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("cant subscribe to trades: %v", err)
+	}
+	defer stream.Body.Close()
+	if ct := stream.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	s := e.shardFor("SPFB.RTS")
+	s.call(func() { s.match(tickers.Tick{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 100, Vol: 1}) })
+
+	line, err := bufio.NewReader(stream.Body).ReadString('\n')
+	if err != nil {
+		t.Fatalf("cant read event: %v", err)
+	}
+	trade := &exchange.Trade{}
+	err = protojson.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "data: ")), trade)
+	if err != nil {
+		t.Fatalf("cant decode event %q: %v", line, err)
+	}
+	if trade.Ticker != "SPFB.RTS" || trade.Volume != 1 {
+		t.Fatalf("unexpected trade %v", trade)
+	}
+
+	one := &exchange.OrdersResponse{}
+	call(http.MethodGet, path, "broker", "", http.StatusOK, one)
+	if len(one.Orders) != 1 || one.Orders[0].Volume != 1 {
+		t.Fatalf("unexpected order %v", one)
+	}
+
+	call(http.MethodDelete, path, "broker", "", http.StatusOK, &exchange.CancelResult{})
+	call(http.MethodDelete, path, "broker", "", http.StatusNotFound, nil)
+	call(http.MethodGet, path, "broker", "", http.StatusNotFound, nil)
+}
+
+func TestHTTPGatewayChaos(t *testing.T) {
+	dir := t.TempDir()
+	err := tlsutil.GenerateTestCA(dir, []string{"127.0.0.1"}, []string{"broker123"})
+	if err != nil {
+		t.Fatalf("cant generate test ca: %v", err)
+	}
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+	serverTLS, err := tlsutil.ServerConfig(path("exchange.pem"), path("exchange-key.pem"), path("ca.pem"))
+	if err != nil {
+		t.Fatalf("cant load server tls: %v", err)
+	}
+
+	e := newTestExchange(t)
+	chaos, _ := NewChaos(nil)
+	e.Chaos = chaos
+	auther := &Authenticator{accessList: map[string][]string{
+		"broker": {"/" + exchange.Exchange_ServiceDesc.ServiceName + "/*"},
+	}}
+	identity := NewBrokerIdentity(map[string]int64{"broker123": 123}, []string{"exchange"})
+	gw := &HTTPGateway{
+		srv:      e,
+		unary:    []grpc.UnaryServerInterceptor{auther.AuthInterceptor, identity.UnaryInterceptor},
+		stream:   []grpc.StreamServerInterceptor{auther.AuthStreamInterceptor, identity.StreamInterceptor},
+		identity: identity,
+	}
+	ts := httptest.NewUnstartedServer(gw.Handler())
+	ts.TLS = serverTLS
+	ts.StartTLS()
+	defer ts.Close()
+
+	setChaos := func(cert string) int {
+		t.Helper()
+		clientTLS, err := tlsutil.ClientConfig(path("ca.pem"), path(cert+".pem"), path(cert+"-key.pem"), "")
+		if err != nil {
+			t.Fatalf("cant load client tls: %v", err)
+		}
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/api/v1/chaos", strings.NewReader(`{"DropProbability": 1}`))
+		req.Header.Set(ConsumerHeader, "broker")
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("cant set chaos: %v", err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if code := setChaos("broker123"); code != http.StatusForbidden {
+		t.Fatalf("chaos by broker: status %v", code)
+	}
+	if settings := e.Chaos.Settings(); settings.DropProbability != 0 {
+		t.Fatalf("chaos must stay off: %v", settings)
+	}
+	if code := setChaos("exchange"); code != http.StatusOK {
+		t.Fatalf("chaos by admin: status %v", code)
+	}
+	if settings := e.Chaos.Settings(); settings.DropProbability != 1 {
+		t.Fatalf("chaos is not set: %v", settings)
+	}
+
+	// without identity checks the route is still closed
+	plain := httptest.NewServer((&HTTPGateway{srv: e}).Handler())
+	defer plain.Close()
+	req, _ := http.NewRequest(http.MethodPost, plain.URL+"/api/v1/chaos", strings.NewReader(`{}`))
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("cant set chaos: %v", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusForbidden {
+		t.Fatalf("chaos without identity: status %v", res.StatusCode)
+	}
+}
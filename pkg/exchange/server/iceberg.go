@@ -0,0 +1,28 @@
+package server
+
+// displayed is volume which may trade and is visible in the book,
+// only current peak for iceberg
+func (o *bookOrder) displayed() int32 {
+	if o.peak == 0 || o.shown > o.deal.Volume {
+		return o.deal.Volume
+	}
+	return o.shown
+}
+
+// fillIceberg takes executed volume from shown peak and shows next one from reserve
+// when peak is gone, true means order got new peak
+func (o *bookOrder) fillIceberg(volume int32) bool {
+	if o.peak == 0 || o.deal.Volume == 0 {
+		return false
+	}
+
+	o.shown -= volume
+	if o.shown > 0 {
+		return false
+	}
+	o.shown = o.peak
+	if o.shown > o.deal.Volume {
+		o.shown = o.deal.Volume
+	}
+	return true
+}
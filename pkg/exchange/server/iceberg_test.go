@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+)
+
+func TestIceberg(t *testing.T) {
+	e := newTestExchange(t)
+
+	ice, err := e.Create(context.Background(), &exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 10, Price: 100, DisplayVolume: 3})
+	if err != nil {
+		t.Fatalf("cant create iceberg: %v", err)
+	}
+	plain, err := e.Create(context.Background(), &exchange.Deal{BrokerID: 123, ClientID: 2, Ticker: "SPFB.RTS", Volume: 5, Price: 100})
+	if err != nil {
+		t.Fatalf("cant create order: %v", err)
+	}
+	_, err = e.Create(context.Background(), &exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 5, Price: 100, DisplayVolume: -1})
+	if err == nil {
+		t.Fatalf("expected error for negative display volume")
+	}
+
+	checkDepth := func(want int32) {
+		t.Helper()
+		bids, _ := e.Depth("SPFB.RTS")
+		if !reflect.DeepEqual(bids, []DepthLevel{{Price: 100, Volume: want}}) {
+			t.Fatalf("depth dont match\nhave %v\nwant %v", bids, want)
+		}
+	}
+	// only the peak is visible
+	checkDepth(8)
+
+	// iceberg shows the whole peak first, then goes behind the plain order with new peak
+	s := e.shardFor("SPFB.RTS")
+	s.call(func() { s.match(tickers.Tick{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 100, Vol: 4}) })
+	checkDepth(7)
+	s.call(func() {
+		s.match(tickers.Tick{Ticker: "SPFB.RTS", Timestamp: simStart.Add(time.Second), Last: 100, Vol: 5})
+	})
+	checkDepth(2)
+
+	expected := []struct {
+		id     int64
+		volume int32
+		leaves int32
+	}{
+		{ice.ID, 3, 7},
+		{plain.ID, 1, 4},
+		{plain.ID, 4, 0},
+		{ice.ID, 1, 6},
+	}
+	results := e.Channels[123]
+	for i, want := range expected {
+		have := <-results
+		if have.ID != want.id || have.Volume != want.volume || have.LeavesVolume != want.leaves {
+			t.Fatalf("report %v dont match\nhave %v\nwant %+v", i, have, want)
+		}
+	}
+}
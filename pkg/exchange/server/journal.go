@@ -0,0 +1,336 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrorJournalGap       = errors.New("journal entry is out of sequence")
+	ErrorJournalFormat    = errors.New("not a journal file")
+	ErrorJournalTruncated = errors.New("journal file ends in the middle of entry")
+)
+
+// journal file starts with magic and version, then every entry is uvarint length and marshaled JournalEntry
+var journalMagic = []byte{'J', 'R', 'N', 'L', 1}
+
+// Journal keeps every order and execution of the session in sequence,
+// standby replays it to get the same books, positions and Results.
+// Entries are copies and are never changed after append.
+type Journal struct {
+	lock    *sync.RWMutex
+	entries []*exchange.JournalEntry // entry with Seq n is entries[n-1]
+	updated chan struct{}            // closed and replaced on every append
+
+	clock clock.Clock // entries get no Time if nil
+
+	// file of Record is written by its own goroutine, so shards don't wait for disk.
+	// Ticks are written only to it and only while it is recorded.
+	recording int32 // atomic, 1 while records are taken
+	records   chan *exchange.JournalEntry
+	written   chan error // result of the file when writer is done
+}
+
+// entries queued to file writer
+const journalRecordsSize = 4096
+
+func NewJournal() *Journal {
+	return &Journal{
+		lock:    &sync.RWMutex{},
+		entries: make([]*exchange.JournalEntry, 0, 1024),
+		updated: make(chan struct{}),
+	}
+}
+
+// execution reports sent to brokers in Results
+func isReport(typ exchange.JournalEntryType) bool {
+	switch typ {
+	case exchange.JournalEntryType_ORDER_FILLED, exchange.JournalEntryType_ORDER_EXPIRED, exchange.JournalEntryType_POSITION_SETTLED:
+		return true
+	}
+	return false
+}
+
+// Append records deal with the next sequence number, execution report gets it as ExecSeq
+func (j *Journal) Append(typ exchange.JournalEntryType, deal *exchange.Deal, side exchange.Side) int64 {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	seq := int64(len(j.entries)) + 1
+	if isReport(typ) {
+		deal.ExecSeq = seq
+	}
+	j.add(&exchange.JournalEntry{
+		Seq:  seq,
+		Type: typ,
+		Deal: proto.Clone(deal).(*exchange.Deal),
+		Side: side,
+		Time: j.now(),
+	})
+	return seq
+}
+
+// Tick writes tick matched by shard to journal file, so exchange-replay gives shards
+// the same ticks between the same orders. Tick is not kept in journal and is not replicated,
+// it gets Seq of the entry it goes after. Ticks of shards don't wait for each other, only for Append.
+func (j *Journal) Tick(t tickers.Tick) {
+	if atomic.LoadInt32(&j.recording) == 0 {
+		return
+	}
+
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
+	if atomic.LoadInt32(&j.recording) == 0 {
+		return
+	}
+	j.records <- &exchange.JournalEntry{
+		Seq:  int64(len(j.entries)),
+		Type: exchange.JournalEntryType_TICK,
+		Time: j.now(),
+		Tick: &exchange.JournalTick{
+			Ticker: t.Ticker,
+			Time:   t.Timestamp.UnixNano(),
+			Last:   t.Last,
+			Vol:    t.Vol,
+		},
+	}
+}
+
+// lock must be held
+func (j *Journal) now() int64 {
+	if j.clock == nil {
+		return 0
+	}
+	return j.clock.Now().UnixNano()
+}
+
+// Apply records entry received from primary, it must go right after the last one
+func (j *Journal) Apply(entry *exchange.JournalEntry) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if entry.Seq != int64(len(j.entries))+1 {
+		return ErrorJournalGap
+	}
+	j.add(entry)
+	return nil
+}
+
+// lock must be held
+func (j *Journal) add(entry *exchange.JournalEntry) {
+	j.entries = append(j.entries, entry)
+	close(j.updated)
+	j.updated = make(chan struct{})
+
+	if atomic.LoadInt32(&j.recording) == 1 {
+		j.records <- entry
+	}
+}
+
+// Record writes entries already in journal and every next one to new file at path
+func (j *Journal) Record(path string, logger *zap.SugaredLogger) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("can't create journal file: %w", err)
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	w := newJournalWriter(file)
+	_, err = w.writer.Write(journalMagic)
+	for _, entry := range j.entries {
+		if err != nil {
+			break
+		}
+		err = w.write(entry)
+	}
+	if err == nil {
+		err = w.writer.Flush()
+	}
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("can't write journal file: %w", err)
+	}
+
+	j.records = make(chan *exchange.JournalEntry, journalRecordsSize)
+	j.written = make(chan error, 1)
+	atomic.StoreInt32(&j.recording, 1)
+	go j.writeFile(w, j.records, j.written, logger)
+	return nil
+}
+
+// writeFile writes records until they are closed, buffer is flushed whenever queue is empty.
+// On error recording is stopped and the rest of queue is dropped.
+func (j *Journal) writeFile(w *journalWriter, records <-chan *exchange.JournalEntry, written chan<- error, logger *zap.SugaredLogger) {
+	var err error
+	for entry := range records {
+		if err != nil {
+			continue
+		}
+		err = w.write(entry)
+		if err == nil && len(records) == 0 {
+			err = w.writer.Flush()
+		}
+		if err != nil {
+			logger.Errorw("Can't write journal, recording is stopped", "file", w.file.Name(), "error", err)
+			atomic.StoreInt32(&j.recording, 0)
+		}
+	}
+
+	if err == nil {
+		err = w.writer.Flush()
+	}
+	if errc := w.file.Close(); err == nil {
+		err = errc
+	}
+	written <- err
+}
+
+// Close stops recording to file and waits until queued entries are written
+func (j *Journal) Close() error {
+	j.lock.Lock()
+	records, written := j.records, j.written
+	atomic.StoreInt32(&j.recording, 0)
+	j.records = nil
+	j.written = nil
+	j.lock.Unlock()
+
+	if records == nil {
+		return nil
+	}
+	close(records)
+	return <-written
+}
+
+// journalWriter is used by one goroutine at a time
+type journalWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+	buf    [binary.MaxVarintLen64]byte
+}
+
+func newJournalWriter(file *os.File) *journalWriter {
+	return &journalWriter{
+		file:   file,
+		writer: bufio.NewWriter(file),
+	}
+}
+
+func (w *journalWriter) write(entry *exchange.JournalEntry) error {
+	data, err := proto.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	n := binary.PutUvarint(w.buf[:], uint64(len(data)))
+	_, err = w.writer.Write(w.buf[:n])
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write(data)
+	return err
+}
+
+func (j *Journal) LastSeq() int64 {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+	return int64(len(j.entries))
+}
+
+// Since returns entries after seq and channel closed on the next append
+func (j *Journal) Since(seq int64) ([]*exchange.JournalEntry, <-chan struct{}) {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
+	if seq < 0 || seq > int64(len(j.entries)) {
+		seq = int64(len(j.entries))
+	}
+	res := make([]*exchange.JournalEntry, len(j.entries)-int(seq))
+	copy(res, j.entries[seq:])
+	return res, j.updated
+}
+
+// Fills returns execution reports of broker after seq and the last seq they were looked up to,
+// seq beyond the journal is looked up from its end
+func (j *Journal) Fills(brokerID int64, seq int64) ([]*exchange.Deal, int64) {
+	j.lock.RLock()
+	defer j.lock.RUnlock()
+
+	last := int64(len(j.entries))
+	if seq < 0 || seq > last {
+		seq = last
+	}
+	res := make([]*exchange.Deal, 0, 4)
+	for _, entry := range j.entries[seq:] {
+		if isReport(entry.Type) && int64(entry.Deal.BrokerID) == brokerID {
+			res = append(res, entry.Deal)
+		}
+	}
+	return res, last
+}
+
+// JournalReader reads entries of file written by Journal.Record
+type JournalReader struct {
+	file   *os.File
+	reader *bufio.Reader
+}
+
+func OpenJournal(path string) (*JournalReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &JournalReader{
+		file:   file,
+		reader: bufio.NewReader(file),
+	}
+
+	magic := make([]byte, len(journalMagic))
+	_, err = io.ReadFull(r.reader, magic)
+	if err != nil || string(magic) != string(journalMagic) {
+		file.Close()
+		return nil, fmt.Errorf("%w: %v", ErrorJournalFormat, path)
+	}
+	return r, nil
+}
+
+// Next returns entries in file order, io.EOF after the last one.
+// Exchange stopped while writing leaves the last entry incomplete, it is ErrorJournalTruncated.
+func (r *JournalReader) Next() (*exchange.JournalEntry, error) {
+	size, err := binary.ReadUvarint(r.reader)
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, ErrorJournalTruncated
+	}
+
+	data := make([]byte, size)
+	_, err = io.ReadFull(r.reader, data)
+	if err != nil {
+		return nil, ErrorJournalTruncated
+	}
+	entry := &exchange.JournalEntry{}
+	err = proto.Unmarshal(data, entry)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrorJournalFormat, err)
+	}
+	return entry, nil
+}
+
+func (r *JournalReader) Close() error {
+	return r.file.Close()
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"github.com/KSerditov/Trading/api/exchange"
+)
+
+// client positions against exchange, needed for open interest
+type positionKey struct {
+	brokerID int32
+	clientID int32
+}
+
+// BestBidAsk returns best resting buy and sell prices for ticker, 0 if side is empty
+func (e *ExchangeSrv) BestBidAsk(ticker string) (float32, float32) {
+	var bid, ask float32
+	if s := e.existingShard(ticker); s != nil {
+		s.call(func() {
+			bid, ask = s.book.bestBidAsk()
+		})
+	}
+	return bid, ask
+}
+
+// DepthLevel is resting volume at price, hidden reserve of icebergs is not included
+type DepthLevel struct {
+	Price  float32
+	Volume int32
+}
+
+// Depth returns displayed price levels of ticker, bids and asks from the best one
+func (e *ExchangeSrv) Depth(ticker string) ([]DepthLevel, []DepthLevel) {
+	bids, asks := []DepthLevel{}, []DepthLevel{}
+	if s := e.existingShard(ticker); s != nil {
+		s.call(func() {
+			bids, asks = s.book.depth()
+		})
+	}
+	return bids, asks
+}
+
+// nil if ticker has no book yet, readers should not start shards.
+// Continuous ticker of futures has book of its front series.
+func (e *ExchangeSrv) existingShard(ticker string) *shard {
+	if e.Schedule.IsContinuous(ticker) {
+		ticker = e.Schedule.Route(ticker, e.Clock.Now())
+	}
+	e.shardsLock.RLock()
+	defer e.shardsLock.RUnlock()
+	return e.shards[ticker]
+}
+
+// OpenInterest is total size of open client positions in ticker, exchange is the other side of each of them
+func (e *ExchangeSrv) OpenInterest(ticker string) int64 {
+	var oi int64
+	if s := e.existingShard(ticker); s != nil {
+		s.call(func() {
+			for _, pos := range s.positions {
+				if pos < 0 {
+					pos = -pos
+				}
+				oi += pos
+			}
+		})
+	}
+	return oi
+}
+
+// volume is positive when client buys, called on shard goroutine
+func (s *shard) changePosition(deal *exchange.Deal, volume int64) {
+	key := positionKey{brokerID: deal.BrokerID, clientID: deal.ClientID}
+	s.positions[key] += volume
+	if s.positions[key] == 0 {
+		delete(s.positions, key)
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/KSerditov/Trading/api/exchange"
+)
+
+func TestMarketState(t *testing.T) {
+	e := newTestExchange(t)
+	for _, deal := range []*exchange.Deal{
+		{Ticker: "SPFB.RTS", Volume: 1, Price: 100},
+		{Ticker: "SPFB.RTS", Volume: 2, Price: 101},
+		{Ticker: "SPFB.RTS", Volume: 0, Price: 105}, // nothing to fill
+		{Ticker: "SPFB.RTS", Volume: 1, Price: -110},
+		{Ticker: "SPFB.RTS", Volume: 1, Price: -108},
+		{Ticker: "SPFB.Si", Volume: 1, Price: -50},
+	} {
+		if _, err := e.Create(context.Background(), deal); err != nil {
+			t.Fatalf("cant create order: %v", err)
+		}
+	}
+
+	bid, ask := e.BestBidAsk("SPFB.RTS")
+	if bid != 101 || ask != 108 {
+		t.Fatalf("SPFB.RTS best bid/ask dont match: have %v/%v, want 101/108", bid, ask)
+	}
+	bid, ask = e.BestBidAsk("SPFB.Si")
+	if bid != 0 || ask != 50 {
+		t.Fatalf("SPFB.Si best bid/ask dont match: have %v/%v, want 0/50", bid, ask)
+	}
+
+	rts, si := e.shardFor("SPFB.RTS"), e.shardFor("SPFB.Si")
+	rts.call(func() {
+		rts.changePosition(&exchange.Deal{Ticker: "SPFB.RTS", BrokerID: 1, ClientID: 1}, 3)
+		rts.changePosition(&exchange.Deal{Ticker: "SPFB.RTS", BrokerID: 1, ClientID: 2}, -2)
+		rts.changePosition(&exchange.Deal{Ticker: "SPFB.RTS", BrokerID: 1, ClientID: 1}, -1)
+	})
+	si.call(func() {
+		si.changePosition(&exchange.Deal{Ticker: "SPFB.Si", BrokerID: 2, ClientID: 1}, 5)
+		si.changePosition(&exchange.Deal{Ticker: "SPFB.Si", BrokerID: 2, ClientID: 1}, -5)
+	})
+
+	if oi := e.OpenInterest("SPFB.RTS"); oi != 4 {
+		t.Fatalf("SPFB.RTS open interest dont match: have %v, want 4", oi)
+	}
+	if oi := e.OpenInterest("SPFB.Si"); oi != 0 {
+		t.Fatalf("SPFB.Si open interest dont match: have %v, want 0", oi)
+	}
+}
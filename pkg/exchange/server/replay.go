@@ -0,0 +1,259 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/matching"
+	"github.com/KSerditov/Trading/pkg/exchange/simulation"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// fills of one tick to one broker are read after the tick, so they must fit
+const replayBufferSize = 1 << 16
+
+// Replayer repeats session recorded by Journal.Record on books of its own.
+// Orders, cancels and ticks go to shards in journal order, which is the order shards got them,
+// so the same matchers and simulation give the same fills. Fills of the journal are kept for Diff.
+type Replayer struct {
+	srv *ExchangeSrv
+	seq int64 // last applied entry
+
+	recorded map[string][]*exchange.JournalEntry // ticker -> fills of journal
+	replayed map[string][]*exchange.JournalEntry // ticker -> fills of replay
+	seen     int64                               // replay journal is read up to this seq
+
+	// tickers whose last applied input is tick, its fills may go after other entries
+	matched map[string]bool
+}
+
+// ReplayOrder is resting order with its simulation state
+type ReplayOrder struct {
+	Deal     *exchange.Deal
+	ActiveAt time.Time // trades with ticks from this time, zero if at once
+	Ahead    int64     // volume queued ahead of order at its price
+}
+
+// FillDiff is fill which replay did differently,
+// Recorded or Replayed is nil when the other side has more fills
+type FillDiff struct {
+	Ticker   string
+	N        int // fill of the ticker, from 1
+	Recorded *exchange.JournalEntry
+	Replayed *exchange.JournalEntry
+}
+
+func NewReplayer(matchers map[string]matching.Matcher, sim simulation.Model) *Replayer {
+	return &Replayer{
+		srv: &ExchangeSrv{
+			BufferSize:   replayBufferSize,
+			Logger:       zap.NewNop().Sugar(),
+			Tape:         NewTradeTape(1),
+			Matchers:     matchers,
+			Simulation:   sim,
+			Journal:      NewJournal(),
+			shardsLock:   &sync.RWMutex{},
+			shards:       make(map[string]*shard, 2),
+			orderShards:  &sync.Map{},
+			ChannelsLock: &sync.RWMutex{},
+			Channels:     make(map[int64]chan *exchange.Deal, 10),
+			stopping:     make(chan struct{}),
+		},
+		recorded: make(map[string][]*exchange.JournalEntry, 2),
+		replayed: make(map[string][]*exchange.JournalEntry, 2),
+		matched:  make(map[string]bool, 2),
+	}
+}
+
+// Close stops shards
+func (r *Replayer) Close() {
+	close(r.srv.stopping)
+}
+
+// Seq is the last applied entry
+func (r *Replayer) Seq() int64 {
+	return r.seq
+}
+
+// Apply repeats orders, cancels, expiry and ticks and keeps recorded fills, entries must go in journal order
+func (r *Replayer) Apply(entry *exchange.JournalEntry) error {
+	// tick has Seq of the entry it goes after
+	next := r.seq + 1
+	if entry.Type == exchange.JournalEntryType_TICK {
+		next = r.seq
+	}
+	if entry.Seq != next {
+		return fmt.Errorf("%w: got %v after %v", ErrorJournalGap, entry.Seq, r.seq)
+	}
+	r.seq = entry.Seq
+
+	e := r.srv
+	deal := entry.Deal
+	switch entry.Type {
+	case exchange.JournalEntryType_ORDER_CREATED:
+		order := proto.Clone(deal).(*exchange.Deal)
+		s := e.shardFor(order.Ticker)
+		e.orderShards.Store(order.ID, s)
+		s.call(func() { s.rest(order) })
+		delete(r.matched, order.Ticker)
+
+	case exchange.JournalEntryType_ORDER_CANCELLED, exchange.JournalEntryType_ORDER_EXPIRED:
+		// order filled by replay earlier than by exchange is not in book, it shows in Diff
+		if v, ok := e.orderShards.LoadAndDelete(deal.ID); ok {
+			s := v.(*shard)
+			s.call(func() { s.book.remove(deal.ID) })
+		}
+		delete(r.matched, deal.Ticker)
+
+	case exchange.JournalEntryType_TICK:
+		t := tickers.Tick{
+			Ticker:    entry.Tick.Ticker,
+			Timestamp: time.Unix(0, entry.Tick.Time),
+			Last:      entry.Tick.Last,
+			Vol:       entry.Tick.Vol,
+		}
+		s := e.shardFor(t.Ticker)
+		s.call(func() { s.match(t) })
+		r.matched[t.Ticker] = true
+
+	case exchange.JournalEntryType_ORDER_FILLED:
+		r.recorded[deal.Ticker] = append(r.recorded[deal.Ticker], entry)
+
+	case exchange.JournalEntryType_POSITION_SETTLED:
+		// settlement is not matching, replay has no positions
+
+	default:
+		return fmt.Errorf("unknown journal entry type %v", entry.Type)
+	}
+
+	r.collect()
+	return nil
+}
+
+// Tail keeps fills of ticks applied before stop which other shards pushed further in journal.
+// It is given entries after the last applied one, false when no more such fills may come.
+func (r *Replayer) Tail(entry *exchange.JournalEntry) bool {
+	ticker := entry.GetDeal().GetTicker()
+	if entry.Type == exchange.JournalEntryType_TICK {
+		ticker = entry.Tick.Ticker
+	}
+	if r.matched[ticker] {
+		if entry.Type == exchange.JournalEntryType_ORDER_FILLED {
+			r.recorded[ticker] = append(r.recorded[ticker], entry)
+		} else {
+			delete(r.matched, ticker)
+		}
+	}
+	return len(r.matched) > 0
+}
+
+// collect takes fills made by replay from its own journal, nobody reads Results of replay
+func (r *Replayer) collect() {
+	e := r.srv
+	e.ChannelsLock.RLock()
+	for _, c := range e.Channels {
+		for len(c) > 0 {
+			<-c
+		}
+	}
+	e.ChannelsLock.RUnlock()
+
+	entries, _ := e.Journal.Since(r.seen)
+	for _, entry := range entries {
+		r.seen = entry.Seq
+		if entry.Type == exchange.JournalEntryType_ORDER_FILLED {
+			r.replayed[entry.Deal.Ticker] = append(r.replayed[entry.Deal.Ticker], entry)
+		}
+	}
+}
+
+// Fills is number of recorded and replayed fills
+func (r *Replayer) Fills() (int, int) {
+	var recorded, replayed int
+	for _, fills := range r.recorded {
+		recorded += len(fills)
+	}
+	for _, fills := range r.replayed {
+		replayed += len(fills)
+	}
+	return recorded, replayed
+}
+
+// Diff compares fills of every ticker in order, journal seq and ExecSeq are not compared
+func (r *Replayer) Diff() []FillDiff {
+	res := make([]FillDiff, 0)
+	for _, ticker := range r.Tickers() {
+		recorded, replayed := r.recorded[ticker], r.replayed[ticker]
+		for i := 0; i < len(recorded) || i < len(replayed); i++ {
+			d := FillDiff{Ticker: ticker, N: i + 1}
+			if i < len(recorded) {
+				d.Recorded = recorded[i]
+			}
+			if i < len(replayed) {
+				d.Replayed = replayed[i]
+			}
+			if d.Recorded != nil && d.Replayed != nil && sameFill(d.Recorded, d.Replayed) {
+				continue
+			}
+			res = append(res, d)
+		}
+	}
+	return res
+}
+
+func sameFill(a, b *exchange.JournalEntry) bool {
+	if a.Side != b.Side {
+		return false
+	}
+	da := proto.Clone(a.Deal).(*exchange.Deal)
+	db := proto.Clone(b.Deal).(*exchange.Deal)
+	da.ExecSeq, db.ExecSeq = 0, 0
+	return proto.Equal(da, db)
+}
+
+// Book returns copies of resting orders of ticker, bids then asks in priority order
+func (r *Replayer) Book(ticker string) []ReplayOrder {
+	r.srv.shardsLock.RLock()
+	s, ok := r.srv.shards[ticker]
+	r.srv.shardsLock.RUnlock()
+	if !ok {
+		return nil
+	}
+
+	res := make([]ReplayOrder, 0, 4)
+	s.call(func() {
+		s.book.each(func(o *bookOrder) {
+			res = append(res, ReplayOrder{
+				Deal:     proto.Clone(o.deal).(*exchange.Deal),
+				ActiveAt: o.activeAt,
+				Ahead:    o.ahead,
+			})
+		})
+	})
+	return res
+}
+
+// Tickers seen in orders, ticks or fills, sorted
+func (r *Replayer) Tickers() []string {
+	seen := make(map[string]bool, 2)
+	for t := range r.recorded {
+		seen[t] = true
+	}
+	for t := range r.replayed {
+		seen[t] = true
+	}
+	for _, s := range r.srv.allShards() {
+		seen[s.book.ticker] = true
+	}
+	res := make([]string, 0, len(seen))
+	for t := range seen {
+		res = append(res, t)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/simulation"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"go.uber.org/zap"
+)
+
+func readJournal(t *testing.T, path string) []*exchange.JournalEntry {
+	t.Helper()
+	r, err := OpenJournal(path)
+	if err != nil {
+		t.Fatalf("cant open journal: %v", err)
+	}
+	defer r.Close()
+
+	res := make([]*exchange.JournalEntry, 0, 16)
+	for {
+		entry, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return res
+		}
+		if err != nil {
+			t.Fatalf("cant read journal: %v", err)
+		}
+		res = append(res, entry)
+	}
+}
+
+func replayJournal(t *testing.T, entries []*exchange.JournalEntry, sim simulation.Model, stop int64) *Replayer {
+	t.Helper()
+	r := NewReplayer(nil, sim)
+	t.Cleanup(r.Close)
+	for i, entry := range entries {
+		if stop > 0 && entry.Seq > stop {
+			for _, tail := range entries[i:] {
+				if !r.Tail(tail) {
+					break
+				}
+			}
+			break
+		}
+		err := r.Apply(entry)
+		if err != nil {
+			t.Fatalf("cant apply entry %v: %v", entry.Seq, err)
+		}
+	}
+	return r
+}
+
+func TestReplay(t *testing.T) {
+	sim := simulation.Model{PartialFillProbability: 0.5, QueueAhead: 0.5, Seed: 7}
+	e := newTestExchange(t)
+	e.Simulation = sim
+	path := filepath.Join(t.TempDir(), "journal.bin")
+	err := e.Journal.Record(path, zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatalf("cant record journal: %v", err)
+	}
+	results, _ := e.GetBrokerChannel(&exchange.BrokerID{ID: 123})
+
+	match := func(ticker string, sec int, last float32, vol int32) {
+		s := e.shardFor(ticker)
+		s.call(func() {
+			s.match(tickers.Tick{Ticker: ticker, Timestamp: simStart.Add(time.Duration(sec) * time.Second), Last: last, Vol: vol})
+		})
+	}
+	create := func(ticker string, price float32, volume int32) int64 {
+		id, err := e.Create(context.Background(), &exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: ticker, Price: price, Volume: volume})
+		if err != nil {
+			t.Fatalf("cant create order: %v", err)
+		}
+		return id.ID
+	}
+
+	match("SPFB.RTS", 0, 100, 10)
+	create("SPFB.RTS", 100, 20)
+	create("SPFB.Si", -64, 10)
+	cancelled := create("SPFB.RTS", 90, 5)
+	resting := create("SPFB.RTS", -120, 3)
+	for i := 1; i <= 5; i++ {
+		match("SPFB.RTS", i, 100, 6)
+		match("SPFB.Si", i, 65, 4)
+	}
+	_, err = e.Cancel(context.Background(), &exchange.DealID{ID: cancelled, BrokerID: 123})
+	if err != nil {
+		t.Fatalf("cant cancel order: %v", err)
+	}
+	match("SPFB.RTS", 6, 99, 50)
+
+	err = e.Journal.Close()
+	if err != nil {
+		t.Fatalf("cant close journal: %v", err)
+	}
+	fills := len(results)
+	if fills == 0 {
+		t.Fatalf("expected some fills")
+	}
+
+	entries := readJournal(t, path)
+	ticks := 0
+	for _, entry := range entries {
+		if entry.Type == exchange.JournalEntryType_TICK {
+			ticks++
+		}
+	}
+	if ticks != 12 || int64(len(entries)-ticks) != e.Journal.LastSeq() {
+		t.Fatalf("file has %v entries with %v ticks, journal %v", len(entries), ticks, e.Journal.LastSeq())
+	}
+	// ticks are only in file, standby gets orders and fills
+	kept, _ := e.Journal.Since(0)
+	for _, entry := range kept {
+		if entry.Type == exchange.JournalEntryType_TICK {
+			t.Fatalf("tick must not be kept in journal: %v", entry)
+		}
+	}
+
+	// the same model gives the same fills and book
+	r := replayJournal(t, entries, sim, 0)
+	if diffs := r.Diff(); len(diffs) != 0 {
+		t.Fatalf("replay differs: %+v", diffs[0])
+	}
+	if recorded, replayed := r.Fills(); recorded != fills || replayed != fills {
+		t.Fatalf("expected %v fills, recorded %v, replayed %v", fills, recorded, replayed)
+	}
+	book := r.Book("SPFB.RTS")
+	if len(book) != 1 || book[0].Deal.ID != resting {
+		t.Fatalf("expected only resting sell in book, have %v", book)
+	}
+
+	// books at the tick before cancel, its fills are written after other ticker ones
+	var stop int64
+	for _, entry := range entries {
+		if entry.Type == exchange.JournalEntryType_TICK && entry.Tick.Ticker == "SPFB.RTS" && entry.Tick.Vol == 6 {
+			stop = entry.Seq
+		}
+	}
+	r = replayJournal(t, entries, sim, stop)
+	if diffs := r.Diff(); len(diffs) != 0 {
+		t.Fatalf("replay up to %v differs: %+v", stop, diffs[0])
+	}
+	found := false
+	for _, o := range r.Book("SPFB.RTS") {
+		found = found || o.Deal.ID == cancelled
+	}
+	if !found {
+		t.Fatalf("cancelled order must be in book before cancel")
+	}
+
+	// other model fills differently
+	r = replayJournal(t, entries, simulation.Model{}, 0)
+	if len(r.Diff()) == 0 {
+		t.Fatalf("expected differences with other simulation model")
+	}
+}
@@ -0,0 +1,274 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	replicationHeartbeat = time.Second
+	replicationRetry     = time.Second
+)
+
+var errorStandby = status.Error(codes.Unavailable, "exchange is standby")
+
+func (e *ExchangeSrv) isStandby() bool {
+	return atomic.LoadInt32(&e.standby) == 1
+}
+
+// журнал заявок и исполнений для резервной биржи, сначала записи после AfterSeq, потом новые
+// пока записей нет, раз в секунду идет HEARTBEAT, по нему резервная биржа понимает что основная жива.
+// HEARTBEAT идет по настоящему времени и при ручных часах симуляции
+func (e *ExchangeSrv) Replicate(req *exchange.ReplicateRequest, replicateServer exchange.Exchange_ReplicateServer) error {
+	if req.AfterSeq > e.Journal.LastSeq() {
+		return status.Errorf(codes.FailedPrecondition, "standby is ahead of primary: %v > %v", req.AfterSeq, e.Journal.LastSeq())
+	}
+	e.Logger.Infow("Standby connected to Replicate", "afterSeq", req.AfterSeq)
+
+	heartbeat := time.NewTicker(replicationHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx := replicateServer.Context()
+	sent := req.AfterSeq
+	for {
+		entries, updated := e.Journal.Since(sent)
+		for _, entry := range entries {
+			err := replicateServer.Send(entry)
+			if err != nil {
+				return err
+			}
+			sent = entry.Seq
+		}
+
+		select {
+		case <-updated:
+		case <-heartbeat.C:
+			err := replicateServer.Send(&exchange.JournalEntry{Type: exchange.JournalEntryType_HEARTBEAT})
+			if err != nil {
+				return err
+			}
+		case <-e.stopping:
+			// trader is stopped, send what is left
+			entries, _ := e.Journal.Since(sent)
+			for _, entry := range entries {
+				err := replicateServer.Send(entry)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// резервная биржа становится основной, на основной ничего не делает
+func (e *ExchangeSrv) Promote(ctx context.Context, req *exchange.PromoteRequest) (*exchange.PromoteResponse, error) {
+	e.promote()
+	return &exchange.PromoteResponse{Seq: e.Journal.LastSeq()}, nil
+}
+
+// promote stops applying journal and starts trading on the state it got
+func (e *ExchangeSrv) promote() {
+	if !atomic.CompareAndSwapInt32(&e.standby, 1, 0) {
+		return
+	}
+	close(e.promoted)
+	<-e.followerDone
+
+	e.Logger.Infow("Standby is promoted to primary", "seq", e.Journal.LastSeq())
+	e.StartTrader()
+	e.health.SetServingStatus(exchange.Exchange_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+}
+
+func (e *ExchangeSrv) startFollower(ctx context.Context, cfg Config) error {
+	creds := insecure.NewCredentials()
+	if cfg.PrimaryTLS != nil {
+		creds = credentials.NewTLS(cfg.PrimaryTLS)
+	}
+	conn, err := grpc.Dial(cfg.Primary, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	if cfg.PrimaryConsumer != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "consumer", cfg.PrimaryConsumer)
+	}
+
+	e.Logger.Infow("Starting as standby", "primary", cfg.Primary, "lease", cfg.Lease)
+	go func() {
+		defer conn.Close()
+		e.follow(ctx, exchange.NewExchangeClient(conn), cfg.Lease)
+	}()
+	return nil
+}
+
+// follow applies journal of primary until promotion or ctx is done,
+// promotes standby when primary is silent longer than lease.
+// Lease and reconnects are network timeouts, they run on wall clock even if exchange runs on manual one.
+// There is no fencing: primary which is only cut off from standby keeps trading after standby
+// promoted itself, so lease must be used only when primary can't be alive without standby hearing it.
+func (e *ExchangeSrv) follow(ctx context.Context, primary exchange.ExchangeClient, lease time.Duration) {
+	defer close(e.followerDone)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-e.promoted:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	heard := time.Now().UnixNano()
+	if lease > 0 {
+		go func() {
+			ticker := time.NewTicker(lease / 4)
+			defer ticker.Stop()
+			for {
+				select {
+				case now := <-ticker.C:
+					silence := now.Sub(time.Unix(0, atomic.LoadInt64(&heard)))
+					if silence > lease {
+						e.Logger.Warnw("Primary lease expired", "silence", silence)
+						go e.promote()
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	for {
+		err := e.replicateFrom(ctx, primary, &heard)
+		if ctx.Err() != nil {
+			return
+		}
+		e.Logger.Warnw("Replication from primary interrupted", "seq", e.Journal.LastSeq(), "error", err)
+
+		retry := time.NewTimer(replicationRetry)
+		select {
+		case <-retry.C:
+		case <-ctx.Done():
+			retry.Stop()
+			return
+		}
+	}
+}
+
+func (e *ExchangeSrv) replicateFrom(ctx context.Context, primary exchange.ExchangeClient, heard *int64) error {
+	stream, err := primary.Replicate(ctx, &exchange.ReplicateRequest{AfterSeq: e.Journal.LastSeq()})
+	if err != nil {
+		return err
+	}
+	for {
+		entry, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		atomic.StoreInt64(heard, time.Now().UnixNano())
+		if entry.Type == exchange.JournalEntryType_HEARTBEAT {
+			continue
+		}
+
+		err = e.apply(entry)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// apply repeats journal entry of primary on own books
+func (e *ExchangeSrv) apply(entry *exchange.JournalEntry) error {
+	if entry.Seq != e.Journal.LastSeq()+1 {
+		return fmt.Errorf("%w: got %v after %v", ErrorJournalGap, entry.Seq, e.Journal.LastSeq())
+	}
+
+	deal := entry.Deal
+	switch entry.Type {
+	case exchange.JournalEntryType_ORDER_CREATED:
+		order := proto.Clone(deal).(*exchange.Deal)
+		s := e.shardFor(order.Ticker)
+		e.orderShards.Store(order.ID, s)
+		s.call(func() {
+			s.rest(order)
+			s.book.updateMetrics()
+		})
+
+	case exchange.JournalEntryType_ORDER_CANCELLED:
+		if v, ok := e.orderShards.LoadAndDelete(deal.ID); ok {
+			s := v.(*shard)
+			s.call(func() {
+				s.book.remove(deal.ID)
+				s.book.updateMetrics()
+			})
+		}
+
+	case exchange.JournalEntryType_ORDER_FILLED:
+		v, ok := e.orderShards.Load(deal.ID)
+		if !ok {
+			return fmt.Errorf("%w: fill of unknown order %v", ErrorJournalGap, deal.ID)
+		}
+		s := v.(*shard)
+		s.call(func() {
+			o, ok := s.book.orders[deal.ID]
+			if !ok {
+				return
+			}
+			report := &exchange.Deal{
+				ID:       deal.ID,
+				BrokerID: deal.BrokerID,
+				ClientID: deal.ClientID,
+				Ticker:   deal.Ticker,
+				Time:     deal.Time,
+				Price:    deal.Price,
+				Volume:   deal.Volume,
+			}
+			s.book.fill(o, report)
+			report.ExecSeq = entry.Seq
+			s.settle(report, entry.Side)
+			s.book.updateMetrics()
+		})
+
+	case exchange.JournalEntryType_ORDER_EXPIRED:
+		s := e.shardFor(deal.Ticker)
+		e.orderShards.Delete(deal.ID)
+		s.call(func() {
+			s.expired = true
+			s.book.remove(deal.ID)
+			s.book.updateMetrics()
+		})
+
+	case exchange.JournalEntryType_POSITION_SETTLED:
+		s := e.shardFor(deal.Ticker)
+		s.call(func() {
+			s.expired = true
+			s.settlement = deal.Price
+			delete(s.positions, positionKey{brokerID: deal.BrokerID, clientID: deal.ClientID})
+			if e.ClearingHouse != nil {
+				e.ClearingHouse.Expire(deal.Ticker, deal.Price)
+			}
+		})
+
+	default:
+		return fmt.Errorf("unknown journal entry type %v", entry.Type)
+	}
+
+	return e.Journal.Apply(entry)
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const standbyAddr = "127.0.0.1:8084"
+
+// waitFeeds waits until n consumers are subscribed to ticks
+func waitFeeds(t *testing.T, ts *TickersSourceTest, n int) {
+	t.Helper()
+	for i := 0; i < 500; i++ {
+		ts.chLock.RLock()
+		have := len(ts.ch)
+		ts.chLock.RUnlock()
+		if have >= n {
+			return
+		}
+		wait(1)
+	}
+	t.Fatalf("%v tick consumers are not subscribed", n)
+}
+
+func TestReplication(t *testing.T) {
+	primaryTicks := &TickersSourceTest{chLock: &sync.RWMutex{}}
+	standbyTicks := &TickersSourceTest{chLock: &sync.RWMutex{}}
+
+	// servers are stopped on failure too, so next tests get their addresses
+	primaryCtx, stopPrimary := context.WithCancel(context.Background())
+	primaryStopped := make(chan struct{})
+	go func() {
+		defer close(primaryStopped)
+		StartWithConfig(primaryCtx, Config{
+			ListenAddr: listenAddr,
+			BufferSize: 100,
+			Clock:      clock.NewManual(simStart),
+		}, primaryTicks)
+	}()
+	t.Cleanup(func() {
+		stopPrimary()
+		<-primaryStopped
+	})
+	standbyCtx, stopStandby := context.WithCancel(context.Background())
+	standbyStopped := make(chan struct{})
+	go func() {
+		defer close(standbyStopped)
+		StartWithConfig(standbyCtx, Config{
+			ListenAddr: standbyAddr,
+			BufferSize: 100,
+			Clock:      clock.NewManual(simStart),
+			Primary:    listenAddr,
+		}, standbyTicks)
+	}()
+	t.Cleanup(func() {
+		stopStandby()
+		<-standbyStopped
+	})
+
+	conn := getGrpcConn(t)
+	defer conn.Close()
+	primary := exchange.NewExchangeClient(conn)
+	standbyConn, err := grpc.Dial(standbyAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("cant connect to standby: %v", err)
+	}
+	defer standbyConn.Close()
+	standby := exchange.NewExchangeClient(standbyConn)
+
+	_, err = standby.Create(context.Background(), &exchange.Deal{BrokerID: 123, Ticker: "SPFB.RTS", Volume: 1, Price: 100}, grpc.WaitForReady(true))
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("standby accepted order: %v", err)
+	}
+
+	results, err := primary.Results(context.Background(), &exchange.BrokerID{ID: 123}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("cant get results stream: %v", err)
+	}
+	// candles and trader
+	waitFeeds(t, primaryTicks, 2)
+
+	ids := make([]int64, 0, 3)
+	for _, o := range []*exchange.Deal{
+		{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 2, Price: 100},
+		{BrokerID: 123, ClientID: 2, Ticker: "SPFB.RTS", Volume: 5, Price: -90, DisplayVolume: 2},
+		{BrokerID: 123, ClientID: 3, Ticker: "SPFB.Si", Volume: 1, Price: 60},
+	} {
+		id, err := primary.Create(context.Background(), o)
+		if err != nil {
+			t.Fatalf("cant create order: %v", err)
+		}
+		ids = append(ids, id.ID)
+	}
+	_, err = primary.Cancel(context.Background(), &exchange.DealID{ID: ids[2], BrokerID: 123})
+	if err != nil {
+		t.Fatalf("cant cancel order: %v", err)
+	}
+	primaryTicks.Run([]tickers.Tick{{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 95, Vol: 3}})
+
+	// broker gets only the first fill before primary goes away
+	first, err := results.Recv()
+	if err != nil {
+		t.Fatalf("cant receive fill: %v", err)
+	}
+	if first.ExecSeq == 0 {
+		t.Fatalf("fill has no ExecSeq: %v", first)
+	}
+
+	// standby has the same books as primary
+	want, err := primary.Orders(context.Background(), &exchange.OrdersRequest{BrokerID: 123})
+	if err != nil {
+		t.Fatalf("cant get primary orders: %v", err)
+	}
+	var have *exchange.OrdersResponse
+	for i := 0; i < 500; i++ {
+		have, err = standby.Orders(context.Background(), &exchange.OrdersRequest{BrokerID: 123})
+		if err == nil && proto.Equal(have, want) {
+			break
+		}
+		wait(1)
+	}
+	if !proto.Equal(have, want) {
+		t.Fatalf("standby orders dont match\nhave %v\nwant %v", have, want)
+	}
+
+	conn.Close()
+	stopPrimary()
+	<-primaryStopped
+
+	promoted, err := standby.Promote(context.Background(), &exchange.PromoteRequest{})
+	if err != nil {
+		t.Fatalf("cant promote standby: %v", err)
+	}
+	if promoted.Seq != 6 {
+		t.Fatalf("standby is promoted at seq %v, want 6", promoted.Seq)
+	}
+
+	// broker got fill primary had not replicated, standby can't resume after it
+	ahead, err := standby.Results(context.Background(), &exchange.BrokerID{ID: 123, LastExecSeq: promoted.Seq + 1}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("cant get results stream: %v", err)
+	}
+	_, err = ahead.Recv()
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("broker ahead of standby must be rejected, got %v", err)
+	}
+
+	// broker resumes after the last fill it got: the missed one comes once, then new ones
+	resumed, err := standby.Results(context.Background(), &exchange.BrokerID{ID: 123, LastExecSeq: first.ExecSeq}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("cant resume results: %v", err)
+	}
+	missed, err := resumed.Recv()
+	if err != nil {
+		t.Fatalf("cant receive missed fill: %v", err)
+	}
+	if missed.ExecSeq <= first.ExecSeq || missed.ID == first.ID {
+		t.Fatalf("unexpected missed fill %v after %v", missed, first)
+	}
+
+	waitFeeds(t, standbyTicks, 2)
+	standbyTicks.Run([]tickers.Tick{{Ticker: "SPFB.RTS", Timestamp: simStart.Add(1), Last: 90, Vol: 10}})
+
+	next, err := resumed.Recv()
+	if err != nil {
+		t.Fatalf("cant receive fill from promoted standby: %v", err)
+	}
+	if next.ExecSeq != promoted.Seq+1 || next.ID != ids[1] || next.LeavesVolume != 1 {
+		t.Fatalf("unexpected fill after promotion %v", next)
+	}
+}
+
+func TestJournalFills(t *testing.T) {
+	j := NewJournal()
+	j.Append(exchange.JournalEntryType_ORDER_CREATED, &exchange.Deal{ID: 1, BrokerID: 123}, exchange.Side_BUY)
+	j.Append(exchange.JournalEntryType_ORDER_FILLED, &exchange.Deal{ID: 1, BrokerID: 123}, exchange.Side_BUY)
+	j.Append(exchange.JournalEntryType_ORDER_FILLED, &exchange.Deal{ID: 2, BrokerID: 124}, exchange.Side_BUY)
+
+	tests := []struct {
+		seq   int64
+		fills int
+	}{
+		{seq: 0, fills: 1},
+		{seq: 2, fills: 0},
+		{seq: 3, fills: 0},
+		{seq: 10, fills: 0}, // broker is ahead of journal
+	}
+	for _, tt := range tests {
+		fills, last := j.Fills(123, tt.seq)
+		if len(fills) != tt.fills || last != 3 {
+			t.Fatalf("after %v: have %v fills up to %v, want %v up to 3", tt.seq, len(fills), last, tt.fills)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package server
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/matching"
+	"github.com/KSerditov/Trading/pkg/exchange/metrics"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+)
+
+const shardQueueSize = 1024
+
+// shard owns order book of one ticker, everything touching the book is queued
+// to its goroutine, so tickers never wait for each other
+type shard struct {
+	srv     *ExchangeSrv
+	book    *orderBook
+	matcher matching.Matcher
+	queue   chan func()
+
+	// client positions in ticker for open interest
+	positions map[positionKey]int64
+
+	// simulation state: random source, time of the last tick and volume traded by price
+	rand       *rand.Rand
+	marketTime time.Time
+	traded     map[float32]int64
+
+	// futures series: price of the last tick, expired series is settled at it and does not trade
+	lastPrice  float32
+	expired    bool
+	settlement float32
+
+	done    <-chan struct{} // exchange is stopping
+	stopped chan struct{}   // shard goroutine returned
+}
+
+func (s *shard) run() {
+	defer close(s.stopped)
+	for {
+		select {
+		case f := <-s.queue:
+			f()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// do queues f to shard goroutine, false if shard is stopped
+func (s *shard) do(f func()) bool {
+	select {
+	case s.queue <- f:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+// call runs f on shard goroutine and waits for it, false if shard is stopped
+func (s *shard) call(f func()) bool {
+	executed := make(chan struct{})
+	if !s.do(func() {
+		f()
+		close(executed)
+	}) {
+		return false
+	}
+
+	// f is either done before goroutine returns or never runs
+	select {
+	case <-executed:
+		return true
+	case <-s.stopped:
+		select {
+		case <-executed:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+// shardFor returns shard of ticker, starting it on first use
+func (e *ExchangeSrv) shardFor(ticker string) *shard {
+	e.shardsLock.RLock()
+	s, ok := e.shards[ticker]
+	e.shardsLock.RUnlock()
+	if ok {
+		return s
+	}
+
+	e.shardsLock.Lock()
+	defer e.shardsLock.Unlock()
+	if s, ok := e.shards[ticker]; ok {
+		return s
+	}
+
+	s = &shard{
+		srv:       e,
+		book:      newOrderBook(ticker),
+		matcher:   e.matcherFor(ticker),
+		queue:     make(chan func(), shardQueueSize),
+		positions: make(map[positionKey]int64, 4),
+		rand:      e.Simulation.Rand(ticker),
+		traded:    make(map[float32]int64),
+		done:      e.stopping,
+		stopped:   make(chan struct{}),
+	}
+	e.shards[ticker] = s
+	go s.run()
+	return s
+}
+
+// existing shards, tickers without orders and ticks have none
+func (e *ExchangeSrv) allShards() []*shard {
+	e.shardsLock.RLock()
+	defer e.shardsLock.RUnlock()
+
+	res := make([]*shard, 0, len(e.shards))
+	for _, s := range e.shards {
+		res = append(res, s)
+	}
+	return res
+}
+
+func (e *ExchangeSrv) matcherFor(ticker string) matching.Matcher {
+	m, ok := e.Matchers[ticker]
+	if !ok {
+		return matching.FIFO{}
+	}
+	return m
+}
+
+// rest adds order to book, it may trade after entry latency and behind volume queued at its price
+func (s *shard) rest(deal *exchange.Deal) *bookOrder {
+	o := s.book.add(deal)
+	sim := s.srv.Simulation
+	if sim.Latency > 0 {
+		o.activeAt = s.marketTime.Add(sim.Latency)
+	}
+	if sim.QueueAhead > 0 {
+		price := deal.Price
+		if price < 0 {
+			price = -price
+		}
+		o.ahead = sim.Ahead(s.traded[price])
+	}
+	return o
+}
+
+// match executes resting orders against the tick
+func (s *shard) match(t tickers.Tick) {
+	matchStart := time.Now()
+	s.srv.Journal.Tick(t)
+
+	// pending deal price exceeds ticker from feed, then exchange sells, broker buys
+	// positive price expected if pending deal has BUY type
+	buys, buyCaps := s.tradable(t, s.book.eligible(t.Last, true))
+	// exchange buys, broker sells
+	// negative price expected if pending deal has SELL type
+	sells, sellCaps := s.tradable(t, s.book.eligible(t.Last, false))
+
+	// tick volume is the liquidity for each side
+	s.execute(t, buys, s.allocate(buys, buyCaps, t.Vol), exchange.Side_BUY)
+	s.execute(t, sells, s.allocate(sells, sellCaps, t.Vol), exchange.Side_SELL)
+
+	s.marketTime = t.Timestamp
+	if !s.expired {
+		s.lastPrice = t.Last
+	}
+	if s.srv.Simulation.QueueAhead > 0 {
+		s.traded[t.Last] += int64(t.Vol)
+	}
+
+	s.book.updateMetrics()
+	metrics.MatchLatency.Observe(time.Since(matchStart).Seconds())
+}
+
+// tradable drops orders which are not yet at exchange or still have volume queued ahead at tick price.
+// Volume of the tick taken by the queue is not available to the order, caps are what is left for each order.
+func (s *shard) tradable(t tickers.Tick, orders []*bookOrder) ([]*bookOrder, []int32) {
+	res := orders[:0]
+	caps := make([]int32, 0, len(orders))
+	for _, o := range orders {
+		if t.Timestamp.Before(o.activeAt) {
+			continue
+		}
+		limit := t.Vol
+		// better priced order is traded through, queue does not matter
+		if o.ahead > 0 && (o.deal.Price == t.Last || -o.deal.Price == t.Last) {
+			consumed := o.ahead
+			if consumed > int64(t.Vol) {
+				consumed = int64(t.Vol)
+			}
+			o.ahead -= consumed
+			limit -= int32(consumed)
+			if limit == 0 {
+				continue
+			}
+		}
+		res = append(res, o)
+		caps = append(caps, limit)
+	}
+	return res, caps
+}
+
+func (s *shard) allocate(orders []*bookOrder, caps []int32, available int32) []int32 {
+	mo := toMatching(orders)
+	for i := range mo {
+		if caps[i] < mo[i].Volume {
+			mo[i].Volume = caps[i]
+		}
+	}
+	return s.matcher.Allocate(mo, available)
+}
+
+// only displayed part of iceberg may trade
+func toMatching(orders []*bookOrder) []matching.Order {
+	mo := make([]matching.Order, 0, len(orders))
+	for _, o := range orders {
+		price := o.deal.Price
+		if price < 0 {
+			price = -price
+		}
+		mo = append(mo, matching.Order{
+			ID:     o.deal.ID,
+			Volume: o.displayed(),
+			Price:  price,
+		})
+	}
+	return mo
+}
+
+// execute sends execution reports for allocated volumes
+func (s *shard) execute(t tickers.Tick, orders []*bookOrder, alloc []int32, side exchange.Side) {
+	e := s.srv
+	for i, o := range orders {
+		if alloc[i] <= 0 {
+			continue
+		}
+		order := o.deal
+		deal := &exchange.Deal{
+			ID:       order.ID,
+			BrokerID: order.BrokerID,
+			ClientID: order.ClientID,
+			Ticker:   order.Ticker,
+			Time:     int32(t.Timestamp.Unix()),
+			Price:    t.Last,
+			Volume:   e.Simulation.Fill(s.rand, alloc[i]),
+		}
+		if limit := order.Price; limit > 0 {
+			deal.Price = e.Simulation.Slip(t.Last, limit, true, deal.Volume, t.Vol)
+		} else {
+			deal.Price = e.Simulation.Slip(t.Last, -limit, false, deal.Volume, t.Vol)
+		}
+		s.book.fill(o, deal)
+		e.Journal.Append(exchange.JournalEntryType_ORDER_FILLED, deal, side)
+
+		e.Logger.Debugw("TRADER FILLED", "deal", deal, "side", side)
+
+		s.report(deal)
+		s.settle(deal, side)
+	}
+}
+
+// report queues execution report to Results of its broker. Shard never waits for broker:
+// report is dropped if its buffer is full and Results is broken, so broker resumes it from journal by LastExecSeq.
+func (s *shard) report(deal *exchange.Deal) {
+	e := s.srv
+	c, err := e.GetBrokerChannel(&exchange.BrokerID{
+		ID: int64(deal.BrokerID),
+	})
+	if err != nil {
+		e.Logger.Errorw("Error getting broker channel", "error", err)
+		return
+	}
+	select {
+	case c <- deal:
+	default:
+		e.resultsDropped.Store(int64(deal.BrokerID), true)
+		e.Logger.Warnw("Results buffer of broker is full, report is dropped", "brokerId", deal.BrokerID, "execSeq", deal.ExecSeq)
+	}
+}
+
+// settle books executed deal to positions, trades tape and clearing,
+// both for own fills and for fills replicated from primary
+func (s *shard) settle(deal *exchange.Deal, side exchange.Side) {
+	e := s.srv
+	if deal.LeavesVolume == 0 {
+		e.orderShards.Delete(deal.ID)
+	}
+	if side == exchange.Side_BUY {
+		s.changePosition(deal, int64(deal.Volume))
+	} else {
+		s.changePosition(deal, -int64(deal.Volume))
+	}
+	e.publishTrade(deal, side)
+	countFill(deal)
+	if e.ClearingHouse != nil {
+		e.ClearingHouse.Fill(deal, side == exchange.Side_BUY)
+	}
+}
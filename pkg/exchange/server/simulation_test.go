@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/simulation"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+)
+
+func TestSimulation(t *testing.T) {
+	e := newTestExchange(t)
+	e.Simulation = simulation.Model{Latency: time.Second, QueueAhead: 0.5, SlippageRate: 0.01}
+	results, _ := e.GetBrokerChannel(&exchange.BrokerID{ID: 123})
+
+	s := e.shardFor("SPFB.RTS")
+	s.call(func() { s.match(tickers.Tick{Ticker: "SPFB.RTS", Timestamp: simStart, Last: 100, Vol: 10}) })
+
+	// 5 of 10 lots traded at 100 are queued ahead of the first order, nothing at 101
+	queued, err := e.Create(context.Background(), &exchange.Deal{BrokerID: 123, ClientID: 1, Ticker: "SPFB.RTS", Volume: 5, Price: 100})
+	if err != nil {
+		t.Fatalf("cant create order: %v", err)
+	}
+	through, err := e.Create(context.Background(), &exchange.Deal{BrokerID: 123, ClientID: 2, Ticker: "SPFB.RTS", Volume: 2, Price: 101})
+	if err != nil {
+		t.Fatalf("cant create order: %v", err)
+	}
+
+	// orders are not at exchange yet
+	s.call(func() {
+		s.match(tickers.Tick{Ticker: "SPFB.RTS", Timestamp: simStart.Add(500 * time.Millisecond), Last: 100, Vol: 10})
+	})
+	select {
+	case deal := <-results:
+		t.Fatalf("order filled before entry latency: %v", deal)
+	default:
+	}
+
+	// the queue takes 5 of 6 lots, order at 101 trades through with slippage
+	s.call(func() {
+		s.match(tickers.Tick{Ticker: "SPFB.RTS", Timestamp: simStart.Add(time.Second), Last: 100, Vol: 6})
+	})
+	first := <-results
+	if first.ID != through.ID || first.Volume != 2 || first.Price <= 100 || first.Price >= 101 {
+		t.Fatalf("unexpected fill of order at 101: %v", first)
+	}
+	second := <-results
+	if second.ID != queued.ID || second.Volume != 1 || second.LeavesVolume != 4 || second.Price != 100 {
+		t.Fatalf("unexpected fill of queued order: %v", second)
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"strings"
+
+	"github.com/KSerditov/Trading/api/exchange"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// BrokerIdentity checks that broker id in request belongs to the client certificate.
+// Certificate subject CommonName is mapped to BrokerID, requests without broker id are allowed
+// to any known certificate. Replication, promotion and chaos are allowed to admin certificates only,
+// request of unknown type is denied.
+type BrokerIdentity struct {
+	subjects map[string]int64
+	admins   map[string]bool
+}
+
+func NewBrokerIdentity(subjects map[string]int64, admins []string) *BrokerIdentity {
+	b := &BrokerIdentity{
+		subjects: subjects,
+		admins:   make(map[string]bool, len(admins)),
+	}
+	for _, cn := range admins {
+		b.admins[cn] = true
+	}
+	return b
+}
+
+func (b *BrokerIdentity) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	err := b.check(ctx, info.FullMethod, req)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (b *BrokerIdentity) StreamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &identityStream{
+		ServerStream: ss,
+		identity:     b,
+		method:       info.FullMethod,
+	})
+}
+
+func (b *BrokerIdentity) check(ctx context.Context, method string, req interface{}) error {
+	if len(b.subjects) == 0 && len(b.admins) == 0 || strings.HasPrefix(method, "/grpc.health.v1.Health/") {
+		return nil
+	}
+	if strings.HasPrefix(method, "/grpc.reflection.") {
+		return b.known(ctx)
+	}
+
+	var requested int64
+	switch r := req.(type) {
+	case *exchange.BrokerID:
+		requested = r.ID
+	case *exchange.Deal:
+		requested = int64(r.BrokerID)
+	case *exchange.DealID:
+		requested = r.BrokerID
+	case *exchange.ClearingRequest:
+		requested = r.BrokerID
+	case *exchange.OrdersRequest:
+		requested = r.BrokerID
+	case *exchange.CandlesRequest, *exchange.ContractsRequest:
+		// market data of no broker
+		return b.known(ctx)
+	case *exchange.ReplicateRequest, *exchange.PromoteRequest, *exchange.ChaosSettings:
+		return b.admin(ctx, method)
+	default:
+		return status.Errorf(codes.PermissionDenied, "method %v is not allowed", method)
+	}
+
+	subject, err := subject(ctx)
+	if err != nil {
+		return err
+	}
+	brokerID, ok := b.subjects[subject]
+	if !ok {
+		return status.Errorf(codes.Unauthenticated, "certificate %q is not mapped to broker", subject)
+	}
+	if brokerID != requested {
+		return status.Errorf(codes.PermissionDenied, "certificate %q may not act as broker %v", subject, requested)
+	}
+	return nil
+}
+
+// known checks that client certificate belongs to broker or admin
+func (b *BrokerIdentity) known(ctx context.Context) error {
+	subject, err := subject(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := b.subjects[subject]; !ok && !b.admins[subject] {
+		return status.Errorf(codes.Unauthenticated, "certificate %q is not mapped to broker", subject)
+	}
+	return nil
+}
+
+// admin checks that client certificate may call method which affects all brokers
+func (b *BrokerIdentity) admin(ctx context.Context, method string) error {
+	subject, err := subject(ctx)
+	if err != nil {
+		return err
+	}
+	if !b.admins[subject] {
+		return status.Errorf(codes.PermissionDenied, "certificate %q may not call %v", subject, method)
+	}
+	return nil
+}
+
+// subject is CommonName of verified client certificate
+func subject(ctx context.Context) (string, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "no peer info")
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", status.Error(codes.Unauthenticated, "client certificate is required")
+	}
+	return tlsInfo.State.VerifiedChains[0][0].Subject.CommonName, nil
+}
+
+// server streams get request from RecvMsg inside the handler
+type identityStream struct {
+	grpc.ServerStream
+	identity *BrokerIdentity
+	method   string
+}
+
+func (s *identityStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err != nil {
+		return err
+	}
+	return s.identity.check(s.Context(), s.method, m)
+}
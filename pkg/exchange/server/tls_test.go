@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/tickers"
+	"github.com/KSerditov/Trading/pkg/tlsutil"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	err := tlsutil.GenerateTestCA(dir, []string{"127.0.0.1"}, []string{"broker123", "stranger"})
+	if err != nil {
+		t.Fatalf("cant generate test ca: %v", err)
+	}
+	path := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	serverTLS, err := tlsutil.ServerConfig(path("exchange.pem"), path("exchange-key.pem"), path("ca.pem"))
+	if err != nil {
+		t.Fatalf("cant load server tls: %v", err)
+	}
+
+	ts := &TickersSourceTest{
+		chLock: &sync.RWMutex{},
+		ch:     make([]chan tickers.Tick, 0, 2),
+	}
+
+	ctx, finish := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- StartWithConfig(ctx, Config{
+			ListenAddr:  listenAddr,
+			BufferSize:  100,
+			Clock:       clock.NewManual(simStart),
+			TLS:         serverTLS,
+			BrokerCerts: map[string]int64{"broker123": 123},
+			AdminCerts:  []string{"exchange"},
+		}, ts)
+	}()
+
+	dial := func(cert string) (*grpc.ClientConn, exchange.ExchangeClient) {
+		clientTLS, err := tlsutil.ClientConfig(path("ca.pem"), path(cert+".pem"), path(cert+"-key.pem"), "")
+		if err != nil {
+			t.Fatalf("cant load client tls: %v", err)
+		}
+		conn, err := grpc.Dial(listenAddr, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+		if err != nil {
+			t.Fatalf("cant connect to grpc: %v", err)
+		}
+		return conn, exchange.NewExchangeClient(conn)
+	}
+
+	conn, exch := dial("broker123")
+	_, err = exch.Create(context.Background(), &exchange.Deal{BrokerID: 123, Ticker: "SPFB.RTS", Volume: 1, Price: 100}, grpc.WaitForReady(true))
+	if err != nil {
+		t.Fatalf("own order rejected: %v", err)
+	}
+	_, err = exch.Create(context.Background(), &exchange.Deal{BrokerID: 124, Ticker: "SPFB.RTS", Volume: 1, Price: 100})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("order for other broker: unexpected error %v", err)
+	}
+	results, err := exch.Results(context.Background(), &exchange.BrokerID{ID: 124})
+	if err == nil {
+		_, err = results.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("results of other broker: unexpected error %v", err)
+	}
+	_, err = exch.Contracts(context.Background(), &exchange.ContractsRequest{})
+	if status.Code(err) != codes.Unimplemented {
+		t.Fatalf("market data is open to brokers: unexpected error %v", err)
+	}
+
+	// broker may not act for all brokers
+	_, err = exch.SetChaos(context.Background(), &exchange.ChaosSettings{DropProbability: 1})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("chaos by broker: unexpected error %v", err)
+	}
+	_, err = exch.Promote(context.Background(), &exchange.PromoteRequest{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("promote by broker: unexpected error %v", err)
+	}
+	replication, err := exch.Replicate(context.Background(), &exchange.ReplicateRequest{})
+	if err == nil {
+		_, err = replication.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("replication by broker: unexpected error %v", err)
+	}
+	conn.Close()
+
+	conn, exch = dial("exchange")
+	settings, err := exch.SetChaos(context.Background(), &exchange.ChaosSettings{}, grpc.WaitForReady(true))
+	if err != nil || settings.DropProbability != 0 {
+		t.Fatalf("chaos by admin: %v, %v", settings, err)
+	}
+	_, err = exch.Create(context.Background(), &exchange.Deal{BrokerID: 123, Ticker: "SPFB.RTS", Volume: 1, Price: 100})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("admin is not broker: unexpected error %v", err)
+	}
+	conn.Close()
+
+	// valid certificate which is not mapped to any broker
+	conn, exch = dial("stranger")
+	_, err = exch.Create(context.Background(), &exchange.Deal{BrokerID: 123, Ticker: "SPFB.RTS", Volume: 1, Price: 100}, grpc.WaitForReady(true))
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("unmapped certificate: unexpected error %v", err)
+	}
+	conn.Close()
+
+	finish()
+	<-stopped
+}
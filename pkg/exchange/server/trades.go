@@ -0,0 +1,97 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/KSerditov/Trading/api/exchange"
+	"github.com/KSerditov/Trading/pkg/exchange/metrics"
+)
+
+// TradeTape fans every execution out to Trades subscribers.
+// Publishing never blocks trader: subscriber whose buffer is full is dropped.
+type TradeTape struct {
+	BufferSize int
+
+	lock        *sync.Mutex
+	lastTradeID int64
+	lastSubID   int64
+	subs        map[int64]chan *exchange.Trade
+	recent      []*exchange.Trade // last recentTrades trades for console, oldest first
+}
+
+const recentTrades = 50
+
+func NewTradeTape(bufferSize int) *TradeTape {
+	return &TradeTape{
+		BufferSize: bufferSize,
+		lock:       &sync.Mutex{},
+		subs:       make(map[int64]chan *exchange.Trade, 10),
+	}
+}
+
+// Subscribe returns trades channel which is closed on Unsubscribe or when subscriber falls behind
+func (t *TradeTape) Subscribe() (int64, <-chan *exchange.Trade) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.lastSubID++
+	c := make(chan *exchange.Trade, t.BufferSize)
+	t.subs[t.lastSubID] = c
+	return t.lastSubID, c
+}
+
+func (t *TradeTape) Unsubscribe(id int64) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	c, ok := t.subs[id]
+	if ok {
+		delete(t.subs, id)
+		close(c)
+	}
+}
+
+// Publish assigns trade ID and sends trade to every subscriber
+func (t *TradeTape) Publish(trade *exchange.Trade) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.lastTradeID++
+	trade.ID = t.lastTradeID
+
+	if len(t.recent) == recentTrades {
+		t.recent = append(t.recent[:0], t.recent[1:]...)
+	}
+	t.recent = append(t.recent, trade)
+
+	for id, c := range t.subs {
+		select {
+		case c <- trade:
+		default:
+			delete(t.subs, id)
+			close(c)
+		}
+	}
+}
+
+// Backlog reports trades waiting to be sent per subscriber
+func (t *TradeTape) Backlog() map[string]int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	backlog := make(map[string]int, len(t.subs))
+	for id, c := range t.subs {
+		backlog[metrics.SubscriberID(id)] = len(c)
+	}
+	return backlog
+}
+
+// Recent returns last published trades, oldest first
+func (t *TradeTape) Recent() []*exchange.Trade {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	res := make([]*exchange.Trade, len(t.recent))
+	copy(res, t.recent)
+	return res
+}
@@ -0,0 +1,83 @@
+package simulation
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Model makes fills less optimistic than instant execution at the tick price.
+// Zero model changes nothing. Random decisions come from Seed, so the same
+// ticks and orders give the same fills on every run.
+type Model struct {
+	// order may trade only with ticks at least this much later than the last tick before it came
+	Latency time.Duration
+
+	// part of the volume traded at order price before it came, which is queued ahead of it.
+	// Ticks at exactly order price take this volume first, better prices fill the order at once.
+	QueueAhead float64
+
+	// fill price moves against order by this part of tick price when fill volume equals tick volume,
+	// proportionally less for smaller fills, but never beyond order limit
+	SlippageRate float64
+
+	// chance that order gets only random part of volume allocated to it by the tick
+	PartialFillProbability float64
+
+	Seed int64
+}
+
+// Validate checks model parameters are in range
+func (m Model) Validate() error {
+	if m.Latency < 0 {
+		return fmt.Errorf("latency must not be negative, got %v", m.Latency)
+	}
+	if m.QueueAhead < 0 || m.QueueAhead > 1 {
+		return fmt.Errorf("queue ahead must be in [0, 1], got %v", m.QueueAhead)
+	}
+	if m.SlippageRate < 0 || m.SlippageRate >= 1 {
+		return fmt.Errorf("slippage rate must be in [0, 1), got %v", m.SlippageRate)
+	}
+	if m.PartialFillProbability < 0 || m.PartialFillProbability > 1 {
+		return fmt.Errorf("partial fill probability must be in [0, 1], got %v", m.PartialFillProbability)
+	}
+	return nil
+}
+
+// Rand is random source of one ticker. Seed is mixed with ticker,
+// so tickers matched in parallel do not depend on each other.
+func (m Model) Rand(ticker string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(ticker))
+	return rand.New(rand.NewSource(m.Seed ^ int64(h.Sum64())))
+}
+
+// Ahead is volume queued ahead of new order at price where traded volume was seen
+func (m Model) Ahead(traded int64) int64 {
+	return int64(math.Round(m.QueueAhead * float64(traded)))
+}
+
+// Slip returns fill price for volume out of tick volume at price last, limit is positive for both sides
+func (m Model) Slip(last float32, limit float32, buy bool, volume int32, tickVolume int32) float32 {
+	if m.SlippageRate == 0 || tickVolume <= 0 {
+		return last
+	}
+	slip := float32(m.SlippageRate * float64(last) * float64(volume) / float64(tickVolume))
+	if buy {
+		return float32(math.Min(float64(last+slip), float64(limit)))
+	}
+	return float32(math.Max(float64(last-slip), float64(limit)))
+}
+
+// Fill returns volume actually filled out of allocated one
+func (m Model) Fill(r *rand.Rand, allocated int32) int32 {
+	if m.PartialFillProbability == 0 || allocated <= 1 {
+		return allocated
+	}
+	if r.Float64() >= m.PartialFillProbability {
+		return allocated
+	}
+	return 1 + r.Int31n(allocated-1)
+}
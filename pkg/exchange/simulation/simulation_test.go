@@ -0,0 +1,85 @@
+package simulation
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	for _, m := range []Model{
+		{Latency: -time.Millisecond},
+		{QueueAhead: 1.5},
+		{SlippageRate: 1},
+		{PartialFillProbability: -0.1},
+	} {
+		if m.Validate() == nil {
+			t.Fatalf("expected error for %+v", m)
+		}
+	}
+	if err := (Model{Latency: time.Second, QueueAhead: 1, SlippageRate: 0.01, PartialFillProbability: 1}).Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSlip(t *testing.T) {
+	m := Model{SlippageRate: 0.01}
+	for _, tt := range []struct {
+		name   string
+		limit  float32
+		buy    bool
+		volume int32
+		want   float32
+	}{
+		{"buy whole tick", 110, true, 10, 101},
+		{"buy half tick", 110, true, 5, 100.5},
+		{"buy capped by limit", 100.2, true, 10, 100.2},
+		{"sell whole tick", 90, false, 10, 99},
+		{"sell capped by limit", 99.5, false, 10, 99.5},
+	} {
+		if have := m.Slip(100, tt.limit, tt.buy, tt.volume, 10); have != tt.want {
+			t.Fatalf("%v: have %v, want %v", tt.name, have, tt.want)
+		}
+	}
+	if have := (Model{}).Slip(100, 110, true, 10, 10); have != 100 {
+		t.Fatalf("zero model must not slip, have %v", have)
+	}
+}
+
+func TestFillIsSeeded(t *testing.T) {
+	m := Model{PartialFillProbability: 0.5, Seed: 42}
+	fills := func() []int32 {
+		r := m.Rand("SPFB.RTS")
+		res := make([]int32, 20)
+		for i := range res {
+			res[i] = m.Fill(r, 10)
+		}
+		return res
+	}
+
+	first, second := fills(), fills()
+	partial := 0
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("fills differ with the same seed: %v and %v", first, second)
+		}
+		if first[i] < 1 || first[i] > 10 {
+			t.Fatalf("fill out of range: %v", first[i])
+		}
+		if first[i] < 10 {
+			partial++
+		}
+	}
+	if partial == 0 || partial == len(first) {
+		t.Fatalf("expected both partial and full fills, have %v", first)
+	}
+
+	if have := (Model{}).Fill(nil, 10); have != 10 {
+		t.Fatalf("zero model must fill all, have %v", have)
+	}
+	if have := m.Ahead(7); have != 0 {
+		t.Fatalf("no queue ahead expected, have %v", have)
+	}
+	if have := (Model{QueueAhead: 0.5}).Ahead(7); have != 4 {
+		t.Fatalf("queue ahead dont match: have %v, want 4", have)
+	}
+}
@@ -0,0 +1,234 @@
+package tickers
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Cleaning removes bad ticks of one file before they go to feed
+type Cleaning struct {
+	// remove tick repeating previous tick of the ticker in time, price and volume.
+	// Files have no trade ids, so real trades like that are removed too.
+	Dedup bool
+
+	DropZeroVolume bool
+
+	// remove ticks earlier than previous tick of the ticker, otherwise they are moved in place
+	DropOutOfOrder bool
+
+	// ticks of the ticker on each side of tick which make its median, outlier filter is off if 0
+	OutlierWindow int
+	// tick is outlier if it is farther from median than this many deviations,
+	// deviation is scaled median absolute deviation but not less than the smallest price step of the ticker
+	OutlierThreshold float64
+
+	// gaps between ticks of the ticker within a day longer than this are reported, off if 0
+	MaxGap time.Duration
+}
+
+// DefaultCleaning is used by tickconv -clean and config defaults
+func DefaultCleaning() Cleaning {
+	return Cleaning{
+		Dedup:            true,
+		DropZeroVolume:   true,
+		OutlierWindow:    15,
+		OutlierThreshold: 10,
+		MaxGap:           5 * time.Minute,
+	}
+}
+
+// Validate checks parameters are in range
+func (c Cleaning) Validate() error {
+	if c.OutlierWindow < 0 {
+		return fmt.Errorf("outlier window must not be negative, got %v", c.OutlierWindow)
+	}
+	if c.OutlierWindow > 0 && c.OutlierThreshold <= 0 {
+		return fmt.Errorf("outlier threshold must be positive, got %v", c.OutlierThreshold)
+	}
+	if c.MaxGap < 0 {
+		return fmt.Errorf("max gap must not be negative, got %v", c.MaxGap)
+	}
+	return nil
+}
+
+// Gap is time without ticks of ticker
+type Gap struct {
+	Ticker string
+	From   time.Time
+	To     time.Time
+}
+
+// CleaningReport is what cleaning did with one file
+type CleaningReport struct {
+	File       string
+	Read       int
+	Duplicates int
+	ZeroVolume int
+	OutOfOrder int // removed
+	Reordered  int // out of order ticks moved in place
+	Outliers   int
+	Gaps       []Gap
+}
+
+// Removed is number of ticks dropped for any reason
+func (r CleaningReport) Removed() int {
+	return r.Duplicates + r.ZeroVolume + r.OutOfOrder + r.Outliers
+}
+
+func (r CleaningReport) String() string {
+	return fmt.Sprintf("%v: read %v, removed %v (duplicates %v, zero volume %v, out of order %v, outliers %v), reordered %v, gaps %v",
+		r.File, r.Read, r.Removed(), r.Duplicates, r.ZeroVolume, r.OutOfOrder, r.Outliers, r.Reordered, len(r.Gaps))
+}
+
+// Log writes report and every gap
+func (r CleaningReport) Log(logger *zap.SugaredLogger) {
+	logger.Infow("Tickers file cleaned", "file", r.File, "read", r.Read, "removed", r.Removed(),
+		"duplicates", r.Duplicates, "zero_volume", r.ZeroVolume, "out_of_order", r.OutOfOrder,
+		"outliers", r.Outliers, "reordered", r.Reordered, "gaps", len(r.Gaps))
+	for _, g := range r.Gaps {
+		logger.Warnw("Gap in tickers data", "file", r.File, "ticker", g.Ticker, "from", g.From, "to", g.To)
+	}
+}
+
+// Clean filters ticks of one file given in file order, result is sorted by time
+func (c Cleaning) Clean(file string, ticks []Tick) ([]Tick, CleaningReport) {
+	report := CleaningReport{File: file, Read: len(ticks)}
+
+	res := make([]Tick, 0, len(ticks))
+	last := make(map[string]time.Time, 2)
+	for _, t := range ticks {
+		if c.DropZeroVolume && t.Vol <= 0 {
+			report.ZeroVolume++
+			continue
+		}
+		if prev, ok := last[t.Ticker]; ok && t.Timestamp.Before(prev) {
+			if c.DropOutOfOrder {
+				report.OutOfOrder++
+				continue
+			}
+			report.Reordered++
+		} else {
+			last[t.Ticker] = t.Timestamp
+		}
+		res = append(res, t)
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].Timestamp.Before(res[j].Timestamp)
+	})
+
+	if c.Dedup {
+		prev := make(map[string]Tick, 2)
+		kept := res[:0]
+		for _, t := range res {
+			if p, ok := prev[t.Ticker]; ok && p.Timestamp.Equal(t.Timestamp) && p.Last == t.Last && p.Vol == t.Vol {
+				report.Duplicates++
+				continue
+			}
+			prev[t.Ticker] = t
+			kept = append(kept, t)
+		}
+		res = kept
+	}
+
+	if c.OutlierWindow > 0 {
+		outliers := c.outliers(res)
+		kept := res[:0]
+		for i, t := range res {
+			if outliers[i] {
+				report.Outliers++
+				continue
+			}
+			kept = append(kept, t)
+		}
+		res = kept
+	}
+
+	if c.MaxGap > 0 {
+		prev := make(map[string]time.Time, 2)
+		for _, t := range res {
+			p, ok := prev[t.Ticker]
+			prev[t.Ticker] = t.Timestamp
+			if !ok || t.Timestamp.Sub(p) <= c.MaxGap {
+				continue
+			}
+			// nights and weekends are not gaps
+			if py, pm, pd := p.Date(); py != t.Timestamp.Year() || pm != t.Timestamp.Month() || pd != t.Timestamp.Day() {
+				continue
+			}
+			report.Gaps = append(report.Gaps, Gap{Ticker: t.Ticker, From: p, To: t.Timestamp})
+		}
+	}
+
+	return res, report
+}
+
+// outliers marks ticks by median absolute deviation of their neighbours of the same ticker
+func (c Cleaning) outliers(ticks []Tick) []bool {
+	byTicker := make(map[string][]int, 2)
+	for i, t := range ticks {
+		byTicker[t.Ticker] = append(byTicker[t.Ticker], i)
+	}
+
+	res := make([]bool, len(ticks))
+	window := make([]float64, 0, c.OutlierWindow*2)
+	deviations := make([]float64, 0, c.OutlierWindow*2)
+	for _, idx := range byTicker {
+		prices := make([]float64, len(idx))
+		for i, ti := range idx {
+			prices[i] = float64(ticks[ti].Last)
+		}
+		step := minStep(prices)
+
+		for i, p := range prices {
+			window = window[:0]
+			for j := i - c.OutlierWindow; j <= i+c.OutlierWindow; j++ {
+				if j >= 0 && j < len(prices) && j != i {
+					window = append(window, prices[j])
+				}
+			}
+			if len(window) == 0 {
+				continue
+			}
+			m := median(window)
+
+			deviations = deviations[:0]
+			for _, w := range window {
+				deviations = append(deviations, math.Abs(w-m))
+			}
+			// 1.4826 makes MAD comparable with standard deviation of normal distribution
+			dev := math.Max(1.4826*median(deviations), step)
+			if math.Abs(p-m) > c.OutlierThreshold*dev {
+				res[idx[i]] = true
+			}
+		}
+	}
+	return res
+}
+
+// minStep is the smallest non zero price change, like price step of instrument
+func minStep(prices []float64) float64 {
+	step := math.Inf(1)
+	for i := 1; i < len(prices); i++ {
+		if d := math.Abs(prices[i] - prices[i-1]); d > 0 && d < step {
+			step = d
+		}
+	}
+	if math.IsInf(step, 1) {
+		return 0
+	}
+	return step
+}
+
+// median sorts values in place
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
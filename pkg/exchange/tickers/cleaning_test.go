@@ -0,0 +1,236 @@
+package tickers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+)
+
+func TestCleaning(t *testing.T) {
+	at := func(sec int) time.Time { return fileStart.Add(time.Duration(sec) * time.Second) }
+	rts := func(sec int, last float32, vol int32) Tick {
+		return Tick{Ticker: "SPFB.RTS", Timestamp: at(sec), Last: last, Vol: vol}
+	}
+
+	ticks := make([]Tick, 0, 40)
+	for i := 0; i < 30; i++ {
+		ticks = append(ticks, rts(i, float32(120000+i%3*10), 1))
+	}
+	ticks = append(ticks[:10], append([]Tick{
+		rts(9, 120000, 1),  // repeats previous tick
+		rts(10, 150000, 1), // spike
+		rts(10, 120010, 0), // zero volume
+		rts(3, 120000, 2),  // out of order
+	}, ticks[10:]...)...)
+	ticks = append(ticks, rts(30+600, 120000, 1)) // gap of 10 minutes
+
+	c := DefaultCleaning()
+	res, report := c.Clean("rts.txt", ticks)
+	want := CleaningReport{File: "rts.txt", Read: len(ticks), Duplicates: 1, ZeroVolume: 1, Outliers: 1, Reordered: 1,
+		Gaps: []Gap{{Ticker: "SPFB.RTS", From: at(29), To: at(630)}}}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("report dont match:\nhave %v %v\nwant %v %v", report, report.Gaps, want, want.Gaps)
+	}
+	if len(res) != len(ticks)-report.Removed() {
+		t.Fatalf("expected %v ticks, have %v", len(ticks)-report.Removed(), len(res))
+	}
+	for i := 1; i < len(res); i++ {
+		if res[i].Timestamp.Before(res[i-1].Timestamp) {
+			t.Fatalf("ticks are not sorted at %v: %v", i, res[i])
+		}
+		if res[i].Last > 130000 {
+			t.Fatalf("spike is not removed: %v", res[i])
+		}
+	}
+
+	c.DropOutOfOrder = true
+	_, report = c.Clean("rts.txt", ticks)
+	if report.OutOfOrder != 1 || report.Reordered != 0 {
+		t.Fatalf("expected out of order tick to be removed: %v", report)
+	}
+
+	// zero value changes only order
+	res, report = Cleaning{}.Clean("rts.txt", ticks)
+	if len(res) != len(ticks) || report.Removed() != 0 || len(report.Gaps) != 0 {
+		t.Fatalf("nothing must be removed: %v", report)
+	}
+}
+
+// tick of fileStart day sec seconds after its start
+func rtsTick(sec int, last float32, vol int32) Tick {
+	return Tick{Ticker: "SPFB.RTS", Timestamp: fileStart.Add(time.Duration(sec) * time.Second), Last: last, Vol: vol}
+}
+
+type cleaningTest struct {
+	name     string
+	cleaning Cleaning
+	ticks    []Tick
+	expected CleaningReport // File and Read are set by test
+	kept     int
+}
+
+var cleaningTests = []cleaningTest{
+	{
+		name:     "dedup removes repeated tick",
+		cleaning: Cleaning{Dedup: true},
+		ticks:    []Tick{rtsTick(0, 100, 1), rtsTick(0, 100, 1), rtsTick(0, 100, 2), rtsTick(1, 100, 1)},
+		expected: CleaningReport{Duplicates: 1},
+		kept:     3,
+	},
+	{
+		name:     "dedup compares ticks of the same ticker",
+		cleaning: Cleaning{Dedup: true},
+		ticks:    []Tick{rtsTick(0, 100, 1), {Ticker: "SPFB.Si", Timestamp: fileStart, Last: 100, Vol: 1}},
+		kept:     2,
+	},
+	{
+		name:     "dedup off keeps repeated tick",
+		cleaning: Cleaning{},
+		ticks:    []Tick{rtsTick(0, 100, 1), rtsTick(0, 100, 1)},
+		kept:     2,
+	},
+	{
+		name:     "zero and negative volume",
+		cleaning: Cleaning{DropZeroVolume: true},
+		ticks:    []Tick{rtsTick(0, 100, 0), rtsTick(1, 100, -1), rtsTick(2, 100, 1)},
+		expected: CleaningReport{ZeroVolume: 2},
+		kept:     1,
+	},
+	{
+		name:     "out of order tick is moved in place",
+		cleaning: Cleaning{},
+		ticks:    []Tick{rtsTick(0, 100, 1), rtsTick(2, 100, 1), rtsTick(1, 100, 1), rtsTick(3, 100, 1)},
+		expected: CleaningReport{Reordered: 1},
+		kept:     4,
+	},
+	{
+		name:     "out of order tick is dropped",
+		cleaning: Cleaning{DropOutOfOrder: true},
+		ticks:    []Tick{rtsTick(0, 100, 1), rtsTick(2, 100, 1), rtsTick(1, 100, 1), rtsTick(3, 100, 1)},
+		expected: CleaningReport{OutOfOrder: 1},
+		kept:     3,
+	},
+	{
+		name:     "outlier far from median of neighbours",
+		cleaning: Cleaning{OutlierWindow: 2, OutlierThreshold: 3},
+		ticks:    []Tick{rtsTick(0, 100, 1), rtsTick(1, 101, 1), rtsTick(2, 100, 1), rtsTick(3, 200, 1), rtsTick(4, 101, 1), rtsTick(5, 100, 1)},
+		expected: CleaningReport{Outliers: 1},
+		kept:     5,
+	},
+	{
+		name:     "price step of flat prices is not outlier",
+		cleaning: Cleaning{OutlierWindow: 2, OutlierThreshold: 3},
+		ticks:    []Tick{rtsTick(0, 100, 1), rtsTick(1, 100, 1), rtsTick(2, 101, 1), rtsTick(3, 100, 1), rtsTick(4, 100, 1)},
+		kept:     5,
+	},
+	{
+		name:     "gap within day",
+		cleaning: Cleaning{MaxGap: time.Minute},
+		ticks:    []Tick{rtsTick(0, 100, 1), rtsTick(60, 100, 1), rtsTick(121, 100, 1)},
+		expected: CleaningReport{Gaps: []Gap{{Ticker: "SPFB.RTS", From: fileStart.Add(time.Minute), To: fileStart.Add(121 * time.Second)}}},
+		kept:     3,
+	},
+	{
+		name:     "night is not gap",
+		cleaning: Cleaning{MaxGap: time.Minute},
+		ticks:    []Tick{rtsTick(0, 100, 1), rtsTick(14*3600, 100, 1)},
+		kept:     2,
+	},
+}
+
+func TestCleaningRules(t *testing.T) {
+	for _, tt := range cleaningTests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticks := append([]Tick(nil), tt.ticks...)
+			res, report := tt.cleaning.Clean("rts.txt", ticks)
+
+			want := tt.expected
+			want.File, want.Read = "rts.txt", len(tt.ticks)
+			if !reflect.DeepEqual(report, want) {
+				t.Fatalf("report dont match\nhave %v %v\nwant %v %v", report, report.Gaps, want, want.Gaps)
+			}
+			if len(res) != tt.kept {
+				t.Fatalf("expected %v ticks, have %v", tt.kept, len(res))
+			}
+			for i := 1; i < len(res); i++ {
+				if res[i].Timestamp.Before(res[i-1].Timestamp) {
+					t.Fatalf("ticks are not sorted at %v: %v", i, res[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCleaningValidate(t *testing.T) {
+	tests := []struct {
+		cleaning Cleaning
+		valid    bool
+	}{
+		{cleaning: Cleaning{}, valid: true},
+		{cleaning: DefaultCleaning(), valid: true},
+		{cleaning: Cleaning{OutlierWindow: -1}},
+		{cleaning: Cleaning{OutlierWindow: 5}},
+		{cleaning: Cleaning{OutlierWindow: 5, OutlierThreshold: -1}},
+		{cleaning: Cleaning{MaxGap: -time.Second}},
+	}
+
+	for _, tt := range tests {
+		err := tt.cleaning.Validate()
+		if (err == nil) != tt.valid {
+			t.Fatalf("%+v: unexpected error %v", tt.cleaning, err)
+		}
+	}
+}
+
+func TestTickersSourceInMemCleaning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rts.txt")
+	err := os.WriteFile(path, []byte("<TICKER>,<PER>,<DATE>,<TIME>,<LAST>,<VOL>\n"+
+		"SPFB.RTS,0,20190517,100001,120010.0,1\n"+
+		"SPFB.RTS,0,20190517,100000,120000.0,2\n"+
+		"SPFB.RTS,0,20190517,100000,120000.0,2\n"+
+		"SPFB.RTS,0,20190517,100002,120020.0,0\n"), 0644)
+	if err != nil {
+		t.Fatalf("cant write file: %v", err)
+	}
+
+	clk := clock.NewManual(time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC))
+	d := &TickersSourceInMem{
+		FilePaths:    []string{path},
+		UseTodayDate: true,
+		Cleaning:     &Cleaning{Dedup: true, DropZeroVolume: true},
+		Clock:        clk,
+	}
+	d.Prepare()
+	c := d.GetFeedChannel()
+	err = d.Load()
+	if err != nil {
+		t.Fatalf("cant load ticks: %v", err)
+	}
+	<-d.Ready()
+	clk.WaitTickers(1)
+	for i := 0; i < 5; i++ {
+		clk.Advance(time.Hour)
+	}
+
+	// duplicate and zero volume ticks are gone, the rest is sorted and moved to today
+	want := []Tick{
+		{Ticker: "SPFB.RTS", Timestamp: time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC), Last: 120000, Vol: 2},
+		{Ticker: "SPFB.RTS", Timestamp: time.Date(2026, 10, 19, 10, 0, 1, 0, time.UTC), Last: 120010, Vol: 1},
+	}
+	for _, w := range want {
+		tick := <-c
+		if tick.Ticker != w.Ticker || !tick.Timestamp.Equal(w.Timestamp) || tick.Last != w.Last || tick.Vol != w.Vol {
+			t.Fatalf("tick dont match: have %v, want %v", tick, w)
+		}
+	}
+	select {
+	case tick := <-c:
+		t.Fatalf("unexpected tick %v", tick)
+	case <-time.After(10 * time.Millisecond):
+	}
+	d.CloseFeed()
+}
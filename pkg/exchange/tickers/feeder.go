@@ -0,0 +1,191 @@
+package tickers
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+	"github.com/KSerditov/Trading/pkg/exchange/metrics"
+
+	"go.uber.org/zap"
+)
+
+// Position is how far replay went
+type Position struct {
+	Time  time.Time // replay clock, zero before feed starts
+	Sent  int64     // ticks sent to consumers
+	Left  int       // ticks waiting for their time
+	Speed float64
+}
+
+// tickIterator gives historical ticks in time order
+type tickIterator interface {
+	// seek skips ticks up to t, nothing is skipped if no tick is later than t
+	seek(t time.Time) error
+	next() (Tick, bool, error)
+	left() int // ticks not returned by next yet
+}
+
+// feeder sends ticks to consumer channels when replay clock reaches them, sources embed it
+type feeder struct {
+	speed      float64
+	bufferSize int
+	logger     *zap.SugaredLogger
+	clock      clock.Clock
+
+	channelsLock *sync.RWMutex
+	channels     []chan Tick
+	closed       bool
+
+	ready chan struct{}
+
+	positionLock *sync.Mutex
+	position     Position
+}
+
+func (d *feeder) prepare(speed float64, bufferSize int, logger *zap.SugaredLogger, clk clock.Clock) {
+	d.speed = speed
+	d.bufferSize = bufferSize
+	d.logger = logger
+	d.clock = clk
+
+	d.channelsLock = &sync.RWMutex{}
+	d.positionLock = &sync.Mutex{}
+	d.position = Position{Speed: speed}
+
+	d.channels = make([]chan Tick, 0, 2)
+	d.ready = make(chan struct{})
+}
+
+// Ready is closed when tickers are loaded and feed is running
+func (d *feeder) Ready() <-chan struct{} {
+	return d.ready
+}
+
+func (d *feeder) GetFeedChannel() <-chan Tick {
+	c := make(chan Tick, d.bufferSize)
+
+	d.channelsLock.Lock()
+	if d.closed {
+		close(c)
+	} else {
+		d.channels = append(d.channels, c)
+	}
+	d.channelsLock.Unlock()
+
+	return c
+}
+
+func (d *feeder) ReleaseFeedChannel(c <-chan Tick) {
+	d.channelsLock.Lock()
+	defer d.channelsLock.Unlock()
+
+	for i, v := range d.channels {
+		if v == c {
+			d.channels = append(d.channels[:i], d.channels[i+1:]...)
+			close(v)
+			return
+		}
+	}
+}
+
+func (d *feeder) CloseFeed() {
+	d.channelsLock.Lock()
+	defer d.channelsLock.Unlock()
+
+	if d.closed {
+		return
+	}
+	d.closed = true
+	for _, v := range d.channels {
+		close(v)
+	}
+	// consumers release their channels after close
+	d.channels = nil
+}
+
+// Backlog reports ticks waiting in every consumer channel, consumers are numbered in subscription order
+func (d *feeder) Backlog() map[string]int {
+	d.channelsLock.RLock()
+	defer d.channelsLock.RUnlock()
+
+	backlog := make(map[string]int, len(d.channels))
+	for i, c := range d.channels {
+		backlog[strconv.Itoa(i)] = len(c)
+	}
+	return backlog
+}
+
+// Position reports replay clock and progress
+func (d *feeder) Position() Position {
+	d.positionLock.Lock()
+	defer d.positionLock.Unlock()
+	return d.position
+}
+
+func (d *feeder) setPosition(ts time.Time, sent int, left int) {
+	d.positionLock.Lock()
+	defer d.positionLock.Unlock()
+	d.position.Time = ts
+	d.position.Sent += int64(sent)
+	d.position.Left = left
+}
+
+// start runs feed and reports source ready
+func (d *feeder) start(ticks tickIterator) {
+	go d.feed(ticks)
+	close(d.ready)
+}
+
+/* another way to feed consumers with tickers
+ */
+func (d *feeder) feed(ticks tickIterator) {
+	// discard everything before exchange startup
+	start := d.clock.Now()
+	err := ticks.seek(start)
+	if err != nil {
+		d.logger.Errorw("Can't seek tickers to exchange startup", "error", err)
+		return
+	}
+	pending, ok, err := ticks.next()
+
+	interval := time.Second * 1
+	ticker := d.clock.NewTicker(interval)
+	defer ticker.Stop()
+
+	// each second push all tickers with appropriate timestamp to listeners
+	for range ticker.C() {
+		sent := 0
+		// replay time runs Speed times faster than wall clock
+		tsnow := start.Add(time.Duration(float64(d.clock.Now().Sub(start)) * d.speed))
+		for ok && pending.Timestamp.Before(tsnow) {
+			d.channelsLock.Lock()
+			if d.closed {
+				d.channelsLock.Unlock()
+				return
+			}
+			for _, c := range d.channels {
+				c <- pending
+			}
+			d.channelsLock.Unlock()
+
+			// wall time when tick was due, slow consumers make it grow
+			due := start.Add(time.Duration(float64(pending.Timestamp.Sub(start)) / d.speed))
+			metrics.FeedLag.Set(d.clock.Now().Sub(due).Seconds())
+
+			sent++
+			pending, ok, err = ticks.next()
+		}
+		if err != nil {
+			d.logger.Errorw("Can't read tickers, feed is stopped", "error", err)
+			return
+		}
+
+		left := ticks.left()
+		if ok {
+			left++
+		}
+		d.setPosition(tsnow, sent, left)
+	}
+}
@@ -11,5 +11,6 @@ type Tick struct {
 
 type TickersSource interface {
 	GetFeedChannel() <-chan Tick
+	ReleaseFeedChannel(c <-chan Tick) // consumer stopped reading, feed must not block on it
 	CloseFeed()
 }
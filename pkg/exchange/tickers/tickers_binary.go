@@ -0,0 +1,252 @@
+package tickers
+
+import (
+	"container/heap"
+	"errors"
+	"time"
+
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+
+	"go.uber.org/zap"
+)
+
+// TickersSourceBinary replays binary tick files made by tickconv.
+// Only one block per ticker is decoded at a time, so months of ticks start at once and take little memory.
+type TickersSourceBinary struct {
+	FilePaths []string
+	// replay the first day of data as today, following days go after it
+	UseTodayDate bool
+	Speed        float64 // replay speed, 1 (real time) if not set
+	BufferSize   int     // per consumer channel, 100 if not set
+
+	Logger *zap.SugaredLogger
+	Clock  clock.Clock // wall clock if not set
+
+	feeder
+}
+
+// Init prepares source and opens files, feed starts right after that
+func (d *TickersSourceBinary) Init() error {
+	d.Prepare()
+	return d.Load()
+}
+
+// Prepare makes source usable by consumers before files are opened
+func (d *TickersSourceBinary) Prepare() {
+	if d.Speed <= 0 {
+		d.Speed = 1
+	}
+	if d.BufferSize <= 0 {
+		d.BufferSize = 100
+	}
+	if d.Logger == nil {
+		d.Logger = zap.S()
+	}
+	if d.Clock == nil {
+		d.Clock = clock.Real{}
+	}
+	d.prepare(d.Speed, d.BufferSize, d.Logger, d.Clock)
+}
+
+// Load reads indexes of all files and starts feed, Ready is closed on success
+func (d *TickersSourceBinary) Load() error {
+	if len(d.FilePaths) < 1 {
+		return errors.New("empty list of input files for tickers data")
+	}
+
+	it := &mergeIterator{}
+	for _, path := range d.FilePaths {
+		tf, err := OpenTickFile(path)
+		if err != nil {
+			it.close()
+			return err
+		}
+		it.add(tf)
+	}
+
+	if d.UseTodayDate && it.count > 0 {
+		first := it.first.UTC()
+		now := d.Clock.Now()
+		it.shift = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).
+			Sub(time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.UTC))
+	}
+
+	d.Logger.Infow("Historical data index loaded", "tickers", it.count, "files", len(d.FilePaths))
+	d.Logger.Info("Starting tickers feed")
+
+	d.start(it)
+	return nil
+}
+
+// tickCursor goes over blocks of one ticker in one file
+type tickCursor struct {
+	file   *TickFile
+	blocks []TickBlock
+	ticks  []Tick // decoded block, from the next tick
+	buf    []Tick
+	order  int // ties in time go in file and ticker order
+}
+
+// load decodes the next block if current one is done, false when ticker is over
+func (c *tickCursor) load() (bool, error) {
+	for len(c.ticks) == 0 {
+		if len(c.blocks) == 0 {
+			return false, nil
+		}
+		var err error
+		c.buf, err = c.file.ReadBlock(c.blocks[0], c.buf)
+		if err != nil {
+			return false, err
+		}
+		c.ticks = c.buf
+		c.blocks = c.blocks[1:]
+	}
+	return true, nil
+}
+
+// mergeIterator merges tickers of all files in time order
+type mergeIterator struct {
+	files   []*TickFile
+	cursors []*tickCursor
+	active  cursorHeap
+	started bool
+
+	count int           // ticks not returned yet
+	first time.Time     // the earliest tick
+	last  time.Time     // the latest tick
+	shift time.Duration // added to every tick
+}
+
+func (it *mergeIterator) add(tf *TickFile) {
+	it.files = append(it.files, tf)
+
+	byTicker := make(map[string]*tickCursor, 2)
+	for _, b := range tf.Index {
+		c, ok := byTicker[b.Ticker]
+		if !ok {
+			c = &tickCursor{file: tf, order: len(it.cursors)}
+			byTicker[b.Ticker] = c
+			it.cursors = append(it.cursors, c)
+		}
+		c.blocks = append(c.blocks, b)
+
+		if it.count == 0 || b.First.Before(it.first) {
+			it.first = b.First
+		}
+		if b.Last.After(it.last) {
+			it.last = b.Last
+		}
+		it.count += b.Count
+	}
+}
+
+func (it *mergeIterator) seek(t time.Time) error {
+	t = t.Add(-it.shift)
+	// like in memory source nothing is skipped when all ticks are in the past
+	if it.count == 0 || !it.last.After(t) {
+		return it.init()
+	}
+
+	for _, c := range it.cursors {
+		for len(c.blocks) > 0 && !c.blocks[0].Last.After(t) {
+			it.count -= c.blocks[0].Count
+			c.blocks = c.blocks[1:]
+		}
+		ok, err := c.load()
+		if err != nil {
+			return err
+		}
+		for ok && !c.ticks[0].Timestamp.After(t) {
+			c.ticks = c.ticks[1:]
+			it.count--
+			ok, err = c.load()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return it.init()
+}
+
+// init puts cursors with ticks to heap
+func (it *mergeIterator) init() error {
+	it.started = true
+	it.active = make(cursorHeap, 0, len(it.cursors))
+	for _, c := range it.cursors {
+		ok, err := c.load()
+		if err != nil {
+			return err
+		}
+		if ok {
+			it.active = append(it.active, c)
+		}
+	}
+	heap.Init(&it.active)
+	return nil
+}
+
+func (it *mergeIterator) next() (Tick, bool, error) {
+	if !it.started {
+		err := it.init()
+		if err != nil {
+			return Tick{}, false, err
+		}
+	}
+	if len(it.active) == 0 {
+		it.close()
+		return Tick{}, false, nil
+	}
+
+	c := it.active[0]
+	t := c.ticks[0]
+	c.ticks = c.ticks[1:]
+	it.count--
+
+	ok, err := c.load()
+	if err != nil {
+		return Tick{}, false, err
+	}
+	if ok {
+		heap.Fix(&it.active, 0)
+	} else {
+		heap.Pop(&it.active)
+	}
+
+	t.Timestamp = t.Timestamp.Add(it.shift)
+	return t, true, nil
+}
+
+func (it *mergeIterator) left() int {
+	return it.count
+}
+
+func (it *mergeIterator) close() {
+	for _, tf := range it.files {
+		tf.Close()
+	}
+	it.files = nil
+}
+
+// cursorHeap orders cursors by their next tick
+type cursorHeap []*tickCursor
+
+func (h cursorHeap) Len() int { return len(h) }
+
+func (h cursorHeap) Less(i, j int) bool {
+	a, b := h[i].ticks[0].Timestamp, h[j].ticks[0].Timestamp
+	if !a.Equal(b) {
+		return a.Before(b)
+	}
+	return h[i].order < h[j].order
+}
+
+func (h cursorHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *cursorHeap) Push(x interface{}) { *h = append(*h, x.(*tickCursor)) }
+
+func (h *cursorHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
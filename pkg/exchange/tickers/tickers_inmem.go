@@ -4,158 +4,157 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
-	"log"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
+
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+
+	"go.uber.org/zap"
 )
 
 type TickersSourceInMem struct {
 	FilePaths    []string
 	UseTodayDate bool
+	Speed        float64 // replay speed, 1 (real time) if not set
+	BufferSize   int     // per consumer channel, 100 if not set
+
+	Cleaning *Cleaning // nil keeps ticks as they are
 
-	tickersLock *sync.RWMutex
-	tickers     []Tick
+	Logger *zap.SugaredLogger
+	Clock  clock.Clock // wall clock if not set
 
-	channelsLock *sync.RWMutex
-	channels     []chan Tick
+	feeder
 }
 
+// Init prepares source and loads tickers, feed starts right after that
 func (d *TickersSourceInMem) Init() error {
+	d.Prepare()
+	return d.Load()
+}
+
+// Prepare makes source usable by consumers before tickers are loaded
+func (d *TickersSourceInMem) Prepare() {
+	if d.Speed <= 0 {
+		d.Speed = 1
+	}
+	if d.BufferSize <= 0 {
+		d.BufferSize = 100
+	}
+	if d.Logger == nil {
+		d.Logger = zap.S()
+	}
+	if d.Clock == nil {
+		d.Clock = clock.Real{}
+	}
+	d.prepare(d.Speed, d.BufferSize, d.Logger, d.Clock)
+}
+
+// Load reads all files and starts feed, Ready is closed on success
+func (d *TickersSourceInMem) Load() error {
 	if len(d.FilePaths) < 1 {
 		return errors.New("empty list of input files for tickers data")
 	}
 
-	d.tickersLock = &sync.RWMutex{}
-	d.channelsLock = &sync.RWMutex{}
-
-	d.channels = make([]chan Tick, 0, 2)
-
-	d.tickersLock.Lock()
-	defer d.tickersLock.Unlock()
-	d.tickers = make([]Tick, 0, 300000)
-
 	//read all to memory
+	ticks := make([]Tick, 0, 300000)
 	for _, f := range d.FilePaths {
-		file, err := os.Open(f)
+		fileTicks, err := ReadCSV(f)
 		if err != nil {
-			log.Fatal(err)
+			return err
 		}
-		defer file.Close()
-
-		scanner := bufio.NewScanner(file)
-		scanner.Scan() // skip header
-		for scanner.Scan() {
-			s := strings.Split(scanner.Text(), `,`)
-
-			var ts time.Time
-			if d.UseTodayDate {
-				ts, err = time.Parse("20060102 150405 MST", fmt.Sprintf("%v %v MSK", time.Now().Format("20060102"), s[3]))
-			} else {
-				ts, err = time.Parse("20060102 150405 MST", fmt.Sprintf("%v %v MSK", s[2], s[3]))
-			}
-			if err != nil {
-				return err
-			}
-
-			l, err := strconv.ParseFloat(strings.Split(s[4], `.`)[0], 32)
-			if err != nil {
-				return err
-			}
-
-			v, err := strconv.ParseInt(s[5], 10, 32)
-			if err != nil {
-				return err
-			}
-
-			t := &Tick{
-				Ticker:    s[0],
-				Timestamp: ts,
-				Last:      float32(l),
-				Vol:       int32(v),
+		// cleaning sees real dates, so days of one file do not mix up
+		if d.Cleaning != nil {
+			var report CleaningReport
+			fileTicks, report = d.Cleaning.Clean(f, fileTicks)
+			report.Log(d.Logger)
+		}
+		if d.UseTodayDate {
+			y, m, day := d.Clock.Now().Date()
+			for i, t := range fileTicks {
+				fileTicks[i].Timestamp = time.Date(y, m, day, t.Timestamp.Hour(), t.Timestamp.Minute(), t.Timestamp.Second(), t.Timestamp.Nanosecond(), t.Timestamp.Location())
 			}
-
-			d.tickers = append(d.tickers, *t)
 		}
+		ticks = append(ticks, fileTicks...)
 	}
 
 	// sort by timestamp ascending
-	sort.SliceStable(d.tickers, func(i, j int) bool {
-		return d.tickers[i].Timestamp.Before(d.tickers[j].Timestamp)
+	sort.SliceStable(ticks, func(i, j int) bool {
+		return ticks[i].Timestamp.Before(ticks[j].Timestamp)
 	})
 
-	fmt.Println("Historical data load completed")
-	fmt.Println("Starting tickers feed")
-
-	go d.feed()
+	d.Logger.Infow("Historical data load completed", "tickers", len(ticks))
+	d.Logger.Info("Starting tickers feed")
 
+	d.start(&sliceIterator{ticks: ticks})
 	return nil
 }
 
-func (d *TickersSourceInMem) GetFeedChannel() <-chan Tick {
-	c := make(chan Tick, 100)
+// ReadCSV reads ticks of text file in file order, header line is skipped
+func ReadCSV(path string) ([]Tick, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open tickers file: %w", err)
+	}
+	defer file.Close()
 
-	d.channelsLock.Lock()
-	d.channels = append(d.channels, c)
-	d.channelsLock.Unlock()
+	ticks := make([]Tick, 0, 1024)
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // skip header
+	for scanner.Scan() {
+		s := strings.Split(scanner.Text(), `,`)
 
-	return c
-}
+		ts, err := time.Parse("20060102 150405 MST", fmt.Sprintf("%v %v MSK", s[2], s[3]))
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := strconv.ParseFloat(strings.Split(s[4], `.`)[0], 32)
+		if err != nil {
+			return nil, err
+		}
 
-func (d *TickersSourceInMem) CloseFeed() {
-	d.channelsLock.Lock()
-	defer d.channelsLock.Unlock()
+		v, err := strconv.ParseInt(s[5], 10, 32)
+		if err != nil {
+			return nil, err
+		}
 
-	for _, v := range d.channels {
-		close(v)
+		ticks = append(ticks, Tick{
+			Ticker:    s[0],
+			Timestamp: ts,
+			Last:      float32(l),
+			Vol:       int32(v),
+		})
 	}
+	return ticks, scanner.Err()
+}
 
-	//clean up channels, not needed for now since it should happen only on final stop of exchange
-	//d.channels = make([]chan Tick, 0, 2)
+// sliceIterator goes over ticks loaded to memory
+type sliceIterator struct {
+	ticks []Tick
 }
 
-/* another way to feed consumers with tickers
- */
-func (d *TickersSourceInMem) feed() {
-	// discard everything before exchange startup
-	now := time.Now()
-	for i, v := range d.tickers {
-		if v.Timestamp.After(now) {
-			d.tickers = d.tickers[i:]
+func (it *sliceIterator) seek(t time.Time) error {
+	for i, v := range it.ticks {
+		if v.Timestamp.After(t) {
+			it.ticks = it.ticks[i:]
 			break
 		}
 	}
+	return nil
+}
 
-	interval := time.Second * 1
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	// each second push all tickers with appropriate timestamp to listeners
-	var l int64
-	for range ticker.C {
-		d.tickersLock.RLock()
-
-		var maxid int
-		tsnow := time.Now()
-		for j, k := range d.tickers {
-			if k.Timestamp.Before(tsnow) {
-				maxid = j + 1
-
-				d.channelsLock.Lock()
-				for _, c := range d.channels {
-					c <- k
-				}
-				d.channelsLock.Unlock()
-			} else {
-				break
-			}
-		}
-		d.tickers = d.tickers[maxid:]
-		d.tickersLock.RUnlock()
-
-		l += 1
+func (it *sliceIterator) next() (Tick, bool, error) {
+	if len(it.ticks) == 0 {
+		return Tick{}, false, nil
 	}
+	t := it.ticks[0]
+	it.ticks = it.ticks[1:]
+	return t, true, nil
+}
+
+func (it *sliceIterator) left() int {
+	return len(it.ticks)
 }
@@ -0,0 +1,418 @@
+package tickers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"time"
+)
+
+// Binary tick file:
+//
+//	"TICK" version | block ... | index | index offset uint64 | "TICK"
+//
+// Block holds up to TickBlockSize ticks of one ticker in time order, column by column:
+// timestamps as deltas in block time unit, prices as deltas of integers in block price scale, volumes.
+// Index lists every block with ticker, time range, offset, size and crc,
+// so reader seeks by time without decoding blocks it skips.
+const (
+	tickFileMagic   = "TICK"
+	tickFileVersion = 1
+	tickFooterSize  = 8 + len(tickFileMagic)
+
+	TickBlockSize = 65536
+
+	maxPriceScale = 6
+)
+
+var (
+	ErrorTickFileFormat  = errors.New("not a tick file")
+	ErrorTickFileCorrupt = errors.New("tick file is corrupt")
+	ErrorTicksOutOfOrder = errors.New("ticks of ticker go back in time")
+)
+
+// time units of block timestamps, the largest one all deltas fit is used
+var tickTimeUnits = []time.Duration{time.Second, time.Millisecond, time.Microsecond, time.Nanosecond}
+
+// TickBlock is index entry of one block
+type TickBlock struct {
+	Ticker string
+	First  time.Time
+	Last   time.Time
+	Count  int
+	Offset int64
+	Size   int
+	CRC    uint32
+}
+
+// TickWriter writes binary tick file, ticks of each ticker must come in time order
+type TickWriter struct {
+	w       *bufio.Writer
+	offset  int64
+	pending map[string][]Tick
+	order   []string // tickers in order of the first tick, blocks of the rest are flushed in it
+	index   []TickBlock
+	buf     []byte
+}
+
+func NewTickWriter(w io.Writer) (*TickWriter, error) {
+	tw := &TickWriter{
+		w:       bufio.NewWriter(w),
+		pending: make(map[string][]Tick, 2),
+		index:   make([]TickBlock, 0, 16),
+	}
+	err := tw.write(append([]byte(tickFileMagic), tickFileVersion))
+	if err != nil {
+		return nil, err
+	}
+	return tw, nil
+}
+
+func (tw *TickWriter) write(data []byte) error {
+	n, err := tw.w.Write(data)
+	tw.offset += int64(n)
+	return err
+}
+
+func (tw *TickWriter) Write(t Tick) error {
+	block, ok := tw.pending[t.Ticker]
+	if !ok {
+		tw.order = append(tw.order, t.Ticker)
+		block = make([]Tick, 0, 1024)
+	} else if n := len(block); n > 0 && t.Timestamp.Before(block[n-1].Timestamp) {
+		return fmt.Errorf("%w: %v at %v after %v", ErrorTicksOutOfOrder, t.Ticker, t.Timestamp, block[n-1].Timestamp)
+	} else if n == 0 {
+		if last := tw.lastTime(t.Ticker); t.Timestamp.Before(last) {
+			return fmt.Errorf("%w: %v at %v after %v", ErrorTicksOutOfOrder, t.Ticker, t.Timestamp, last)
+		}
+	}
+
+	block = append(block, t)
+	tw.pending[t.Ticker] = block
+	if len(block) >= TickBlockSize {
+		return tw.flush(t.Ticker)
+	}
+	return nil
+}
+
+// time of the last flushed tick of ticker
+func (tw *TickWriter) lastTime(ticker string) time.Time {
+	for i := len(tw.index) - 1; i >= 0; i-- {
+		if tw.index[i].Ticker == ticker {
+			return tw.index[i].Last
+		}
+	}
+	return time.Time{}
+}
+
+func (tw *TickWriter) flush(ticker string) error {
+	ticks := tw.pending[ticker]
+	if len(ticks) == 0 {
+		return nil
+	}
+
+	tw.buf = encodeTickBlock(tw.buf[:0], ticks)
+	tw.index = append(tw.index, TickBlock{
+		Ticker: ticker,
+		First:  ticks[0].Timestamp,
+		Last:   ticks[len(ticks)-1].Timestamp,
+		Count:  len(ticks),
+		Offset: tw.offset,
+		Size:   len(tw.buf),
+		CRC:    crc32.ChecksumIEEE(tw.buf),
+	})
+	tw.pending[ticker] = ticks[:0]
+	return tw.write(tw.buf)
+}
+
+// Close writes what is left, index and footer, underlying writer is not closed
+func (tw *TickWriter) Close() error {
+	for _, ticker := range tw.order {
+		err := tw.flush(ticker)
+		if err != nil {
+			return err
+		}
+	}
+
+	indexOffset := tw.offset
+	buf := binary.AppendUvarint(nil, uint64(len(tw.index)))
+	for _, b := range tw.index {
+		buf = binary.AppendUvarint(buf, uint64(len(b.Ticker)))
+		buf = append(buf, b.Ticker...)
+		buf = binary.AppendVarint(buf, b.First.UnixNano())
+		buf = binary.AppendVarint(buf, b.Last.UnixNano())
+		buf = binary.AppendUvarint(buf, uint64(b.Count))
+		buf = binary.AppendUvarint(buf, uint64(b.Offset))
+		buf = binary.AppendUvarint(buf, uint64(b.Size))
+		buf = binary.LittleEndian.AppendUint32(buf, b.CRC)
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(indexOffset))
+	buf = append(buf, tickFileMagic...)
+
+	err := tw.write(buf)
+	if err != nil {
+		return err
+	}
+	return tw.w.Flush()
+}
+
+func encodeTickBlock(buf []byte, ticks []Tick) []byte {
+	unit := blockTimeUnit(ticks)
+	scale := blockPriceScale(ticks)
+
+	buf = binary.AppendUvarint(buf, uint64(len(ticks)))
+	buf = append(buf, byte(unit), byte(scale))
+
+	first := ticks[0].Timestamp.UnixNano()
+	buf = binary.AppendVarint(buf, first)
+	prev := first
+	for _, t := range ticks[1:] {
+		ns := t.Timestamp.UnixNano()
+		buf = binary.AppendUvarint(buf, uint64((ns-prev)/int64(tickTimeUnits[unit])))
+		prev = ns
+	}
+
+	var prevPrice int64
+	for _, t := range ticks {
+		price := scalePrice(t.Last, scale)
+		buf = binary.AppendVarint(buf, price-prevPrice)
+		prevPrice = price
+	}
+
+	for _, t := range ticks {
+		buf = binary.AppendVarint(buf, int64(t.Vol))
+	}
+	return buf
+}
+
+// index of the largest time unit every timestamp delta is a multiple of
+func blockTimeUnit(ticks []Tick) int {
+	for i, unit := range tickTimeUnits {
+		fits := true
+		for j := 1; j < len(ticks); j++ {
+			if ticks[j].Timestamp.Sub(ticks[j-1].Timestamp)%unit != 0 {
+				fits = false
+				break
+			}
+		}
+		if fits {
+			return i
+		}
+	}
+	return len(tickTimeUnits) - 1
+}
+
+// the least number of decimal digits which keep every price exactly
+func blockPriceScale(ticks []Tick) int {
+	for scale := 0; scale < maxPriceScale; scale++ {
+		exact := true
+		for _, t := range ticks {
+			if unscalePrice(scalePrice(t.Last, scale), scale) != t.Last {
+				exact = false
+				break
+			}
+		}
+		if exact {
+			return scale
+		}
+	}
+	return maxPriceScale
+}
+
+func scalePrice(price float32, scale int) int64 {
+	return int64(math.Round(float64(price) * math.Pow10(scale)))
+}
+
+func unscalePrice(price int64, scale int) float32 {
+	return float32(float64(price) / math.Pow10(scale))
+}
+
+func decodeTickBlock(data []byte, ticker string, ticks []Tick) ([]Tick, error) {
+	r := &blockReader{data: data}
+	count := int(r.uvarint())
+	unit, scale := int(r.byte()), int(r.byte())
+	if r.err != nil || unit >= len(tickTimeUnits) || scale > maxPriceScale || count > len(data) {
+		return nil, ErrorTickFileCorrupt
+	}
+
+	ticks = ticks[:0]
+	ns := r.varint()
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			ns += int64(r.uvarint()) * int64(tickTimeUnits[unit])
+		}
+		ticks = append(ticks, Tick{Ticker: ticker, Timestamp: time.Unix(0, ns).UTC()})
+	}
+	var price int64
+	for i := range ticks {
+		price += r.varint()
+		ticks[i].Last = unscalePrice(price, scale)
+	}
+	for i := range ticks {
+		ticks[i].Vol = int32(r.varint())
+	}
+	if r.err != nil || r.pos != len(data) {
+		return nil, ErrorTickFileCorrupt
+	}
+	return ticks, nil
+}
+
+// blockReader decodes varints and remembers the first error
+type blockReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *blockReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.err = ErrorTickFileCorrupt
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *blockReader) varint() int64 {
+	v, n := binary.Varint(r.data[r.pos:])
+	if n <= 0 {
+		r.err = ErrorTickFileCorrupt
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *blockReader) byte() byte {
+	if r.pos >= len(r.data) {
+		r.err = ErrorTickFileCorrupt
+		return 0
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *blockReader) bytes(n int) []byte {
+	if n < 0 || r.pos+n > len(r.data) {
+		r.err = ErrorTickFileCorrupt
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// TickFile is open binary tick file, blocks are read on demand
+type TickFile struct {
+	file  *os.File
+	Index []TickBlock
+}
+
+func OpenTickFile(path string) (*TickFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("can't open tick file: %w", err)
+	}
+	tf := &TickFile{file: file}
+	err = tf.readIndex()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return tf, nil
+}
+
+func (tf *TickFile) readIndex() error {
+	st, err := tf.file.Stat()
+	if err != nil {
+		return err
+	}
+	size := st.Size()
+	if size < int64(len(tickFileMagic)+1+tickFooterSize) {
+		return ErrorTickFileFormat
+	}
+
+	head := make([]byte, len(tickFileMagic)+1)
+	_, err = tf.file.ReadAt(head, 0)
+	if err != nil {
+		return err
+	}
+	if string(head[:len(tickFileMagic)]) != tickFileMagic {
+		return ErrorTickFileFormat
+	}
+	if head[len(tickFileMagic)] != tickFileVersion {
+		return fmt.Errorf("%w: unsupported version %v", ErrorTickFileFormat, head[len(tickFileMagic)])
+	}
+
+	footer := make([]byte, tickFooterSize)
+	_, err = tf.file.ReadAt(footer, size-int64(tickFooterSize))
+	if err != nil {
+		return err
+	}
+	if string(footer[8:]) != tickFileMagic {
+		return ErrorTickFileCorrupt
+	}
+	indexOffset := int64(binary.LittleEndian.Uint64(footer))
+	if indexOffset < int64(len(head)) || indexOffset > size-int64(tickFooterSize) {
+		return ErrorTickFileCorrupt
+	}
+
+	data := make([]byte, size-int64(tickFooterSize)-indexOffset)
+	_, err = tf.file.ReadAt(data, indexOffset)
+	if err != nil {
+		return err
+	}
+
+	r := &blockReader{data: data}
+	n := int(r.uvarint())
+	if n > len(data) {
+		return ErrorTickFileCorrupt
+	}
+	tf.Index = make([]TickBlock, 0, n)
+	for i := 0; i < n && r.err == nil; i++ {
+		b := TickBlock{}
+		b.Ticker = string(r.bytes(int(r.uvarint())))
+		b.First = time.Unix(0, r.varint()).UTC()
+		b.Last = time.Unix(0, r.varint()).UTC()
+		b.Count = int(r.uvarint())
+		b.Offset = int64(r.uvarint())
+		b.Size = int(r.uvarint())
+		crc := r.bytes(4)
+		if r.err != nil {
+			break
+		}
+		b.CRC = binary.LittleEndian.Uint32(crc)
+		if b.Offset < int64(len(head)) || b.Offset+int64(b.Size) > indexOffset {
+			return ErrorTickFileCorrupt
+		}
+		tf.Index = append(tf.Index, b)
+	}
+	if r.err != nil || r.pos != len(data) {
+		return ErrorTickFileCorrupt
+	}
+	return nil
+}
+
+// ReadBlock decodes block into ticks, reusing its memory
+func (tf *TickFile) ReadBlock(b TickBlock, ticks []Tick) ([]Tick, error) {
+	data := make([]byte, b.Size)
+	_, err := tf.file.ReadAt(data, b.Offset)
+	if err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(data) != b.CRC {
+		return nil, fmt.Errorf("%w: crc mismatch in block of %v at %v", ErrorTickFileCorrupt, b.Ticker, b.Offset)
+	}
+	return decodeTickBlock(data, b.Ticker, ticks)
+}
+
+func (tf *TickFile) Close() error {
+	return tf.file.Close()
+}
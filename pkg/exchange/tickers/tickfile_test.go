@@ -0,0 +1,192 @@
+package tickers
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/KSerditov/Trading/pkg/exchange/clock"
+)
+
+var fileStart = time.Date(2019, 5, 17, 10, 0, 0, 0, time.UTC)
+
+func writeTickFile(t *testing.T, ticks []Tick) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ticks.tks")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("cant create file: %v", err)
+	}
+	defer file.Close()
+
+	w, err := NewTickWriter(file)
+	if err != nil {
+		t.Fatalf("cant create writer: %v", err)
+	}
+	for _, tick := range ticks {
+		err = w.Write(tick)
+		if err != nil {
+			t.Fatalf("cant write tick: %v", err)
+		}
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatalf("cant close writer: %v", err)
+	}
+	return path
+}
+
+func readAll(t *testing.T, it tickIterator) []Tick {
+	t.Helper()
+	res := make([]Tick, 0)
+	for {
+		tick, ok, err := it.next()
+		if err != nil {
+			t.Fatalf("cant read tick: %v", err)
+		}
+		if !ok {
+			return res
+		}
+		res = append(res, tick)
+	}
+}
+
+func TestTickFile(t *testing.T) {
+	// two tickers interleaved, RTS spans several blocks
+	ticks := make([]Tick, 0, TickBlockSize*2+10)
+	for i := 0; i < TickBlockSize*2+10; i++ {
+		ticks = append(ticks, Tick{Ticker: "SPFB.RTS", Timestamp: fileStart.Add(time.Duration(i) * time.Second), Last: float32(120000 + i%50*10), Vol: int32(1 + i%7)})
+		if i%1000 == 0 {
+			ticks = append(ticks, Tick{Ticker: "SPFB.Si", Timestamp: fileStart.Add(time.Duration(i)*time.Second + 300*time.Millisecond), Last: 64.125, Vol: 3})
+		}
+	}
+	path := writeTickFile(t, ticks)
+
+	tf, err := OpenTickFile(path)
+	if err != nil {
+		t.Fatalf("cant open file: %v", err)
+	}
+	if len(tf.Index) != 4 {
+		t.Fatalf("expected 3 RTS blocks and 1 Si block, have %v", tf.Index)
+	}
+	it := &mergeIterator{}
+	it.add(tf)
+	if have := readAll(t, it); !reflect.DeepEqual(have, ticks) {
+		t.Fatalf("ticks dont match after roundtrip, have %v, want %v", len(have), len(ticks))
+	}
+
+	// seek skips whole blocks by index and the rest tick by tick
+	tf, err = OpenTickFile(path)
+	if err != nil {
+		t.Fatalf("cant open file: %v", err)
+	}
+	it = &mergeIterator{}
+	it.add(tf)
+	from := ticks[TickBlockSize+100].Timestamp
+	err = it.seek(from)
+	if err != nil {
+		t.Fatalf("cant seek: %v", err)
+	}
+	want := make([]Tick, 0)
+	for _, tick := range ticks {
+		if tick.Timestamp.After(from) {
+			want = append(want, tick)
+		}
+	}
+	if it.left() != len(want) {
+		t.Fatalf("left after seek dont match: have %v, want %v", it.left(), len(want))
+	}
+	if have := readAll(t, it); !reflect.DeepEqual(have, want) {
+		t.Fatalf("ticks after seek dont match, have %v, want %v", len(have), len(want))
+	}
+}
+
+func TestTickFileErrors(t *testing.T) {
+	w, err := NewTickWriter(&bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("cant create writer: %v", err)
+	}
+	w.Write(Tick{Ticker: "SPFB.RTS", Timestamp: fileStart, Last: 100, Vol: 1})
+	err = w.Write(Tick{Ticker: "SPFB.RTS", Timestamp: fileStart.Add(-time.Second), Last: 100, Vol: 1})
+	if !errors.Is(err, ErrorTicksOutOfOrder) {
+		t.Fatalf("expected out of order error, got %v", err)
+	}
+
+	path := writeTickFile(t, []Tick{{Ticker: "SPFB.RTS", Timestamp: fileStart, Last: 100, Vol: 1}})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("cant read file: %v", err)
+	}
+	data[6] ^= 0xff // inside the block
+	err = os.WriteFile(path, data, 0644)
+	if err != nil {
+		t.Fatalf("cant write file: %v", err)
+	}
+	tf, err := OpenTickFile(path)
+	if err != nil {
+		t.Fatalf("index must be intact: %v", err)
+	}
+	_, err = tf.ReadBlock(tf.Index[0], nil)
+	if !errors.Is(err, ErrorTickFileCorrupt) {
+		t.Fatalf("expected corrupt block error, got %v", err)
+	}
+
+	_, err = OpenTickFile(filepath.Join(t.TempDir(), "missing.tks"))
+	if err == nil {
+		t.Fatalf("expected error for missing file")
+	}
+}
+
+func TestTickersSourceBinary(t *testing.T) {
+	path := writeTickFile(t, []Tick{
+		{Ticker: "SPFB.RTS", Timestamp: fileStart, Last: 100, Vol: 1},
+		{Ticker: "SPFB.RTS", Timestamp: fileStart.Add(24*time.Hour + time.Second), Last: 101, Vol: 2},
+	})
+
+	now := time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)
+	clk := clock.NewManual(now)
+	d := &TickersSourceBinary{
+		FilePaths:    []string{path},
+		UseTodayDate: true,
+		Clock:        clk,
+	}
+	d.Prepare()
+	c := d.GetFeedChannel()
+	err := d.Load()
+	if err != nil {
+		t.Fatalf("cant load ticks: %v", err)
+	}
+	<-d.Ready()
+	clk.WaitTickers(1)
+
+	// the first day is today, the next one is tomorrow
+	for !clk.Now().After(now.Add(25*time.Hour + 2*time.Second)) {
+		clk.Advance(time.Hour)
+	}
+	want := []time.Time{
+		time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+		time.Date(2026, 10, 20, 10, 0, 1, 0, time.UTC),
+	}
+	for _, w := range want {
+		tick := <-c
+		if !tick.Timestamp.Equal(w) {
+			t.Fatalf("tick time dont match: have %v, want %v", tick.Timestamp, w)
+		}
+	}
+	// position is updated after ticks are sent
+	var pos Position
+	for i := 0; i < 500; i++ {
+		if pos = d.Position(); pos.Sent == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if pos.Sent != 2 || pos.Left != 0 {
+		t.Fatalf("position dont match: %+v", pos)
+	}
+	d.CloseFeed()
+}
@@ -0,0 +1,134 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const testCAValidity = 365 * 24 * time.Hour
+
+// GenerateTestCA writes development CA and certificates signed by it into dir:
+// ca.pem, exchange.pem/exchange-key.pem for hosts and <broker>.pem/<broker>-key.pem per broker name,
+// broker name becomes certificate CommonName which exchange maps to BrokerID.
+// Not for production use.
+func GenerateTestCA(dir string, hosts []string, brokers []string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	caTemplate, err := certTemplate("Trading test CA")
+	if err != nil {
+		return err
+	}
+	caTemplate.IsCA = true
+	caTemplate.BasicConstraintsValid = true
+	caTemplate.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return err
+	}
+	err = writePair(dir, "ca", caDER, caKey)
+	if err != nil {
+		return err
+	}
+
+	server, err := certTemplate("exchange")
+	if err != nil {
+		return err
+	}
+	// standby exchange connects to primary with the same certificate
+	server.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			server.IPAddresses = append(server.IPAddresses, ip)
+		} else {
+			server.DNSNames = append(server.DNSNames, h)
+		}
+	}
+	err = issue(dir, "exchange", server, caCert, caKey)
+	if err != nil {
+		return err
+	}
+
+	for _, b := range brokers {
+		client, err := certTemplate(b)
+		if err != nil {
+			return err
+		}
+		client.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		err = issue(dir, b, client, caCert, caKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func certTemplate(commonName string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			CommonName:   commonName,
+			Organization: []string{"Trading"},
+		},
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.Add(testCAValidity),
+		KeyUsage:  x509.KeyUsageDigitalSignature,
+	}, nil
+}
+
+func issue(dir string, name string, template *x509.Certificate, ca *x509.Certificate, caKey *ecdsa.PrivateKey) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	return writePair(dir, name, der, key)
+}
+
+func writePair(dir string, name string, der []byte, key *ecdsa.PrivateKey) error {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+	if err != nil {
+		return fmt.Errorf("can't write %v certificate: %w", name, err)
+	}
+	err = os.WriteFile(filepath.Join(dir, name+"-key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	if err != nil {
+		return fmt.Errorf("can't write %v key: %w", name, err)
+	}
+	return nil
+}